@@ -667,7 +667,7 @@ func (e *UnaryExpr) exprNode() {}
 
 // Pos returns the position of first character belonging to the node.
 func (e *UnaryExpr) Pos() token.Pos {
-	return e.Expr.Pos()
+	return e.TokenPos
 }
 
 // End returns the position of first character immediately after the node.
@@ -470,3 +470,88 @@ func (s *ThrowStmt) String() string {
 	}
 	return b.String()
 }
+
+// TryStmt represents a try-catch-finally statement.
+type TryStmt struct {
+	TryPos     token.Pos
+	Body       *BlockStmt
+	CatchPos   token.Pos  // position of "catch" keyword; or NoPos
+	CatchIdent *Ident     // variable of the caught error; or nil
+	Catch      *BlockStmt // catch block; or nil
+	FinallyPos token.Pos  // position of "finally" keyword; or NoPos
+	Finally    *BlockStmt // finally block; or nil
+}
+
+func (s *TryStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *TryStmt) Pos() token.Pos {
+	return s.TryPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *TryStmt) End() token.Pos {
+	if s.Finally != nil {
+		return s.Finally.End()
+	}
+	if s.Catch != nil {
+		return s.Catch.End()
+	}
+	return s.Body.End()
+}
+
+func (s *TryStmt) String() string {
+	var b strings.Builder
+	b.WriteString("try ")
+	b.WriteString(s.Body.String())
+	if s.Catch != nil {
+		b.WriteString(" catch ")
+		if s.CatchIdent != nil {
+			b.WriteString(s.CatchIdent.String())
+			b.WriteByte(' ')
+		}
+		b.WriteString(s.Catch.String())
+	}
+	if s.Finally != nil {
+		b.WriteString(" finally ")
+		b.WriteString(s.Finally.String())
+	}
+	return b.String()
+}
+
+// ExportStmt represents an export declaration.
+type ExportStmt struct {
+	ExportPos token.Pos
+	Names     []*Ident    // exported variables
+	Assign    *AssignStmt // definition of the exported variables; or nil
+}
+
+func (s *ExportStmt) stmtNode() {}
+
+// Pos returns the position of first character belonging to the node.
+func (s *ExportStmt) Pos() token.Pos {
+	return s.ExportPos
+}
+
+// End returns the position of first character immediately after the node.
+func (s *ExportStmt) End() token.Pos {
+	if s.Assign != nil {
+		return s.Assign.End()
+	}
+	return s.Names[len(s.Names)-1].End()
+}
+
+func (s *ExportStmt) String() string {
+	if s.Assign != nil {
+		return "export " + s.Assign.String()
+	}
+	var b strings.Builder
+	b.WriteString("export ")
+	for i, name := range s.Names {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name.String())
+	}
+	return b.String()
+}
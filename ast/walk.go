@@ -0,0 +1,165 @@
+package ast
+
+import "fmt"
+
+// Inspect traverses the AST in depth-first order:
+// it starts by calling f(node); node must not be nil.
+// If f returns true, Inspect invokes f recursively
+// for each of the non-nil children of node.
+func Inspect(node Node, f func(Node) bool) {
+	if !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	// expressions
+	case *BadExpr, *BoolLit, *CharLit, *FloatLit, *Ident, *ImportExpr,
+		*IntLit, *StringFragment, *NilLit:
+		// nothing to do
+	case *ArrayLit:
+		inspectList(n.Elements, f)
+	case *BinaryExpr:
+		Inspect(n.LHS, f)
+		Inspect(n.RHS, f)
+	case *SplatExpr:
+		Inspect(n.Expr, f)
+	case *CallExpr:
+		Inspect(n.Func, f)
+		inspectList(n.Args, f)
+	case *CondExpr:
+		Inspect(n.Cond, f)
+		Inspect(n.True, f)
+		Inspect(n.False, f)
+	case *FuncLit:
+		Inspect(n.Type, f)
+		Inspect(n.Body, f)
+	case *FuncType:
+		if n.Params != nil {
+			Inspect(n.Params, f)
+		}
+	case *TryExpr:
+		Inspect(n.CallExpr, f)
+	case *IndexExpr:
+		Inspect(n.Expr, f)
+		Inspect(n.Index, f)
+	case *TableKeyExpr:
+		Inspect(n.Expr, f)
+	case *TableElement:
+		Inspect(n.Key, f)
+		Inspect(n.Value, f)
+	case *TableLit:
+		inspectList(n.Exprs, f)
+	case *ParenExpr:
+		Inspect(n.Expr, f)
+	case *SelectorExpr:
+		Inspect(n.Expr, f)
+		Inspect(n.Sel, f)
+	case *SliceExpr:
+		Inspect(n.Expr, f)
+		if n.Low != nil {
+			Inspect(n.Low, f)
+		}
+		if n.High != nil {
+			Inspect(n.High, f)
+		}
+	case *StringInterpolationExpr:
+		Inspect(n.Expr, f)
+	case *StringLit:
+		inspectList(n.Exprs, f)
+	case *UnaryExpr:
+		Inspect(n.Expr, f)
+
+	// statements
+	case *BadStmt, *EmptyStmt:
+		// nothing to do
+	case *AssignStmt:
+		inspectList(n.LHS, f)
+		inspectList(n.RHS, f)
+	case *BlockStmt:
+		inspectList(n.Stmts, f)
+	case *ShortFuncBodyStmt:
+		Inspect(n.Expr, f)
+	case *BranchStmt:
+		if n.Label != nil {
+			Inspect(n.Label, f)
+		}
+	case *LabeledStmt:
+		Inspect(n.Label, f)
+		Inspect(n.Stmt, f)
+	case *ExprStmt:
+		Inspect(n.Expr, f)
+	case *ForInStmt:
+		if n.Key != nil {
+			Inspect(n.Key, f)
+		}
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+		Inspect(n.Iterable, f)
+		Inspect(n.Body, f)
+	case *ForStmt:
+		if n.Init != nil {
+			Inspect(n.Init, f)
+		}
+		if n.Cond != nil {
+			Inspect(n.Cond, f)
+		}
+		if n.Post != nil {
+			Inspect(n.Post, f)
+		}
+		Inspect(n.Body, f)
+	case *IfStmt:
+		if n.Init != nil {
+			Inspect(n.Init, f)
+		}
+		Inspect(n.Cond, f)
+		Inspect(n.Body, f)
+		if n.Else != nil {
+			Inspect(n.Else, f)
+		}
+	case *IncDecStmt:
+		Inspect(n.Expr, f)
+	case *ReturnStmt:
+		inspectList(n.Results, f)
+	case *DeferStmt:
+		Inspect(n.CallExpr, f)
+	case *ThrowStmt:
+		inspectList(n.Errors, f)
+	case *TryStmt:
+		Inspect(n.Body, f)
+		if n.CatchIdent != nil {
+			Inspect(n.CatchIdent, f)
+		}
+		if n.Catch != nil {
+			Inspect(n.Catch, f)
+		}
+		if n.Finally != nil {
+			Inspect(n.Finally, f)
+		}
+	case *ExportStmt:
+		if n.Assign != nil {
+			Inspect(n.Assign, f)
+		} else {
+			for _, name := range n.Names {
+				Inspect(name, f)
+			}
+		}
+
+	// other nodes
+	case *IdentList:
+		for _, ident := range n.List {
+			Inspect(ident, f)
+		}
+	case *File:
+		inspectList(n.Stmts, f)
+
+	default:
+		panic(fmt.Errorf("ast.Inspect: unexpected node type %T", n))
+	}
+}
+
+func inspectList[N Node](list []N, f func(Node) bool) {
+	for _, node := range list {
+		Inspect(node, f)
+	}
+}
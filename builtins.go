@@ -43,6 +43,10 @@ var Universe = []*Variable{
 	NewVariable("tuple", TupleType),
 	NewVariable("range", RangeType),
 	NewVariable("function", FunctionType),
+
+	NewVariable("spawn", NewBuiltinFunction("spawn", builtinSpawn)),
+	NewVariable("select", NewBuiltinFunction("select", builtinSelect)),
+	NewVariable("chan", ChanType),
 }
 
 func builtinTypeName(_ *Runtime, args ...Value) (Value, error) {
@@ -56,7 +60,7 @@ func builtinTypeName(_ *Runtime, args ...Value) (Value, error) {
 	return String(TypeName(args[0])), nil
 }
 
-func builtinClone(_ *Runtime, args ...Value) (Value, error) {
+func builtinClone(r *Runtime, args ...Value) (Value, error) {
 	if len(args) != 1 {
 		return nil, &WrongNumArgumentsError{
 			WantMin: 1,
@@ -64,6 +68,11 @@ func builtinClone(_ *Runtime, args ...Value) (Value, error) {
 			Got:     len(args),
 		}
 	}
+	if _, size := collectionSize(args[0]); size != 0 {
+		if err := r.Alloc(size); err != nil {
+			return nil, err
+		}
+	}
 	return args[0].Clone(), nil
 }
 
@@ -86,7 +95,7 @@ func builtinLen(_ *Runtime, args ...Value) (Value, error) {
 	return Int(value.Len()), nil
 }
 
-func builtinAppend(_ *Runtime, args ...Value) (Value, error) {
+func builtinAppend(r *Runtime, args ...Value) (Value, error) {
 	var (
 		arr  *Array
 		rest []Value
@@ -94,6 +103,9 @@ func builtinAppend(_ *Runtime, args ...Value) (Value, error) {
 	if err := UnpackArgs(args, "arr", &arr, "...", &rest); err != nil {
 		return nil, err
 	}
+	if err := r.growArray(arr, len(rest)); err != nil {
+		return nil, err
+	}
 	return &Array{
 		elems:     append(arr.elems, rest...),
 		immutable: arr.immutable,
@@ -189,7 +201,7 @@ func builtinDelete(_ *Runtime, args ...Value) (Value, error) {
 	}
 }
 
-func builtinSplice(_ *Runtime, args ...Value) (Value, error) {
+func builtinSplice(r *Runtime, args ...Value) (Value, error) {
 	var (
 		arr         *Array
 		start, stop int
@@ -229,6 +241,9 @@ func builtinSplice(_ *Runtime, args ...Value) (Value, error) {
 	if start > stop {
 		return nil, fmt.Errorf("invalid splice indices: %d > %d", start, stop)
 	}
+	if err := r.growArray(arr, len(rest)-(stop-start)); err != nil {
+		return nil, err
+	}
 	if start == stop {
 		arr.elems = slices.Insert(arr.elems, start, rest...)
 		return NewArray(nil), nil
@@ -242,7 +257,7 @@ func builtinSplice(_ *Runtime, args ...Value) (Value, error) {
 	return NewArray(deleted), nil
 }
 
-func builtinInsert(_ *Runtime, args ...Value) (Value, error) {
+func builtinInsert(r *Runtime, args ...Value) (Value, error) {
 	if len(args) < 2 {
 		return nil, &WrongNumArgumentsError{
 			WantMin: 2,
@@ -268,6 +283,9 @@ func builtinInsert(_ *Runtime, args ...Value) (Value, error) {
 		if index > n {
 			return nil, fmt.Errorf("insert index %d out of range [:%d]", index, n)
 		}
+		if err := r.growArray(x, len(rest)); err != nil {
+			return nil, err
+		}
 		x.elems = slices.Insert(x.elems, index, rest...)
 		return Nil, nil
 	case *Table:
@@ -282,9 +300,18 @@ func builtinInsert(_ *Runtime, args ...Value) (Value, error) {
 		if err := UnpackArgs(args[1:], "index", &index, "value", &value); err != nil {
 			return nil, err
 		}
+		n := x.Len()
 		if err := x.SetProperty(index, value); err != nil {
 			return nil, fmt.Errorf("failed to insert '%s' into table: %w", TypeName(index), err)
 		}
+		if x.Len() > n {
+			if err := r.CheckLen(x.Len()); err != nil {
+				return nil, err
+			}
+			if err := r.Alloc(entrySize); err != nil {
+				return nil, err
+			}
+		}
 		return Nil, nil
 	default:
 		return nil, &InvalidArgumentTypeError{
@@ -385,7 +412,7 @@ func builtinOptional(r *Runtime, args ...Value) (Value, error) {
 	}
 }
 
-func builtinFormat(_ *Runtime, args ...Value) (Value, error) {
+func builtinFormat(r *Runtime, args ...Value) (Value, error) {
 	var (
 		format string
 		rest   []Value
@@ -400,6 +427,9 @@ func builtinFormat(_ *Runtime, args ...Value) (Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := r.Alloc(int64(len(s))); err != nil {
+		return nil, err
+	}
 	return String(s), nil
 }
 
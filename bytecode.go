@@ -28,7 +28,7 @@ func (b *Bytecode) FormatConstants() (output []string) {
 		switch cn := cn.(type) {
 		case *CompiledFunction:
 			output = append(output, fmt.Sprintf(
-				"[% 3d] (Compiled Function|%p)", cidx, &cn))
+				"[% 3d] %s (Compiled Function|%p)", cidx, cn, &cn))
 			for _, l := range bytecode.FormatInstructions(cn.instructions, 0) {
 				output = append(output, fmt.Sprintf("     %s", l))
 			}
@@ -188,7 +188,9 @@ func updateConstIndexes(insts []byte, indexMap map[int]int) {
 			if !ok {
 				panic(fmt.Errorf("constant index not found: %d", curIdx))
 			}
-			copy(insts[i:], bytecode.MakeInstruction(opcode, newIdx))
+			if newIdx != curIdx {
+				copy(insts[i:], bytecode.MakeInstruction(opcode, newIdx))
+			}
 		case bytecode.OpClosure:
 			curIdx := operands[0]
 			numFree := operands[1]
@@ -196,7 +198,9 @@ func updateConstIndexes(insts []byte, indexMap map[int]int) {
 			if !ok {
 				panic(fmt.Errorf("constant index not found: %d", curIdx))
 			}
-			copy(insts[i:], bytecode.MakeInstruction(opcode, newIdx, numFree))
+			if newIdx != curIdx {
+				copy(insts[i:], bytecode.MakeInstruction(opcode, newIdx, numFree))
+			}
 		}
 		i += 1 + offset
 	}
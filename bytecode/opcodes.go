@@ -48,6 +48,8 @@ const (
 	OpBinaryOp                      // Binary operation
 	OpUnaryOp                       // Unary operation
 	OpCompare                       // Comparison operation
+	OpTryBegin                      // Install exception handler
+	OpTryEnd                        // Remove exception handler
 )
 
 // OpcodeNames are string representation of opcodes.
@@ -93,6 +95,8 @@ var OpcodeNames = [...]string{
 	OpBinaryOp:        "BINARYOP",
 	OpUnaryOp:         "UNARYOP",
 	OpCompare:         "CMP",
+	OpTryBegin:        "TRYBEGIN",
+	OpTryEnd:          "TRYEND",
 }
 
 // OpcodeOperands is the number of operands.
@@ -138,6 +142,8 @@ var OpcodeOperands = [...][]int{
 	OpBinaryOp:        {1},
 	OpUnaryOp:         {1},
 	OpCompare:         {1},
+	OpTryBegin:        {4},
+	OpTryEnd:          {},
 }
 
 // Read2 reads a 2-byte operand.
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/infastin/toy/repl"
+)
+
+func attachCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "attach",
+		Usage:     "connect to the REPL served by a running application",
+		ArgsUsage: "SOCKET",
+		Description: "The input is evaluated by the application listening on the Unix socket,\n" +
+			"which exposes the REPL with repl.Server.",
+		Action: attachAction,
+	}
+}
+
+func attachAction(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one socket path")
+	}
+	path := ctx.Args().First()
+	c, err := repl.Dial(path)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	r := repl.NewRemote(c)
+	r.SetBanner(fmt.Sprintf("Attached to %s, enter :help for the list of commands", path))
+	r.SetInput(os.Stderr)
+	r.SetOutput(os.Stdout)
+	if history, err := repl.DefaultHistoryFile(); err == nil {
+		r.SetHistoryFile(history)
+	}
+	return r.Run()
+}
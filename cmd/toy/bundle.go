@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/ast"
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
+
+func bundleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "bundle",
+		Usage:     "link a script and the files it imports into a single source file",
+		ArgsUsage: "FILE",
+		Description: "Every imported file is inlined as a function,\n" +
+			"while builtin modules are still imported by name.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Usage:   "write the bundle to `FILE` instead of the standard output",
+				Aliases: []string{"o"},
+			},
+		},
+		Action: bundleAction,
+	}
+}
+
+func depsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "deps",
+		Usage:     "print the graph of modules imported by a script",
+		ArgsUsage: "FILE",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: text or dot",
+				Value: "text",
+			},
+		},
+		Action: depsAction,
+	}
+}
+
+// compileInputFile compiles the file given as the argument of the command.
+func compileInputFile(ctx *cli.Context) (*toy.Compiler, string, error) {
+	if ctx.Args().Len() != 1 {
+		return nil, "", fmt.Errorf("expected exactly one input file")
+	}
+	inputFile := ctx.Args().First()
+	m, err := toy.FindManifest(filepath.Dir(inputFile))
+	if err != nil {
+		return nil, "", err
+	}
+	l, err := findLockfile(filepath.Dir(inputFile), m)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := compileFile(inputFile, m, l)
+	if err != nil {
+		return nil, "", err
+	}
+	return c, inputFile, nil
+}
+
+func bundleAction(ctx *cli.Context) error {
+	c, inputFile, err := compileInputFile(ctx)
+	if err != nil {
+		return err
+	}
+	bundle, err := newBundler(c).bundle(inputFile)
+	if err != nil {
+		return err
+	}
+	if output := ctx.String("output"); output != "" {
+		return os.WriteFile(output, bundle, 0o644)
+	}
+	_, err = os.Stdout.Write(bundle)
+	return err
+}
+
+func depsAction(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "text" && format != "dot" {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+	c, inputFile, err := compileInputFile(ctx)
+	if err != nil {
+		return err
+	}
+	g := newDepGraph(c.Imports())
+	if format == "dot" {
+		return g.writeDOT(os.Stdout, inputFile)
+	}
+	return g.writeTree(os.Stdout, inputFile, "")
+}
+
+// depGraph is the graph of imports made by a script.
+type depGraph struct {
+	imports map[string][]toy.Import // imports by importing files
+}
+
+func newDepGraph(imports []toy.Import) *depGraph {
+	g := &depGraph{imports: make(map[string][]toy.Import)}
+	for _, imp := range imports {
+		g.imports[imp.File] = append(g.imports[imp.File], imp)
+	}
+	return g
+}
+
+// writeTree writes the graph as a tree rooted at the given file.
+// Each line is prefixed with the given prefix.
+func (g *depGraph) writeTree(w io.Writer, root, prefix string) error {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(displayPath(root))
+	b.WriteByte('\n')
+	visited := make(map[string]bool)
+	var walk func(file, indent string)
+	walk = func(file, indent string) {
+		visited[file] = true
+		imports := g.imports[file]
+		for i, imp := range imports {
+			branch, next := "├── ", "│   "
+			if i == len(imports)-1 {
+				branch, next = "└── ", "    "
+			}
+			b.WriteString(prefix)
+			b.WriteString(indent)
+			b.WriteString(branch)
+			b.WriteString(imp.Name)
+			if imp.Path != "" {
+				b.WriteString(" (" + displayPath(imp.Path) + ")")
+				if visited[imp.Path] && len(g.imports[imp.Path]) != 0 {
+					// the subtree has already been shown
+					b.WriteString(" (*)")
+					b.WriteByte('\n')
+					continue
+				}
+			}
+			b.WriteByte('\n')
+			if imp.Path != "" {
+				walk(imp.Path, indent+next)
+			}
+		}
+	}
+	walk(root, "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOT writes the graph in the DOT language.
+func (g *depGraph) writeDOT(w io.Writer, root string) error {
+	var b strings.Builder
+	b.WriteString("digraph deps {\n")
+	fmt.Fprintf(&b, "\t%s;\n", strconv.Quote(displayPath(root)))
+	visited := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) != 0 {
+		file := queue[0]
+		queue = queue[1:]
+		for _, imp := range g.imports[file] {
+			from := strconv.Quote(displayPath(file))
+			if imp.Path == "" {
+				to := strconv.Quote(imp.Name)
+				if !visited[imp.Name] {
+					visited[imp.Name] = true
+					fmt.Fprintf(&b, "\t%s [shape=box];\n", to)
+				}
+				fmt.Fprintf(&b, "\t%s -> %s;\n", from, to)
+				continue
+			}
+			to := strconv.Quote(displayPath(imp.Path))
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", from, to, strconv.Quote(imp.Name))
+			if !visited[imp.Path] {
+				visited[imp.Path] = true
+				queue = append(queue, imp.Path)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// displayPath returns the path relative to the current directory if possible.
+func displayPath(name string) string {
+	if !filepath.IsAbs(name) {
+		return name
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return name
+	}
+	rel, err := filepath.Rel(wd, name)
+	if err != nil {
+		return name
+	}
+	return rel
+}
+
+// bundler links a script and the files it imports into a single source file.
+//
+// Each imported file is turned into a function defined before the script,
+// and its imports are replaced with calls of the function.
+// Export declarations are replaced with the return of the table of exports.
+type bundler struct {
+	c     *toy.Compiler
+	funcs map[string]string     // names of functions by file paths
+	paths map[toy.Import]string // paths of imported files by importing files and module names
+}
+
+func newBundler(c *toy.Compiler) *bundler {
+	b := &bundler{
+		c:     c,
+		funcs: make(map[string]string),
+		paths: make(map[toy.Import]string),
+	}
+	// files are listed after their imports
+	for i, name := range c.ImportedFiles() {
+		b.funcs[name] = fmt.Sprintf("__module%d", i+1)
+	}
+	for _, imp := range c.Imports() {
+		if imp.Path != "" {
+			b.paths[toy.Import{File: imp.File, Name: imp.Name}] = imp.Path
+		}
+	}
+	return b
+}
+
+func (b *bundler) bundle(inputFile string) ([]byte, error) {
+	mainSrc, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if len(mainSrc) > 1 && string(mainSrc[:2]) == "#!" {
+		// keep the shebang on the first line
+		line, _, _ := bytes.Cut(mainSrc, []byte("\n"))
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&out, "// Code generated by \"toy bundle %s\"; DO NOT EDIT.\n//\n", filepath.Base(inputFile))
+	if err := newDepGraph(b.c.Imports()).writeTree(&out, inputFile, "// "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+
+	for _, name := range b.c.ImportedFiles() {
+		src, _ := b.c.ImportedSource(name)
+		body, err := b.rewrite(name, src, true)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "// %s\n%s := fn() {\n%s\n}\n\n", displayPath(name), b.funcs[name], body)
+	}
+
+	body, err := b.rewrite(inputFile, mainSrc, false)
+	if err != nil {
+		return nil, err
+	}
+	out.Write(body)
+	if !bytes.HasSuffix(body, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// rewrite replaces imports of files in the source code
+// with the calls of the corresponding functions.
+func (b *bundler) rewrite(name string, src []byte, isModule bool) ([]byte, error) {
+	src = slices.Clone(src)
+	var skip int
+	if len(src) > 1 && string(src[:2]) == "#!" {
+		// parse the shebang as a comment, but drop it from the output:
+		// the bundle has its own one
+		copy(src, "//")
+		skip = len(src)
+		if i := bytes.IndexByte(src, '\n'); i != -1 {
+			skip = i + 1
+		}
+	}
+
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile(name, -1, len(src))
+	parsed, err := parser.NewParser(file, src, nil).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+
+	var (
+		edits   []edit
+		exports []string
+	)
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ImportExpr:
+			path, ok := b.paths[toy.Import{File: name, Name: node.ModuleName}]
+			if ok {
+				edits = append(edits, edit{
+					start: file.Offset(node.Pos()),
+					end:   file.Offset(node.End()),
+					text:  b.funcs[path] + "()",
+				})
+			}
+		case *ast.ExportStmt:
+			for _, ident := range node.Names {
+				exports = append(exports, ident.Name)
+			}
+			if node.Assign != nil {
+				// keep the definition
+				edits = append(edits, edit{
+					start: file.Offset(node.Pos()),
+					end:   file.Offset(node.Assign.Pos()),
+				})
+				return true
+			}
+			edits = append(edits, edit{
+				start: file.Offset(node.Pos()),
+				end:   file.Offset(node.End()),
+			})
+			return false
+		}
+		return true
+	})
+	slices.SortFunc(edits, func(a, b edit) int {
+		return a.start - b.start
+	})
+
+	var out bytes.Buffer
+	last := skip
+	for _, e := range edits {
+		out.Write(src[last:e.start])
+		out.WriteString(e.text)
+		last = e.end
+	}
+	out.Write(src[last:])
+
+	if isModule && len(exports) != 0 {
+		if !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		out.WriteString("return freeze({")
+		for i, export := range exports {
+			if i != 0 {
+				out.WriteString(", ")
+			}
+			fmt.Fprintf(&out, "%s: %s", export, export)
+		}
+		out.WriteString("})")
+	}
+
+	return bytes.TrimRight(out.Bytes(), "\n"), nil
+}
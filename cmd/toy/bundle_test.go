@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var bundleFiles = map[string]string{
+	"main.toy": "#!/usr/bin/env toy\n" +
+		"fmt := import(\"fmt\")\n" +
+		"util := import(\"./lib/util\")\n" +
+		"helper := import(\"./lib/helper\")\n" +
+		"fmt.println(util.greet(\"world\"), helper.prefix)\n",
+	"lib/util.toy": "text := import(\"text\")\n" +
+		"helper := import(\"./helper\")\n" +
+		"export greet := fn(name) { return helper.prefix + text.toUpper(name) }\n",
+	"lib/helper.toy": "strings := import(\"text\")\n" +
+		"export prefix := strings.trimSpace(\" hello, \") + \" \"\n",
+}
+
+func TestBundle(t *testing.T) {
+	dir := writeFiles(t, bundleFiles)
+	chdir(t, dir)
+
+	stdout, stderr, code := runToy(t, "", "bundle", "-o", "bundle.toy", "main.toy")
+	require.Zero(t, code, stderr)
+	require.Empty(t, stdout)
+
+	data, err := os.ReadFile(filepath.Join(dir, "bundle.toy"))
+	require.NoError(t, err)
+	require.Equal(t, `#!/usr/bin/env toy
+// Code generated by "toy bundle main.toy"; DO NOT EDIT.
+//
+// main.toy
+// ├── fmt
+// ├── ./lib/util (lib/util.toy)
+// │   ├── text
+// │   └── ./helper (lib/helper.toy)
+// │       └── text
+// └── ./lib/helper (lib/helper.toy) (*)
+
+// lib/helper.toy
+__module1 := fn() {
+strings := import("text")
+prefix := strings.trimSpace(" hello, ") + " "
+return freeze({prefix: prefix})
+}
+
+// lib/util.toy
+__module2 := fn() {
+text := import("text")
+helper := __module1()
+greet := fn(name) { return helper.prefix + text.toUpper(name) }
+return freeze({greet: greet})
+}
+
+fmt := import("fmt")
+util := __module2()
+helper := __module1()
+fmt.println(util.greet("world"), helper.prefix)
+`, string(data))
+
+	want, stderr, code := runToy(t, "", "main.toy")
+	require.Zero(t, code, stderr)
+	stdout, stderr, code = runToy(t, "", "bundle.toy")
+	require.Zero(t, code, stderr)
+	require.Equal(t, want, stdout)
+}
+
+func TestDeps(t *testing.T) {
+	dir := writeFiles(t, bundleFiles)
+	chdir(t, dir)
+
+	tests := []struct {
+		name   string
+		args   []string
+		output string
+		err    string
+	}{
+		{
+			name: "text",
+			args: []string{"deps", "main.toy"},
+			output: `main.toy
+├── fmt
+├── ./lib/util (lib/util.toy)
+│   ├── text
+│   └── ./helper (lib/helper.toy)
+│       └── text
+└── ./lib/helper (lib/helper.toy) (*)
+`,
+		},
+		{
+			name: "dot",
+			args: []string{"deps", "--format", "dot", "main.toy"},
+			output: `digraph deps {
+	"main.toy";
+	"fmt" [shape=box];
+	"main.toy" -> "fmt";
+	"main.toy" -> "lib/util.toy" [label="./lib/util"];
+	"main.toy" -> "lib/helper.toy" [label="./lib/helper"];
+	"text" [shape=box];
+	"lib/util.toy" -> "text";
+	"lib/util.toy" -> "lib/helper.toy" [label="./helper"];
+	"lib/helper.toy" -> "text";
+}
+`,
+		},
+		{
+			name: "nested file",
+			args: []string{"deps", "lib/util.toy"},
+			output: `lib/util.toy
+├── text
+└── ./helper (lib/helper.toy)
+    └── text
+`,
+		},
+		{
+			name: "unknown format",
+			args: []string{"deps", "--format", "svg", "main.toy"},
+			err:  "unknown format 'svg'",
+		},
+		{
+			name: "no input file",
+			args: []string{"deps"},
+			err:  "expected exactly one input file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runToy(t, "", tt.args...)
+			if tt.err != "" {
+				require.Equal(t, exitError, code)
+				require.Contains(t, stderr, tt.err)
+				return
+			}
+			require.Zero(t, code, stderr)
+			require.Equal(t, tt.output, stdout)
+		})
+	}
+}
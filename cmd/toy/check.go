@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/infastin/toy"
+)
+
+func checkCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "check",
+		Usage:     "parse and compile scripts and the files they import without running them",
+		ArgsUsage: "[FILE | DIR...]",
+		Description: "Directories are searched for source files recursively.\n" +
+			"If no files are given, all the source files of the project are checked.\n" +
+			"Exits with code 1 if any errors are found.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format: text, json or sarif",
+				Value: "text",
+			},
+		},
+		Action: checkAction,
+	}
+}
+
+func checkAction(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "text" && format != "json" && format != "sarif" {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+
+	args := ctx.Args().Slice()
+	if len(args) == 0 {
+		m, err := toy.FindManifest(".")
+		if err != nil {
+			return err
+		}
+		root := "."
+		if m != nil {
+			root = m.Dir
+		}
+		args = []string{root}
+	}
+
+	c := newChecker()
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			c.addError(arg, err)
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		dirFiles, err := projectFiles(arg)
+		if err != nil {
+			c.addError(arg, err)
+			continue
+		}
+		files = append(files, dirFiles...)
+	}
+	for _, inputFile := range files {
+		c.check(inputFile)
+	}
+	diagList := c.diagList
+
+	var err error
+	switch format {
+	case "json":
+		err = json.NewEncoder(os.Stdout).Encode(struct {
+			Errors []diagnostic `json:"errors"`
+		}{diagList})
+	case "sarif":
+		err = writeSARIF(os.Stdout, diagList)
+	default:
+		err = writeCheckText(os.Stdout, diagList)
+	}
+	if err != nil {
+		return err
+	}
+	if len(diagList) != 0 {
+		return cli.Exit("", exitError)
+	}
+	return nil
+}
+
+// checker compiles files and collects the diagnostics of the errors.
+// Errors of the files imported by several files are reported once.
+type checker struct {
+	diagList []diagnostic
+	seen     map[string]bool
+}
+
+func newChecker() *checker {
+	return &checker{
+		diagList: make([]diagnostic, 0),
+		seen:     make(map[string]bool),
+	}
+}
+
+// check compiles the file and adds the diagnostics of its errors.
+func (c *checker) check(inputFile string) {
+	m, err := toy.FindManifest(filepath.Dir(inputFile))
+	if err != nil {
+		c.addError(inputFile, err)
+		return
+	}
+	l, err := findLockfile(filepath.Dir(inputFile), m)
+	if err != nil {
+		c.addError(inputFile, err)
+		return
+	}
+	if _, err := compileFile(inputFile, m, l); err != nil {
+		for _, d := range diagnosticsOf(err) {
+			if d.Position == nil {
+				// errors without positions, e.g. I/O errors
+				c.addError(inputFile, err)
+				continue
+			}
+			c.add(d)
+		}
+	}
+}
+
+// addError adds the diagnostic of an error
+// that isn't located in the source code, e.g. a missing file.
+// The path of the file is reported as the position of the error.
+func (c *checker) addError(name string, err error) {
+	if pathErr := (*fs.PathError)(nil); errors.As(err, &pathErr) {
+		name, err = pathErr.Path, pathErr.Err
+	}
+	c.add(diagnostic{
+		Kind:     "error",
+		Message:  err.Error(),
+		Position: &diagPosition{File: displayPath(name)},
+	})
+}
+
+func (c *checker) add(d diagnostic) {
+	key := fmt.Sprintf("%s:%d:%d: %s", d.Position.File, d.Position.Line, d.Position.Column, d.Message)
+	if !c.seen[key] {
+		c.seen[key] = true
+		c.diagList = append(c.diagList, d)
+	}
+}
+
+func writeCheckText(w io.Writer, diagList []diagnostic) error {
+	p := newDiagPrinter(w)
+	for i := range diagList {
+		text := p.renderDiagnostic(&diagList[i])
+		if diagList[i].Kind == "error" {
+			text = diagList[i].Position.File + ": " + text
+		}
+		if i != len(diagList)-1 {
+			text += "\n"
+		}
+		if _, err := fmt.Fprintln(w, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SARIF 2.1.0 log, which is understood by code review tools.
+// Only the properties used by "toy check" are defined.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name    string      `json:"name"`
+		Version string      `json:"version"`
+		Rules   []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+)
+
+func writeSARIF(w io.Writer, diagList []diagnostic) error {
+	results := make([]sarifResult, 0, len(diagList))
+	for _, d := range diagList {
+		loc := sarifLocation{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.Position.File)},
+			},
+		}
+		if d.Position.Line != 0 {
+			region := &sarifRegion{
+				StartLine:   d.Position.Line,
+				StartColumn: d.Position.Column,
+			}
+			if d.End != nil {
+				region.EndLine = d.End.Line
+				region.EndColumn = d.End.Column
+			}
+			loc.PhysicalLocation.Region = region
+		}
+		results = append(results, sarifResult{
+			RuleID:    d.Kind,
+			Level:     "error",
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	rules := []sarifRule{
+		{ID: "parse", ShortDescription: sarifMessage{Text: "Parse error"}},
+		{ID: "compile", ShortDescription: sarifMessage{Text: "Compile error"}},
+		{ID: "error", ShortDescription: sarifMessage{Text: "Error"}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:    "toy",
+					Version: version,
+					Rules:   rules,
+				},
+			},
+			Results: results,
+		}},
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"toy.mod":          "module app\n",
+		"ok.toy":           `util := import("./lib/util"); x := util.x`,
+		"compile.toy":      `x := y`,
+		"parse.toy":        "x := \n",
+		"lib/util.toy":     `export x := 1`,
+		"shared/a.toy":     `import("./bad")`,
+		"shared/b.toy":     `import("./bad")`,
+		"shared/bad.toy":   `export x := y`,
+		"shared/toy.mod":   "module shared\n",
+		"other/clean.toy":  `export x := 1`,
+		"other/clean2.toy": `import("./clean")`,
+	})
+	chdir(t, dir)
+
+	tests := []struct {
+		name   string
+		args   []string
+		output string
+		code   int
+	}{
+		{
+			name: "clean",
+			args: []string{"check", "ok.toy", "other"},
+		},
+		{
+			name: "compile error",
+			args: []string{"check", "compile.toy"},
+			output: `compile error: unresolved reference 'y'
+└─ at compile.toy:1:6
+
+ 1 | x := y
+   |      ^
+`,
+			code: exitError,
+		},
+		{
+			name: "parse error",
+			args: []string{"check", "parse.toy"},
+			output: `parse error: expected operand, found 'EOF'
+└─ at parse.toy:1:7
+
+ 1 | x := 
+   |      ^
+`,
+			code: exitError,
+		},
+		{
+			name: "missing path",
+			args: []string{"check", "missing.toy", "ok.toy/x.toy", "ok.toy"},
+			output: `missing.toy: no such file or directory
+
+ok.toy/x.toy: not a directory
+`,
+			code: exitError,
+		},
+		{
+			name: "missing path and errors in other files",
+			args: []string{"check", "missing", "compile.toy"},
+			output: `missing: no such file or directory
+
+compile error: unresolved reference 'y'
+└─ at compile.toy:1:6
+
+ 1 | x := y
+   |      ^
+`,
+			code: exitError,
+		},
+		{
+			name: "errors of shared imports are reported once",
+			args: []string{"check", "shared"},
+			output: `compile error: unresolved reference 'y'
+└─ at shared/bad.toy:1:13
+
+ 1 | export x := y
+   |             ^
+`,
+			code: exitError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runToy(t, "", tt.args...)
+			require.Equal(t, tt.code, code, stderr)
+			require.Equal(t, tt.output, stdout)
+		})
+	}
+}
+
+func TestCheckProject(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"toy.mod":         "module app\n",
+		"main.toy":        `x := 1`,
+		"lib/bad.toy":     `x := y`,
+		"vendor/skip.toy": `x := y`,
+		"nested/toy.mod":  "module nested\n",
+		"nested/skip.toy": `x := y`,
+	})
+	chdir(t, dir)
+
+	stdout, stderr, code := runToy(t, "", "check", "--format", "json")
+	require.Equal(t, exitError, code, stderr)
+
+	var out struct {
+		Errors []diagnostic `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	require.Len(t, out.Errors, 1)
+	require.Equal(t, "compile", out.Errors[0].Kind)
+	require.Equal(t, "lib/bad.toy", out.Errors[0].Position.File)
+}
+
+func TestCheckFormats(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"bad.toy": `x := y`})
+	chdir(t, dir)
+	args := []string{"missing.toy", "bad.toy"}
+
+	t.Run("json", func(t *testing.T) {
+		stdout, stderr, code := runToy(t, "", append([]string{"check", "--format", "json"}, args...)...)
+		require.Equal(t, exitError, code, stderr)
+		require.JSONEq(t, `{"errors": [
+			{
+				"kind": "error",
+				"message": "no such file or directory",
+				"position": {"file": "missing.toy", "line": 0, "column": 0, "offset": 0}
+			},
+			{
+				"kind": "compile",
+				"message": "unresolved reference 'y'",
+				"position": {"file": "bad.toy", "line": 1, "column": 6, "offset": 5},
+				"end": {"file": "bad.toy", "line": 1, "column": 7, "offset": 6},
+				"excerpt": {"line": 1, "lines": ["x := y"], "start": 6, "end": 7}
+			}
+		]}`, stdout)
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		stdout, stderr, code := runToy(t, "", append([]string{"check", "--format", "sarif"}, args...)...)
+		require.Equal(t, exitError, code, stderr)
+
+		var log sarifLog
+		require.NoError(t, json.Unmarshal([]byte(stdout), &log))
+		require.Equal(t, "2.1.0", log.Version)
+		require.Len(t, log.Runs, 1)
+		require.Equal(t, []sarifResult{
+			{
+				RuleID:  "error",
+				Level:   "error",
+				Message: sarifMessage{Text: "no such file or directory"},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: "missing.toy"},
+					},
+				}},
+			},
+			{
+				RuleID:  "compile",
+				Level:   "error",
+				Message: sarifMessage{Text: "unresolved reference 'y'"},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: "bad.toy"},
+						Region: &sarifRegion{
+							StartLine:   1,
+							StartColumn: 6,
+							EndLine:     1,
+							EndColumn:   7,
+						},
+					},
+				}},
+			},
+		}, log.Runs[0].Results)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, stderr, code := runToy(t, "", append([]string{"check", "--format", "xml"}, args...)...)
+		require.Equal(t, exitError, code)
+		require.Contains(t, stderr, "unknown format 'xml'")
+	})
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
+
+// diagnostic is a structured representation of an error.
+type diagnostic struct {
+	Kind     string        `json:"kind"`
+	Message  string        `json:"message"`
+	Position *diagPosition `json:"position,omitempty"`
+	End      *diagPosition `json:"end,omitempty"` // position after the erroneous code
+	Excerpt  *diagExcerpt  `json:"excerpt,omitempty"`
+	Deferred []string      `json:"deferred,omitempty"`
+	Frames   []diagFrame   `json:"frames,omitempty"`
+}
+
+type diagPosition struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Offset int    `json:"offset"`
+}
+
+type diagExcerpt struct {
+	Line  int      `json:"line"`
+	Lines []string `json:"lines"`
+	Start int      `json:"start"`
+	End   int      `json:"end"`
+}
+
+type diagFrame struct {
+	Function string        `json:"function,omitempty"`
+	Module   string        `json:"module,omitempty"`
+	Position *diagPosition `json:"position,omitempty"`
+	Excerpt  *diagExcerpt  `json:"excerpt,omitempty"`
+}
+
+func newDiagPosition(pos token.FilePos) *diagPosition {
+	if !pos.IsValid() && pos.Filename == "" {
+		return nil
+	}
+	return &diagPosition{
+		File:   displayPath(pos.Filename),
+		Line:   pos.Line,
+		Column: pos.Column,
+		Offset: pos.Offset,
+	}
+}
+
+func newDiagExcerpt(e *token.Excerpt) *diagExcerpt {
+	if e == nil {
+		return nil
+	}
+	return &diagExcerpt{
+		Line:  e.Line,
+		Lines: e.Lines,
+		Start: e.Start,
+		End:   e.End,
+	}
+}
+
+// parseErrorEnd returns the end of the code highlighted by the excerpt
+// of the parse error, or the position of the error if there's no excerpt.
+func parseErrorEnd(e *parser.Error) *diagPosition {
+	end := newDiagPosition(e.Pos)
+	if end == nil || e.Excerpt == nil || len(e.Excerpt.Lines) != 1 {
+		return end
+	}
+	end.Offset += e.Excerpt.End - end.Column
+	end.Column = e.Excerpt.End
+	return end
+}
+
+// diagnosticsOf converts the error into a list of diagnostics.
+func diagnosticsOf(err error) []diagnostic {
+	var (
+		errList  parser.ErrorList
+		compErr  *toy.CompilerError
+		rtErr    *toy.RuntimeError
+		diagList []diagnostic
+	)
+	switch {
+	case errors.As(err, &errList):
+		for _, e := range errList {
+			diagList = append(diagList, diagnostic{
+				Kind:     "parse",
+				Message:  e.Msg,
+				Position: newDiagPosition(e.Pos),
+				End:      parseErrorEnd(e),
+				Excerpt:  newDiagExcerpt(e.Excerpt),
+			})
+		}
+	case errors.As(err, &compErr):
+		diagList = append(diagList, diagnostic{
+			Kind:     "compile",
+			Message:  compErr.Err.Error(),
+			Position: newDiagPosition(compErr.FileSet.Position(compErr.Node.Pos())),
+			End:      newDiagPosition(compErr.FileSet.Position(compErr.Node.End())),
+			Excerpt:  newDiagExcerpt(compErr.Excerpt()),
+		})
+	case errors.As(err, &rtErr):
+		d := diagnostic{
+			Kind:    "runtime",
+			Message: rtErr.Err.Error(),
+		}
+		for _, e := range rtErr.Deferred {
+			d.Deferred = append(d.Deferred, e.Error())
+		}
+		for _, f := range rtErr.Frames {
+			d.Frames = append(d.Frames, diagFrame{
+				Function: f.Function,
+				Module:   f.Module,
+				Position: newDiagPosition(f.Pos),
+				Excerpt:  newDiagExcerpt(f.Excerpt),
+			})
+		}
+		if len(rtErr.Frames) != 0 {
+			d.Position = d.Frames[0].Position
+			d.Excerpt = d.Frames[0].Excerpt
+		}
+		diagList = append(diagList, d)
+	default:
+		diagList = append(diagList, diagnostic{
+			Kind:    "error",
+			Message: err.Error(),
+		})
+	}
+	return diagList
+}
+
+// printError prints the error in the given format ("text" or "json").
+// Text output is coloured if w is a terminal.
+func printError(w io.Writer, err error, format string) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(w).Encode(struct {
+			Errors []diagnostic `json:"errors"`
+		}{diagnosticsOf(err)})
+	case "text", "":
+		_, err := fmt.Fprintln(w, newDiagPrinter(w).render(err))
+		return err
+	default:
+		return fmt.Errorf("unknown error format '%s'", format)
+	}
+}
+
+// diagPrinter renders errors as text in the same layout
+// as their Error methods, optionally with ANSI colours.
+type diagPrinter struct {
+	errorStyle   lipgloss.Style
+	messageStyle lipgloss.Style
+	faintStyle   lipgloss.Style
+	gutterStyle  lipgloss.Style
+	caretStyle   lipgloss.Style
+}
+
+func newDiagPrinter(w io.Writer) *diagPrinter {
+	r := lipgloss.NewRenderer(w)
+	return &diagPrinter{
+		errorStyle:   r.NewStyle().Inline(true).Bold(true).Foreground(lipgloss.Color("9")),
+		messageStyle: r.NewStyle().Inline(true).Bold(true),
+		faintStyle:   r.NewStyle().Inline(true).Faint(true),
+		gutterStyle:  r.NewStyle().Inline(true).Foreground(lipgloss.Color("12")),
+		caretStyle:   r.NewStyle().Inline(true).Bold(true).Foreground(lipgloss.Color("9")),
+	}
+}
+
+func (p *diagPrinter) render(err error) string {
+	diagList := diagnosticsOf(err)
+	text := p.renderDiagnostic(&diagList[0])
+	if len(diagList) > 1 {
+		text += "\n\n" + p.faintStyle.Render(fmt.Sprintf("(and %d more errors)", len(diagList)-1))
+	}
+	return text
+}
+
+func (p *diagPrinter) renderDiagnostic(d *diagnostic) string {
+	var b strings.Builder
+	if d.Kind == "error" {
+		return d.Message
+	}
+	b.WriteString(p.errorStyle.Render(d.Kind + " error:"))
+	b.WriteByte(' ')
+	b.WriteString(p.messageStyle.Render(d.Message))
+	switch d.Kind {
+	case "parse", "compile":
+		if d.Position != nil {
+			b.WriteString("\n")
+			b.WriteString(p.faintStyle.Render("└─ at " + formatDiagPosition(d.Position)))
+		}
+		if d.Excerpt != nil {
+			b.WriteString("\n\n")
+			b.WriteString(p.renderExcerpt(d.Excerpt))
+		}
+	case "runtime":
+		for i, msg := range d.Deferred {
+			if i != len(d.Deferred)-1 {
+				b.WriteString("\n├─ ")
+			} else {
+				b.WriteString("\n└─ ")
+			}
+			b.WriteString(msg)
+		}
+		if d.Excerpt != nil {
+			b.WriteString("\n\n")
+			b.WriteString(p.renderExcerpt(d.Excerpt))
+		}
+		if len(d.Frames) != 0 {
+			b.WriteString("\n\nstacktrace:")
+			for i, f := range d.Frames {
+				if i != len(d.Frames)-1 {
+					b.WriteString("\n├─ ")
+				} else {
+					b.WriteString("\n└─ ")
+				}
+				b.WriteString("at ")
+				if f.Function != "" {
+					b.WriteString(f.Function)
+					b.WriteString(p.faintStyle.Render(" (" + formatDiagPosition(f.Position) + ")"))
+				} else {
+					b.WriteString(p.faintStyle.Render(formatDiagPosition(f.Position)))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+func (p *diagPrinter) renderExcerpt(e *diagExcerpt) string {
+	excerpt := &token.Excerpt{
+		Line:  e.Line,
+		Lines: e.Lines,
+		Start: e.Start,
+		End:   e.End,
+	}
+	return excerpt.Render(
+		func(s string) string { return p.gutterStyle.Render(s) },
+		func(s string) string { return p.caretStyle.Render(s) },
+	)
+}
+
+func formatDiagPosition(pos *diagPosition) string {
+	if pos == nil {
+		return "-"
+	}
+	return token.FilePos{
+		Filename: pos.File,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}.String()
+}
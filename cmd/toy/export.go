@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/go-faster/jx"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/repl"
+	"github.com/infastin/toy/stdlib"
+	toyjson "github.com/infastin/toy/stdlib/json"
+	toyyaml "github.com/infastin/toy/stdlib/yaml"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "run a script and write the value it returns as JSON, YAML or TOML",
+		ArgsUsage: "FILE",
+		Description: "The exported value is the value of the top-level return statement,\n" +
+			"or the value of the expression given with --expr, e.g. a global variable.\n" +
+			"Keys of tables are written in the order they were inserted.\n" +
+			"Functions and modules can't be exported.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "expr",
+				Usage: "export the value of `EXPR` evaluated after the script, e.g. the name of a global",
+			},
+			&cli.StringFlag{
+				Name:    "format",
+				Usage:   "output format: json, yaml or toml",
+				Value:   "json",
+				Aliases: []string{"f"},
+			},
+			&cli.StringFlag{
+				Name:    "out",
+				Usage:   "write the output to `FILE` instead of the standard output",
+				Aliases: []string{"o"},
+			},
+		},
+		Action: exportAction,
+	}
+}
+
+func exportAction(ctx *cli.Context) error {
+	format := ctx.String("format")
+	if format != "json" && format != "yaml" && format != "toml" {
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("expected exactly one input file")
+	}
+	opts, err := newRunOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts.args = ctx.Args().Slice()
+
+	value, err := exportValue(ctx.Args().First(), ctx.String("expr"), opts)
+	if err != nil {
+		if err := printError(os.Stderr, err, "text"); err != nil {
+			return err
+		}
+		return cli.Exit("", exitCode(err))
+	}
+	if err := checkExportable(value, format); err != nil {
+		return err
+	}
+
+	var data []byte
+	switch format {
+	case "json":
+		data, err = encodeJSON(value)
+	case "yaml":
+		data, err = encodeYAML(value)
+	case "toml":
+		data, err = encodeTOML(value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+
+	if out := ctx.String("out"); out != "" {
+		return os.WriteFile(out, data, 0o644)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// exportValue runs the script and returns the value to export.
+func exportValue(inputFile, expr string, opts *runOptions) (toy.Value, error) {
+	inputData, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+	if len(inputData) > 1 && string(inputData[:2]) == "#!" {
+		copy(inputData, "//")
+	}
+	script, err := newScript(inputData, filepath.Dir(inputFile), opts)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := script.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	if expr == "" {
+		value := compiled.Result()
+		if value == toy.Nil {
+			return nil, fmt.Errorf("script doesn't return a value, use return or --expr")
+		}
+		return value, nil
+	}
+
+	s := repl.NewCompiledSession(compiled)
+	s.SetModules(stdlib.StdLib)
+	return s.EvalExpr(context.Background(), []byte(expr))
+}
+
+// checkExportable returns an error if the value
+// or any of its elements can't be exported in the format,
+// e.g. is a function, refers to itself or is NaN in JSON.
+func checkExportable(value toy.Value, format string) error {
+	c := &exportChecker{format: format, visiting: make(map[toy.Value]bool)}
+	return c.check(value, "")
+}
+
+type exportChecker struct {
+	format   string
+	visiting map[toy.Value]bool // containers on the path to the current value
+}
+
+// check checks the value located at the path,
+// which is used in the error message.
+func (c *exportChecker) check(value toy.Value, path string) error {
+	switch x := value.(type) {
+	case toy.Float:
+		if c.format == "json" && (math.IsInf(float64(x), 0) || math.IsNaN(float64(x))) {
+			return fmt.Errorf("%s: %s can't be represented in JSON", exportPath(path), toy.AsString(x))
+		}
+		return nil
+	case toy.String, toy.Bytes, toy.Char, toy.Int, toy.Bool, toy.NilValue:
+		return nil
+	case toy.Mapping, toy.Sequence:
+		// only pointers can form cycles and be used as map keys
+		if reflect.ValueOf(value).Kind() == reflect.Pointer {
+			if c.visiting[value] {
+				return fmt.Errorf("%s: value of type '%s' refers to itself", exportPath(path), toy.TypeName(value))
+			}
+			c.visiting[value] = true
+			defer delete(c.visiting, value)
+		}
+	case toy.Callable, *toy.BuiltinModule:
+		return fmt.Errorf("%s: value of type '%s' can't be exported", exportPath(path), toy.TypeName(value))
+	default:
+		return nil
+	}
+
+	switch x := value.(type) {
+	case toy.Mapping:
+		for key, elem := range x.Entries() {
+			keyStr, ok := key.(toy.String)
+			if !ok {
+				return fmt.Errorf("%s: unsupported key type: %s", exportPath(path), toy.TypeName(key))
+			}
+			if err := c.check(elem, path+"."+string(keyStr)); err != nil {
+				return err
+			}
+		}
+	case toy.Sequence:
+		var i int
+		for elem := range x.Elements() {
+			if err := c.check(elem, path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+func exportPath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	if path[0] == '.' {
+		return path[1:]
+	}
+	return path
+}
+
+func encodeJSON(value toy.Value) ([]byte, error) {
+	enc := &jx.Encoder{}
+	enc.SetIdent(2)
+	if err := toyjson.EncodeObject(enc, value); err != nil {
+		return nil, err
+	}
+	return append(enc.Bytes(), '\n'), nil
+}
+
+func encodeYAML(value toy.Value) ([]byte, error) {
+	node, err := toyyaml.EncodeObject(value)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
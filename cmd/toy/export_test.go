@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.toy": `
+config := {
+	name: "app",
+	debug: false,
+	ratio: 0.5,
+	empty: {},
+	tags: [],
+	servers: [{host: "a b", port: 80}, {host: "c", port: 81}],
+	owner: {name: "x", nested: {ok: true}},
+	missing: nil,
+}
+config["quoted key"] = "\"q\""
+get := fn() { return config.servers[0] }
+return config
+`,
+		"none.toy":     `x := 1`,
+		"fn.toy":       `return {a: [1, fn() {}]}`,
+		"module.toy":   `return {m: import("text")}`,
+		"cycle.toy":    `a := {}; a.self = a; return a`,
+		"inf.toy":      `return {n: 1 / 0.0}`,
+		"shared.toy":   `a := [1]; return {x: a, y: a}`,
+		"toplevel.toy": `return [1, 2]`,
+	})
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name   string
+		args   []string
+		output string
+		err    string
+	}{
+		{
+			name: "json",
+			args: []string{file("config.toy")},
+			output: `{
+  "name": "app",
+  "debug": false,
+  "ratio": 0.5,
+  "empty": {},
+  "tags": [],
+  "servers": [
+    {
+      "host": "a b",
+      "port": 80
+    },
+    {
+      "host": "c",
+      "port": 81
+    }
+  ],
+  "owner": {
+    "name": "x",
+    "nested": {
+      "ok": true
+    }
+  },
+  "missing": null,
+  "quoted key": "\"q\""
+}
+`,
+		},
+		{
+			name: "yaml",
+			args: []string{"--format", "yaml", file("config.toy")},
+			output: `name: app
+debug: false
+ratio: 0.5
+empty: {}
+tags: []
+servers:
+  - host: a b
+    port: 80
+  - host: c
+    port: 81
+owner:
+  name: x
+  nested:
+    ok: true
+missing: null
+quoted key: '"q"'
+`,
+		},
+		{
+			name: "toml",
+			args: []string{"--format", "toml", file("config.toy")},
+			output: `name = "app"
+debug = false
+ratio = 0.5
+tags = []
+"quoted key" = "\"q\""
+
+[empty]
+
+[owner]
+name = "x"
+
+[owner.nested]
+ok = true
+
+[[servers]]
+host = "a b"
+port = 80
+
+[[servers]]
+host = "c"
+port = 81
+`,
+		},
+		{
+			name:   "expr",
+			args:   []string{"--expr", "get()", file("config.toy")},
+			output: "{\n  \"host\": \"a b\",\n  \"port\": 80\n}\n",
+		},
+		{
+			name:   "shared",
+			args:   []string{file("shared.toy")},
+			output: "{\n  \"x\": [\n    1\n  ],\n  \"y\": [\n    1\n  ]\n}\n",
+		},
+		{
+			name:   "top-level array",
+			args:   []string{"-f", "yaml", file("toplevel.toy")},
+			output: "- 1\n- 2\n",
+		},
+		{
+			name: "top-level array in toml",
+			args: []string{"-f", "toml", file("toplevel.toy")},
+			err:  "failed to encode toml: TOML document must be a table, got array",
+		},
+		{
+			name: "no value",
+			args: []string{file("none.toy")},
+			err:  "script doesn't return a value, use return or --expr",
+		},
+		{
+			name: "function",
+			args: []string{file("fn.toy")},
+			err:  "a[1]: value of type 'function' can't be exported",
+		},
+		{
+			name: "module",
+			args: []string{file("module.toy")},
+			err:  `m: value of type 'module' can't be exported`,
+		},
+		{
+			name: "cycle",
+			args: []string{file("cycle.toy")},
+			err:  "self: value of type 'table' refers to itself",
+		},
+		{
+			name: "inf in json",
+			args: []string{file("inf.toy")},
+			err:  "n: +Inf can't be represented in JSON",
+		},
+		{
+			name:   "inf in yaml",
+			args:   []string{"-f", "yaml", file("inf.toy")},
+			output: "n: !!float +Inf\n",
+		},
+		{
+			name: "unknown format",
+			args: []string{"-f", "xml", file("config.toy")},
+			err:  "unknown format 'xml'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runToy(t, "", append([]string{"export"}, tt.args...)...)
+			if tt.err != "" {
+				require.NotZero(t, code)
+				require.Contains(t, stderr, tt.err)
+				return
+			}
+			require.Zero(t, code, stderr)
+			require.Equal(t, tt.output, stdout)
+		})
+	}
+}
+
+func TestExportOut(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.toy": `return {a: 1}`})
+	out := filepath.Join(dir, "out.json")
+
+	stdout, stderr, code := runToy(t, "", "export", "-o", out, filepath.Join(dir, "main.toy"))
+	require.Zero(t, code, stderr)
+	require.Empty(t, stdout)
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "{\n  \"a\": 1\n}\n", string(data))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/infastin/toy"
+)
+
+func lockCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "lock",
+		Usage:     "record hashes of the files imported by the scripts in " + toy.LockFileName,
+		ArgsUsage: "[FILE...]",
+		Description: "The lock file is written next to " + toy.ManifestFileName +
+			" or to the current directory if there's no manifest.\n" +
+			"If no files are given, all the source files of the project are used.",
+		Action: lockAction,
+	}
+}
+
+func lockAction(ctx *cli.Context) error {
+	m, err := toy.FindManifest(".")
+	if err != nil {
+		return err
+	}
+	root := "."
+	if m != nil {
+		root = m.Dir
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return err
+	}
+
+	files := ctx.Args().Slice()
+	if len(files) == 0 {
+		if files, err = projectFiles(root); err != nil {
+			return err
+		}
+	}
+
+	l := toy.NewLockfile(root)
+	for _, inputFile := range files {
+		c, err := compileFile(inputFile, m, nil)
+		if err != nil {
+			return err
+		}
+		for _, name := range c.ImportedFiles() {
+			src, _ := c.ImportedSource(name)
+			if err := l.Add(name, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	return os.WriteFile(filepath.Join(root, toy.LockFileName), l.Format(), 0o644)
+}
+
+// findLockfile returns the lock file of a script located in dir,
+// which is located next to the manifest if there's one, or in dir otherwise.
+// Returns nil if there's none.
+func findLockfile(dir string, m *toy.Manifest) (*toy.Lockfile, error) {
+	if m != nil {
+		dir = m.Dir
+	}
+	l, err := toy.ReadLockfile(filepath.Join(dir, toy.LockFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return l, err
+}
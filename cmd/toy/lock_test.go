@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	files := map[string]string{
+		"toy.mod":        "module app\n",
+		"main.toy":       `fmt := import("fmt"); util := import("./lib/util"); fmt.println(util.x)`,
+		"lib/util.toy":   `helper := import("app/lib/helper"); export x := helper.y + 1`,
+		"lib/helper.toy": `export y := 1`,
+	}
+	dir := writeFiles(t, files)
+	chdir(t, dir)
+
+	hash := func(name string) string {
+		sum := sha256.Sum256([]byte(files[name]))
+		return hex.EncodeToString(sum[:])
+	}
+
+	stdout, stderr, code := runToy(t, "", "lock", "main.toy")
+	require.Zero(t, code, stderr)
+	require.Empty(t, stdout)
+
+	data, err := os.ReadFile(filepath.Join(dir, "toy.lock"))
+	require.NoError(t, err)
+	require.Equal(t,
+		"lib/helper.toy sha256:"+hash("lib/helper.toy")+"\n"+
+			"lib/util.toy sha256:"+hash("lib/util.toy")+"\n",
+		string(data))
+
+	stdout, stderr, code = runToy(t, "", "main.toy")
+	require.Zero(t, code, stderr)
+	require.Equal(t, "2\n", stdout)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "helper.toy"), []byte(`export y := 2`), 0o644))
+	_, stderr, code = runToy(t, "", "main.toy")
+	require.Equal(t, exitCompileError, code)
+	require.Contains(t, stderr, "checksum mismatch for 'lib/helper.toy'")
+}
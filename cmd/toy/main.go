@@ -10,7 +10,9 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/infastin/toy"
+	"github.com/infastin/toy/ast"
 	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/repl"
 	"github.com/infastin/toy/stdlib"
 	"github.com/infastin/toy/token"
 )
@@ -20,54 +22,228 @@ var (
 	compilationDate = "2006-01-02 15:04:05"
 )
 
+// Exit codes distinguishing the kinds of errors.
+const (
+	exitError        = 1 // any other error, e.g. a missing file
+	exitParseError   = 2
+	exitCompileError = 3
+	exitRuntimeError = 4
+)
+
 func main() {
-	app := &cli.App{
+	if err := newApp().Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(exitError)
+	}
+}
+
+func newApp() *cli.App {
+	return &cli.App{
 		Name:      "toy",
 		Usage:     "Toy language interpreter",
 		Version:   fmt.Sprintf("%s (%s)", version, compilationDate),
-		ArgsUsage: "[FILE]",
+		ArgsUsage: "[FILE | -] [--] [ARGS...]",
+		Description: "Runs the script from the file, or from the standard input if FILE is \"-\".\n" +
+			"Starts the REPL if neither a file nor code to evaluate is given.\n" +
+			"The arguments following the script are available as os.args(),\n" +
+			"preceded by FILE, \"-\" for the standard input or \"(eval)\" for --eval.\n\n" +
+			"Exit codes: 1 for usage and I/O errors, 2 for parse errors,\n" +
+			"3 for compile errors and 4 for runtime errors.",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:    "trace",
 				Usage:   "compile and show trace",
 				Aliases: []string{"t"},
 			},
+			&cli.StringFlag{
+				Name:    "eval",
+				Usage:   "evaluate `CODE` and print the value of the last expression",
+				Aliases: []string{"e"},
+			},
+			&cli.StringFlag{
+				Name:  "error-format",
+				Usage: "error output format: text or json",
+				Value: "text",
+			},
+			&cli.StringSliceFlag{
+				Name:  "import-path",
+				Usage: "search `DIR` for imported files, before the directories from TOYPATH",
+			},
+			&cli.StringSliceFlag{
+				Name:  "ext",
+				Usage: "use `EXT` as the extension of imported files instead of .toy",
+			},
+			&cli.StringSliceFlag{
+				Name:  "module",
+				Usage: "import the file instead of the module, given as `NAME=PATH`",
+			},
+			&cli.StringSliceFlag{
+				Name:  "allow",
+				Usage: "allow only the given builtin `MODULE` or member, e.g. os or os.readfile",
+			},
+			&cli.StringSliceFlag{
+				Name:  "deny",
+				Usage: "deny the given builtin `MODULE` or member",
+			},
+		},
+		Commands: []*cli.Command{
+			modCommand(),
+			lockCommand(),
+			bundleCommand(),
+			depsCommand(),
+			attachCommand(),
+			checkCommand(),
+			exportCommand(),
 		},
 		Action: mainAction,
 	}
-	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
-	}
 }
 
 func mainAction(ctx *cli.Context) error {
-	var inputFile string
-	if args := ctx.Args(); args.Len() > 0 {
-		inputFile = args.First()
-	}
-	if inputFile == "" {
+	args := ctx.Args().Slice()
+	code, eval := ctx.String("eval"), ctx.IsSet("eval")
+	if !eval && len(args) == 0 {
 		return RunREPL(os.Stderr, os.Stdout)
 	}
-	inputData, err := os.ReadFile(inputFile)
+	errorFormat := ctx.String("error-format")
+	if errorFormat != "text" && errorFormat != "json" {
+		return fmt.Errorf("unknown error format '%s'", errorFormat)
+	}
+	opts, err := newRunOptions(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+		return err
+	}
+
+	var (
+		inputData []byte
+		inputFile string
+	)
+	switch {
+	case eval:
+		inputData, inputFile = []byte(code), "(eval)"
+	case args[0] == "-":
+		if inputData, err = io.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read standard input: %w", err)
+		}
+		inputFile, args = "(stdin)", args[1:]
+	default:
+		if inputData, err = os.ReadFile(args[0]); err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+		inputFile, args = args[0], args[1:]
+	}
+	if len(args) != 0 && args[0] == "--" {
+		args = args[1:]
 	}
 	if len(inputData) > 1 && string(inputData[:2]) == "#!" {
 		copy(inputData, "//")
 	}
-	if ctx.Bool("trace") {
-		if err := PrintTrace(inputData, inputFile); err != nil {
-			return err
-		}
-	} else {
-		if err := CompileAndRun(inputData, inputFile); err != nil {
+
+	// os.args() starts with the name of the script
+	name := inputFile
+	if inputFile == "(stdin)" {
+		name = "-"
+	}
+	opts.args = append([]string{name}, args...)
+
+	switch {
+	case ctx.Bool("trace"):
+		err = PrintTrace(inputData, inputFile)
+	case eval:
+		err = Eval(inputData, opts)
+	default:
+		err = CompileAndRun(inputData, inputFile, opts)
+	}
+	if err != nil {
+		if err := printError(os.Stderr, err, errorFormat); err != nil {
 			return err
 		}
+		return cli.Exit("", exitCode(err))
 	}
 	return nil
 }
 
+// exitCode returns the exit code for the error.
+func exitCode(err error) int {
+	switch diagnosticsOf(err)[0].Kind {
+	case "parse":
+		return exitParseError
+	case "compile":
+		return exitCompileError
+	case "runtime":
+		return exitRuntimeError
+	}
+	return exitError
+}
+
+// runOptions are the options of running scripts set by the flags.
+type runOptions struct {
+	importPath  []string
+	exts        []string
+	moduleFiles map[string]string
+	policy      *toy.Policy
+	args        []string // returned by os.args()
+}
+
+func newRunOptions(ctx *cli.Context) (*runOptions, error) {
+	opts := &runOptions{
+		importPath: append(ctx.StringSlice("import-path"), importPath()...),
+		exts:       ctx.StringSlice("ext"),
+	}
+	for _, ext := range opts.exts {
+		if ext != filepath.Ext(ext) || ext == "" {
+			return nil, fmt.Errorf("invalid file extension '%s'", ext)
+		}
+	}
+	if modules := ctx.StringSlice("module"); len(modules) != 0 {
+		opts.moduleFiles = make(map[string]string)
+		for _, module := range modules {
+			name, path, ok := strings.Cut(module, "=")
+			if !ok || name == "" || path == "" {
+				return nil, fmt.Errorf("invalid module '%s', expected NAME=PATH", module)
+			}
+			opts.moduleFiles[name] = path
+		}
+	}
+	if ctx.IsSet("allow") || ctx.IsSet("deny") {
+		opts.policy = toy.FullPolicy()
+		opts.policy.Allow = ctx.StringSlice("allow")
+		opts.policy.Deny = ctx.StringSlice("deny")
+	}
+	return opts, nil
+}
+
+// newScript creates a script configured with the options,
+// which imports files relative to the given directory.
+func newScript(inputData []byte, dir string, opts *runOptions) (*toy.Script, error) {
+	script := toy.NewScript(inputData)
+	script.SetImports(stdlib.StdLib)
+	script.EnableFileImport(true)
+	if err := script.SetImportDir(dir); err != nil {
+		return nil, err
+	}
+	manifest, err := toy.FindManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	script.SetManifest(manifest)
+	lockfile, err := findLockfile(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+	script.SetLockfile(lockfile)
+	script.SetImportPath(opts.importPath...)
+	if len(opts.exts) != 0 {
+		if err := script.SetImportFileExt(opts.exts...); err != nil {
+			return nil, err
+		}
+	}
+	script.SetModuleFiles(opts.moduleFiles)
+	script.SetPolicy(opts.policy)
+	script.SetArgs(opts.args)
+	return script, nil
+}
+
 type compileTracer struct {
 	Out []string
 }
@@ -152,24 +328,152 @@ func PrintTrace(inputData []byte, inputFile string) error {
 }
 
 // CompileAndRun compiles the source code and executes it.
-func CompileAndRun(inputData []byte, inputFile string) error {
-	script := toy.NewScript(inputData)
-	script.SetImports(stdlib.StdLib)
-	script.EnableFileImport(true)
-	if err := script.SetImportDir(filepath.Dir(inputFile)); err != nil {
+func CompileAndRun(inputData []byte, inputFile string, opts *runOptions) error {
+	dir := filepath.Dir(inputFile)
+	if inputFile == "(stdin)" {
+		dir = "."
+	}
+	script, err := newScript(inputData, dir, opts)
+	if err != nil {
 		return err
 	}
+	script.SetName(inputFile)
 	if _, err := script.Run(); err != nil {
 		return err
 	}
 	return nil
 }
 
-// RunREPL starts REPL.
-func RunREPL(in io.ReadCloser, out io.Writer) error {
-	model := newModel()
-	if err := model.Run(in, out); err != nil {
+// evalResultName is the name of the function
+// that receives the value of the last expression evaluated by Eval.
+const evalResultName = "__eval__"
+
+// Eval runs the code and prints the value of the last expression statement
+// unless it's nil.
+func Eval(inputData []byte, opts *runOptions) error {
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile("(eval)", -1, len(inputData))
+	parsed, err := parser.NewParser(file, inputData, nil).ParseFile()
+	if err != nil {
+		return err
+	}
+
+	symbolTable := toy.NewSymbolTable()
+	for i, v := range toy.Universe {
+		symbolTable.DefineBuiltin(i, v.Name())
+	}
+	globals := make([]toy.Value, toy.GlobalsSize)
+
+	var result toy.Value = toy.Nil
+	if n := len(parsed.Stmts); n != 0 {
+		if stmt, ok := parsed.Stmts[n-1].(*ast.ExprStmt); ok {
+			symbol := symbolTable.Define(evalResultName)
+			globals[symbol.Index] = toy.NewBuiltinFunction(evalResultName,
+				func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+					result = args[0]
+					return toy.Nil, nil
+				})
+			parsed.Stmts[n-1] = &ast.ExprStmt{
+				Expr: &ast.CallExpr{
+					Func: &ast.Ident{Name: evalResultName},
+					Args: []ast.Expr{stmt.Expr},
+				},
+			}
+		}
+	}
+
+	var modules toy.ModuleGetter = stdlib.StdLib
+	if opts.policy != nil {
+		modules = opts.policy.Modules(modules)
+	}
+	importDir, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	manifest, err := toy.FindManifest(importDir)
+	if err != nil {
+		return err
+	}
+	lockfile, err := findLockfile(importDir, manifest)
+	if err != nil {
+		return err
+	}
+
+	c := toy.NewCompiler(file, symbolTable, nil, modules, nil)
+	c.EnableFileImport(true)
+	c.SetImportDir(importDir)
+	c.SetImportPath(opts.importPath...)
+	if len(opts.exts) != 0 {
+		if err := c.SetImportFileExt(opts.exts...); err != nil {
+			return err
+		}
+	}
+	c.SetModuleFiles(opts.moduleFiles)
+	c.SetManifest(manifest)
+	c.SetLockfile(lockfile)
+	if err := c.Compile(parsed); err != nil {
 		return err
 	}
+
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	rt := toy.NewRuntime(bytecode, globals)
+	rt.SetPolicy(opts.policy)
+	rt.SetArgs(opts.args)
+	if err := rt.Run(); err != nil {
+		return err
+	}
+	if result != toy.Nil {
+		fmt.Println(toy.AsString(result))
+	}
 	return nil
 }
+
+// compileFile compiles the source file the same way CompileAndRun does,
+// so the returned compiler can be inspected.
+func compileFile(inputFile string, m *toy.Manifest, l *toy.Lockfile) (*toy.Compiler, error) {
+	inputData, err := os.ReadFile(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputData) > 1 && string(inputData[:2]) == "#!" {
+		copy(inputData, "//")
+	}
+	importDir, err := filepath.Abs(filepath.Dir(inputFile))
+	if err != nil {
+		return nil, err
+	}
+
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile(inputFile, -1, len(inputData))
+	parsed, err := parser.NewParser(file, inputData, nil).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	c := toy.NewCompiler(file, nil, nil, stdlib.StdLib, nil)
+	c.EnableFileImport(true)
+	c.SetImportDir(importDir)
+	c.SetImportPath(importPath()...)
+	c.SetManifest(m)
+	c.SetLockfile(l)
+	if err := c.Compile(parsed); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RunREPL starts REPL.
+func RunREPL(in io.ReadCloser, out io.Writer) error {
+	s := repl.NewSession()
+	s.SetModules(stdlib.StdLib.Copy())
+	r := repl.New(s)
+	r.SetBanner(fmt.Sprintf("Toy Language %s (%s)", version, compilationDate))
+	r.SetInput(in)
+	r.SetOutput(out)
+	if history, err := repl.DefaultHistoryFile(); err == nil {
+		r.SetHistoryFile(history)
+	}
+	return r.Run()
+}
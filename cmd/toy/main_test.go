@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+// runToy runs the command with the given arguments and standard input
+// and returns its standard output, standard error and exit code.
+func runToy(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := make([]*os.File, 3)
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		defer f.Close()
+		files[i] = f
+	}
+	_, err := files[0].WriteString(stdin)
+	require.NoError(t, err)
+	_, err = files[0].Seek(0, 0)
+	require.NoError(t, err)
+
+	oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+	oldExiter, oldErrWriter := cli.OsExiter, cli.ErrWriter
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr
+		cli.OsExiter, cli.ErrWriter = oldExiter, oldErrWriter
+	}()
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	cli.OsExiter = func(c int) { code = c }
+	cli.ErrWriter = files[2]
+
+	if err := newApp().Run(append([]string{"toy"}, args...)); err != nil && code == 0 {
+		files[2].WriteString(err.Error() + "\n")
+		code = exitError
+	}
+
+	out, err := os.ReadFile(files[1].Name())
+	require.NoError(t, err)
+	errOut, err := os.ReadFile(files[2].Name())
+	require.NoError(t, err)
+	return string(out), strings.TrimSpace(string(errOut)), code
+}
+
+// writeFiles writes the files relative to a new temporary directory
+// and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, []byte(data), 0o644))
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"args.toy":      "#!/usr/bin/env toy\nfmt := import(\"fmt\")\nfmt.println(import(\"os\").args())",
+		"parse.toy":     `x := `,
+		"compile.toy":   `x := y`,
+		"runtime.toy":   `x := 1 + "a"`,
+		"multiline.toy": "x := 1 +\n\t\"a\"",
+		"throw.toy":     `throw "failed"`,
+		"import.toy":    `fmt := import("fmt"); fmt.println(import("lib/util").name)`,
+		"lib/util.toy":  `return {name: "util"}`,
+	})
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name   string
+		stdin  string
+		args   []string
+		output string
+		err    string
+		code   int
+	}{
+		{
+			name:   "file args",
+			args:   []string{file("args.toy"), "a", "b"},
+			output: "[\"" + file("args.toy") + "\", \"a\", \"b\"]\n",
+		},
+		{
+			name:   "file args after separator",
+			args:   []string{file("args.toy"), "--", "-e", "b"},
+			output: "[\"" + file("args.toy") + "\", \"-e\", \"b\"]\n",
+		},
+		{
+			name:   "stdin args",
+			stdin:  `fmt := import("fmt"); fmt.println(import("os").args())`,
+			args:   []string{"-", "a", "b"},
+			output: "[\"-\", \"a\", \"b\"]\n",
+		},
+		{
+			name:   "eval",
+			args:   []string{"-e", "1 + 2"},
+			output: "3\n",
+		},
+		{
+			name:   "eval args",
+			args:   []string{"-e", `import("os").args()`, "a"},
+			output: "[\"(eval)\", \"a\"]\n",
+		},
+		{
+			name:   "import",
+			args:   []string{file("import.toy")},
+			output: "util\n",
+		},
+		{
+			name: "missing file",
+			args: []string{file("missing.toy")},
+			err:  "failed to read input file",
+			code: exitError,
+		},
+		{
+			name: "unknown error format",
+			args: []string{"--error-format", "xml", file("args.toy")},
+			err:  "unknown error format 'xml'",
+			code: exitError,
+		},
+		{
+			name: "parse error",
+			args: []string{file("parse.toy")},
+			err:  "parse error",
+			code: exitParseError,
+		},
+		{
+			name: "compile error",
+			args: []string{file("compile.toy")},
+			err:  "unresolved reference 'y'",
+			code: exitCompileError,
+		},
+		{
+			name: "runtime error",
+			args: []string{file("runtime.toy")},
+			err:  "invalid operation",
+			code: exitRuntimeError,
+		},
+		{
+			name: "runtime error position",
+			args: []string{file("runtime.toy")},
+			err:  "└─ at " + displayPath(file("runtime.toy")) + ":1:6",
+			code: exitRuntimeError,
+		},
+		{
+			name:  "stdin runtime error position",
+			stdin: `x := 1 + "a"`,
+			args:  []string{"-"},
+			err:   "└─ at (stdin):1:6",
+			code:  exitRuntimeError,
+		},
+		{
+			name: "multi-line runtime error",
+			args: []string{file("multiline.toy")},
+			err:  " 1 | x := 1 +\n   |      ^^^\n 2 | \t\"a\"\n   | \t^^^\n",
+			code: exitRuntimeError,
+		},
+		{
+			name: "thrown error",
+			args: []string{file("throw.toy")},
+			err:  "failed",
+			code: exitRuntimeError,
+		},
+		{
+			name: "json error",
+			args: []string{"--error-format", "json", file("compile.toy")},
+			err:  `"kind":"compile"`,
+			code: exitCompileError,
+		},
+		{
+			name: "eval runtime error",
+			args: []string{"-e", `1 + "a"`},
+			err:  "invalid operation",
+			code: exitRuntimeError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runToy(t, tt.stdin, tt.args...)
+			require.Equal(t, tt.code, code, stderr)
+			if tt.err != "" {
+				require.Contains(t, stderr, tt.err)
+				return
+			}
+			require.Empty(t, stderr)
+			require.Equal(t, tt.output, stdout)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/ast"
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/stdlib"
+	"github.com/infastin/toy/token"
+)
+
+func modCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mod",
+		Usage: "manage the " + toy.ManifestFileName + " manifest",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "vendor",
+				Usage:  "copy requirements into the vendor directory",
+				Action: modVendorAction,
+			},
+			{
+				Name:   "tidy",
+				Usage:  "remove unused requirements and report unresolved imports",
+				Action: modTidyAction,
+			},
+		},
+	}
+}
+
+// importPath returns the list of directories
+// searched for modules, which is set by TOYPATH.
+func importPath() []string {
+	return filepath.SplitList(os.Getenv("TOYPATH"))
+}
+
+// findManifest looks for the manifest in the current directory and its parents.
+func findManifest() (*toy.Manifest, error) {
+	m, err := toy.FindManifest(".")
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("%s not found in current directory or any parent directory",
+			toy.ManifestFileName)
+	}
+	return m, nil
+}
+
+// requirementSource returns the directory containing modules of the requirement.
+func requirementSource(m *toy.Manifest, r *toy.Requirement) (string, error) {
+	if r.Dir != "" {
+		if filepath.IsAbs(r.Dir) {
+			return r.Dir, nil
+		}
+		return filepath.Join(m.Dir, r.Dir), nil
+	}
+	for _, dir := range importPath() {
+		src := filepath.Join(dir, r.Prefix+"@"+r.Version)
+		if fi, err := os.Stat(src); err == nil && fi.IsDir() {
+			return src, nil
+		}
+	}
+	return "", fmt.Errorf("%s@%s not found in TOYPATH", r.Prefix, r.Version)
+}
+
+func modVendorAction(ctx *cli.Context) error {
+	m, err := findManifest()
+	if err != nil {
+		return err
+	}
+	sources := make([]string, len(m.Requires))
+	for i, r := range m.Requires {
+		if sources[i], err = requirementSource(m, r); err != nil {
+			return err
+		}
+	}
+	vendorDir := filepath.Join(m.Dir, toy.VendorDirName)
+	if err := os.RemoveAll(vendorDir); err != nil {
+		return err
+	}
+	if len(m.Requires) == 0 {
+		return nil
+	}
+	var modules strings.Builder
+	for i, r := range m.Requires {
+		if err := copyDir(filepath.Join(vendorDir, filepath.FromSlash(r.Prefix)), sources[i]); err != nil {
+			return fmt.Errorf("failed to vendor '%s': %w", r.Prefix, err)
+		}
+		fmt.Fprintf(&modules, "# %s %s\n", r.Prefix, r.Location())
+	}
+	return os.WriteFile(filepath.Join(vendorDir, "modules.txt"), []byte(modules.String()), 0o644)
+}
+
+// copyDir copies the files of src into dst
+// skipping hidden and vendor directories.
+func copyDir(dst, src string) error {
+	return filepath.WalkDir(src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == toy.VendorDirName) {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+}
+
+func modTidyAction(ctx *cli.Context) error {
+	m, err := findManifest()
+	if err != nil {
+		return err
+	}
+	files, err := projectFiles(m.Dir)
+	if err != nil {
+		return err
+	}
+
+	used := make(map[*toy.Requirement]bool)
+	var unresolved int
+	for _, name := range files {
+		imports, err := fileImports(name)
+		if err != nil {
+			return err
+		}
+		importDir, err := filepath.Abs(filepath.Dir(name))
+		if err != nil {
+			return err
+		}
+		resolver := toy.NewCompiler(nil, nil, nil, nil, nil)
+		resolver.SetImportDir(importDir)
+		resolver.SetImportPath(importPath()...)
+		resolver.SetManifest(m)
+		for _, imp := range imports {
+			if stdlib.StdLib.Get(imp.name) != nil {
+				continue
+			}
+			if r, _, ok := m.Lookup(imp.name); ok && r.Prefix != m.Module {
+				used[m.Require(r.Prefix)] = true
+				continue
+			}
+			if _, err := resolver.ResolveModule(imp.name); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: no requirement provides module '%s'\n", imp.pos, imp.name)
+				unresolved++
+			}
+		}
+	}
+
+	requires := make([]*toy.Requirement, 0, len(m.Requires))
+	for _, r := range m.Requires {
+		if used[r] {
+			requires = append(requires, r)
+		} else {
+			fmt.Fprintf(os.Stderr, "removed unused requirement '%s'\n", r.Prefix)
+		}
+	}
+	slices.SortFunc(requires, func(a, b *toy.Requirement) int {
+		return strings.Compare(a.Prefix, b.Prefix)
+	})
+	m.Requires = requires
+
+	if err := os.WriteFile(filepath.Join(m.Dir, toy.ManifestFileName), m.Format(), 0o644); err != nil {
+		return err
+	}
+	if unresolved != 0 {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// projectFiles returns the source files of the project located in dir,
+// excluding vendored files and nested projects.
+func projectFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name == dir {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || name == filepath.Join(dir, toy.VendorDirName) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(name, toy.ManifestFileName)); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) == toy.SourceFileExtDefault {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+type importRef struct {
+	name string
+	pos  token.FilePos
+}
+
+// fileImports returns the modules imported by the source file.
+func fileImports(name string) ([]importRef, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 1 && string(data[:2]) == "#!" {
+		copy(data, "//")
+	}
+	fileSet := token.NewFileSet()
+	file := fileSet.AddFile(name, -1, len(data))
+	parsed, err := parser.NewParser(file, data, nil).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	var imports []importRef
+	ast.Inspect(parsed, func(node ast.Node) bool {
+		if imp, ok := node.(*ast.ImportExpr); ok {
+			imports = append(imports, importRef{
+				name: imp.ModuleName,
+				pos:  fileSet.Position(imp.Pos()),
+			})
+		}
+		return true
+	})
+	return imports, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// chdir changes the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestModTidy(t *testing.T) {
+	tests := []struct {
+		name     string
+		main     string
+		manifest string
+		err      string
+		code     int
+	}{
+		{
+			name:     "remove unused",
+			main:     `import("shared/used/log")`,
+			manifest: "module app\n\nrequire shared/used => ../libs/used\n",
+			err:      "removed unused requirement 'shared/unused'",
+		},
+		{
+			name:     "project module",
+			main:     `import("app/lib/util"); import("./lib/util"); import("fmt")`,
+			manifest: "module app\n",
+			err:      "removed unused requirement 'shared/used'",
+		},
+		{
+			name:     "import path",
+			main:     `import("global")`,
+			manifest: "module app\n",
+			err:      "removed unused requirement 'shared/used'",
+		},
+		{
+			name:     "unresolved",
+			main:     `import("shared/used/log")` + "\n" + `import("app/missing")`,
+			manifest: "module app\n\nrequire shared/used => ../libs/used\n",
+			err:      "main.toy:2:1: no requirement provides module 'app/missing'",
+			code:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"app/toy.mod": "module app\n\nrequire (\n" +
+					"\tshared/unused => ../libs/unused\n" +
+					"\tshared/used => ../libs/used\n" +
+					")\n",
+				"app/main.toy":        tt.main,
+				"app/lib/util.toy":    `export x := 1`,
+				"libs/used/log.toy":   `export x := 1`,
+				"libs/unused/io.toy":  `export x := 1`,
+				"toypath/global.toy":  `export x := 1`,
+				"app/vendor/skip.toy": `import("skipped")`,
+			})
+			chdir(t, filepath.Join(dir, "app"))
+			t.Setenv("TOYPATH", filepath.Join(dir, "toypath"))
+
+			_, stderr, code := runToy(t, "", "mod", "tidy")
+			require.Equal(t, tt.code, code, stderr)
+			require.Contains(t, stderr, tt.err)
+
+			data, err := os.ReadFile(filepath.Join(dir, "app", "toy.mod"))
+			require.NoError(t, err)
+			require.Equal(t, tt.manifest, string(data))
+		})
+	}
+}
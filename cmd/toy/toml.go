@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/infastin/toy"
+)
+
+// encodeTOML encodes the table as a TOML document.
+// Nil values of tables are omitted, since TOML has no null value.
+func encodeTOML(value toy.Value) ([]byte, error) {
+	m, ok := value.(toy.Mapping)
+	if !ok {
+		return nil, fmt.Errorf("TOML document must be a table, got %s", toy.TypeName(value))
+	}
+	e := &tomlEncoder{}
+	if err := e.table(nil, m); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type tomlEncoder struct {
+	buf bytes.Buffer
+}
+
+type tomlEntry struct {
+	key   string
+	value toy.Value
+}
+
+// table writes the entries of the table with the given path of keys:
+// simple values first, then tables and arrays of tables.
+func (e *tomlEncoder) table(path []string, m toy.Mapping) error {
+	var simple, tables, arrays []tomlEntry
+	for key, value := range m.Entries() {
+		keyStr, ok := key.(toy.String)
+		if !ok {
+			return fmt.Errorf("unsupported key type: %s", toy.TypeName(key))
+		}
+		entry := tomlEntry{key: string(keyStr), value: value}
+		switch {
+		case value == toy.Nil:
+			continue
+		case isTOMLTable(value):
+			tables = append(tables, entry)
+		case isTOMLArrayOfTables(value):
+			arrays = append(arrays, entry)
+		default:
+			simple = append(simple, entry)
+		}
+	}
+
+	for _, entry := range simple {
+		e.buf.WriteString(tomlKey(entry.key))
+		e.buf.WriteString(" = ")
+		if err := e.value(entry.value); err != nil {
+			return fmt.Errorf("%s: %w", entry.key, err)
+		}
+		e.buf.WriteByte('\n')
+	}
+	for _, entry := range tables {
+		tablePath := append(slices.Clip(path), entry.key)
+		e.header("[", tablePath, "]")
+		if err := e.table(tablePath, entry.value.(toy.Mapping)); err != nil {
+			return fmt.Errorf("%s: %w", entry.key, err)
+		}
+	}
+	for _, entry := range arrays {
+		tablePath := append(slices.Clip(path), entry.key)
+		for elem := range entry.value.(toy.Sequence).Elements() {
+			e.header("[[", tablePath, "]]")
+			if err := e.table(tablePath, elem.(toy.Mapping)); err != nil {
+				return fmt.Errorf("%s: %w", entry.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *tomlEncoder) header(open string, path []string, close string) {
+	if e.buf.Len() != 0 {
+		e.buf.WriteByte('\n')
+	}
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	e.buf.WriteString(open + strings.Join(keys, ".") + close + "\n")
+}
+
+// value writes the value inline.
+func (e *tomlEncoder) value(value toy.Value) error {
+	switch x := value.(type) {
+	case toy.String:
+		e.buf.WriteString(tomlString(string(x)))
+	case toy.Char:
+		e.buf.WriteString(tomlString(string(x)))
+	case toy.Bytes:
+		e.buf.WriteString(tomlString(base64.StdEncoding.EncodeToString(x)))
+	case toy.Int:
+		e.buf.WriteString(strconv.FormatInt(int64(x), 10))
+	case toy.Float:
+		e.buf.WriteString(tomlFloat(float64(x)))
+	case toy.Bool:
+		e.buf.WriteString(strconv.FormatBool(bool(x)))
+	case toy.NilValue:
+		return fmt.Errorf("nil can't be represented in TOML")
+	case toy.Mapping:
+		e.buf.WriteByte('{')
+		var i int
+		for key, elem := range x.Entries() {
+			keyStr, ok := key.(toy.String)
+			if !ok {
+				return fmt.Errorf("unsupported key type: %s", toy.TypeName(key))
+			}
+			if elem == toy.Nil {
+				continue
+			}
+			if i != 0 {
+				e.buf.WriteByte(',')
+			}
+			e.buf.WriteString(" " + tomlKey(string(keyStr)) + " = ")
+			if err := e.value(elem); err != nil {
+				return fmt.Errorf("%s: %w", string(keyStr), err)
+			}
+			i++
+		}
+		if i != 0 {
+			e.buf.WriteByte(' ')
+		}
+		e.buf.WriteByte('}')
+	case toy.Sequence:
+		e.buf.WriteByte('[')
+		var i int
+		for elem := range x.Elements() {
+			if i != 0 {
+				e.buf.WriteString(", ")
+			}
+			if err := e.value(elem); err != nil {
+				return err
+			}
+			i++
+		}
+		e.buf.WriteByte(']')
+	default:
+		e.buf.WriteString(tomlString(toy.AsString(x)))
+	}
+	return nil
+}
+
+func isTOMLTable(value toy.Value) bool {
+	switch value.(type) {
+	case toy.String, toy.Bytes:
+		return false
+	case toy.Mapping:
+		return true
+	}
+	return false
+}
+
+func isTOMLArrayOfTables(value toy.Value) bool {
+	switch value.(type) {
+	case toy.String, toy.Bytes:
+		return false
+	}
+	seq, ok := value.(toy.Sequence)
+	if !ok || seq.Len() == 0 {
+		return false
+	}
+	for elem := range seq.Elements() {
+		if !isTOMLTable(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlKey returns the key, which is quoted unless it's a bare key.
+func tomlKey(key string) string {
+	bare := key != "" && strings.IndexFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+	}) == -1
+	if bare {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString returns the string quoted as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func tomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
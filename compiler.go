@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"unicode"
 
@@ -20,9 +23,10 @@ import (
 // and the last two instructions that were emitted.
 type compilationScope struct {
 	instructions []byte
-	sourceMap    map[int]token.Pos
-	deferMap     []token.Pos
+	sourceMap    map[int]sourceRange
+	deferMap     []sourceRange
 	labels       map[string]int
+	tries        []*tryBlock
 }
 
 // loop represents a loop construct that
@@ -31,6 +35,14 @@ type loop struct {
 	label     string
 	continues []int
 	breaks    []int
+	tries     int // number of enclosing try blocks
+}
+
+// tryBlock represents a block of a try statement that
+// the compiler uses to leave it with return, break or continue.
+type tryBlock struct {
+	handler bool           // whether an exception handler is installed
+	finally *ast.BlockStmt // finally block; or nil
 }
 
 // CompilerError represents a compiler error.
@@ -42,9 +54,29 @@ type CompilerError struct {
 
 func (e *CompilerError) Error() string {
 	filePos := e.FileSet.Position(e.Node.Pos())
+	if excerpt := e.Excerpt(); excerpt != nil {
+		return fmt.Sprintf("compile error: %s\n└─ at %s\n\n%s", e.Err.Error(), filePos, excerpt)
+	}
 	return fmt.Sprintf("compile error: %s\n└─ at %s", e.Err.Error(), filePos)
 }
 
+// Excerpt returns the excerpt of the source code spanning the node,
+// or nil if the source code is not available.
+func (e *CompilerError) Excerpt() *token.Excerpt {
+	return e.FileSet.Excerpt(e.Node.Pos(), e.Node.End())
+}
+
+func (e *CompilerError) Unwrap() error {
+	return e.Err
+}
+
+// Import represents an import of a module recorded by the compiler.
+type Import struct {
+	File string // name of the importing file
+	Name string // name of the imported module
+	Path string // path of the imported file; or empty if the module is not a file
+}
+
 // Compiler compiles the AST into a bytecode.
 type Compiler struct {
 	file            *token.File
@@ -52,17 +84,29 @@ type Compiler struct {
 	modulePath      string
 	importDir       string
 	importFileExt   []string
+	importPath      []string
+	moduleFiles     map[string]string // files imported instead of modules
+	manifest        *Manifest
+	lockfile        *Lockfile
 	constants       []Value
 	symbolTable     *SymbolTable
 	scopes          []compilationScope
 	scopeIndex      int
 	modules         ModuleGetter
 	compiledModules map[string]*CompiledFunction
+	moduleExports   map[string][]string
+	importedFiles   []string
+	importedSrcs    map[string][]byte // sources of imported files by paths
+	imports         []Import
 	allowFileImport bool
+	fs              *FS
 	loops           []*loop
 	loopIndex       int
 	trace           io.Writer
 	indent          int
+	funcName        string // name for the next function literal
+	exporting       bool   // whether the file has export declarations
+	exports         []*Symbol
 }
 
 // NewCompiler creates a Compiler.
@@ -74,7 +118,7 @@ func NewCompiler(
 	trace io.Writer,
 ) *Compiler {
 	mainScope := compilationScope{
-		sourceMap: make(map[int]token.Pos),
+		sourceMap: make(map[int]sourceRange),
 		labels:    make(map[string]int),
 	}
 
@@ -103,7 +147,9 @@ func NewCompiler(
 		trace:           trace,
 		modules:         modules,
 		compiledModules: make(map[string]*CompiledFunction),
+		moduleExports:   make(map[string][]string),
 		importFileExt:   []string{SourceFileExtDefault},
+		fs:              hostFS,
 	}
 }
 
@@ -120,11 +166,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	switch node := node.(type) {
 	case *ast.File:
+		c.exporting = slices.ContainsFunc(node.Stmts, func(stmt ast.Stmt) bool {
+			_, ok := stmt.(*ast.ExportStmt)
+			return ok
+		})
 		for _, stmt := range node.Stmts {
 			if err := c.Compile(stmt); err != nil {
 				return err
 			}
 		}
+		if c.exporting && c.parent != nil {
+			// the value of a module with exports is the frozen table of them
+			c.emitExports(node)
+		}
 		// code optimization
 		c.optimizeFunc(node)
 	case *ast.ExprStmt:
@@ -302,6 +356,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			curLoop = c.loops[c.loopIndex]
 		}
 
+		if err := c.compileTryExits(node, curLoop.tries); err != nil {
+			return err
+		}
+
 		switch node.Token {
 		case token.Break:
 			pos := c.emit(node, bytecode.OpJump, 0)
@@ -391,6 +449,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 						return err
 					}
 				}
+				if key, ok := expr.Key.(*ast.Ident); ok {
+					if _, ok := expr.Value.(*ast.FuncLit); ok {
+						c.funcName = key.Name
+					}
+				}
 				if err := c.Compile(expr.Value); err != nil {
 					return err
 				}
@@ -432,6 +495,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(node, bytecode.OpSliceIndex, indices)
 	case *ast.FuncLit:
+		funcName := c.funcName
+		c.funcName = ""
+
 		c.enterScope()
 
 		for _, p := range node.Type.Params.List {
@@ -517,6 +583,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			varArgs:       node.Type.Params.VarArgs,
 			sourceMap:     scope.sourceMap,
 			deferMap:      scope.deferMap,
+			name:          funcName,
+			module:        c.modulePath,
 		}
 
 		if len(freeSymbols) > 0 {
@@ -526,6 +594,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(node, bytecode.OpConstant, c.addConstant(compiledFunction))
 		}
 	case *ast.ReturnStmt:
+		if c.exporting && c.scopeIndex == 0 {
+			return c.errorf(node, "return is not allowed in a module with exports")
+		}
 		for _, result := range node.Results {
 			if err := c.Compile(result); err != nil {
 				return err
@@ -534,6 +605,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if len(node.Results) > 1 {
 			c.emit(node, bytecode.OpTuple, len(node.Results), 0)
 		}
+		if err := c.compileTryExits(node, 0); err != nil {
+			return err
+		}
 		var hasResults int
 		if len(node.Results) != 0 {
 			hasResults = 1
@@ -547,7 +621,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		deferIdx := c.addDeferPos(node.CallExpr.Pos())
+		deferIdx := c.addDeferPos(node.CallExpr)
 		c.emit(node, bytecode.OpDefer, len(node.CallExpr.Args), splat, deferIdx)
 	case *ast.CallExpr:
 		if err := c.Compile(node.Func); err != nil {
@@ -562,7 +636,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if node.ModuleName == "" {
 			return c.errorf(node, "empty module name")
 		}
-		if mod := c.modules.Get(node.ModuleName); mod != nil {
+		_, overridden := c.moduleFiles[node.ModuleName]
+		if mod := c.modules.Get(node.ModuleName); mod != nil && !overridden {
 			switch mod := mod.(type) {
 			case SourceModule:
 				compiled, err := c.compileModule(node, node.ModuleName, mod, false)
@@ -576,7 +651,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			default:
 				panic(fmt.Errorf("invalid import value type: %T", mod))
 			}
-		} else if c.allowFileImport {
+			c.storeImport(Import{File: c.file.Name, Name: node.ModuleName})
+		} else if c.allowFileImport || overridden {
 			moduleName := node.ModuleName
 
 			modulePath, err := c.getPathModule(moduleName)
@@ -585,12 +661,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 					err.Error())
 			}
 
-			moduleSrc, err := os.ReadFile(modulePath)
+			moduleSrc, err := c.fs.ReadFile(modulePath)
 			if err != nil {
 				return c.errorf(node, "module file read error: %s",
 					err.Error())
 			}
 
+			if c.lockfile != nil {
+				if err := c.lockfile.verify(c.fs, modulePath, moduleSrc); err != nil {
+					return c.errorf(node, "module file verification error: %s",
+						err.Error())
+				}
+			}
+
 			compiled, err := c.compileModule(node, modulePath, moduleSrc, true)
 			if err != nil {
 				return err
@@ -598,9 +681,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			c.emit(node, bytecode.OpConstant, c.addConstant(compiled))
 			c.emit(node, bytecode.OpCall, 0, 0)
+			c.storeImport(Import{File: c.file.Name, Name: moduleName, Path: modulePath})
 		} else {
 			return c.errorf(node, "module '%s' not found", node.ModuleName)
 		}
+	case *ast.ExportStmt:
+		if err := c.compileExportStmt(node); err != nil {
+			return err
+		}
 	case *ast.TryExpr:
 		if err := c.Compile(node.CallExpr.Func); err != nil {
 			return err
@@ -610,6 +698,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		c.emit(node, bytecode.OpTry, len(node.CallExpr.Args), splat)
+	case *ast.TryStmt:
+		if err := c.compileTryStmt(node); err != nil {
+			return err
+		}
 	case *ast.ThrowStmt:
 		for _, e := range node.Errors {
 			if err := c.Compile(e); err != nil {
@@ -660,6 +752,7 @@ func (c *Compiler) Bytecode() *Bytecode {
 			instructions: c.currentInstructions(),
 			sourceMap:    c.currentSourceMap(),
 			deferMap:     c.currentDeferMap(),
+			module:       c.modulePath,
 		},
 		Constants: c.constants,
 	}
@@ -671,11 +764,64 @@ func (c *Compiler) EnableFileImport(enable bool) {
 	c.allowFileImport = enable
 }
 
+// SetFS sets the filesystem used for file imports.
+// If fsys is nil, the host filesystem is used.
+func (c *Compiler) SetFS(fsys fs.FS) {
+	c.fs = NewFS(fsys)
+}
+
 // SetImportDir sets the initial import directory path for file imports.
 func (c *Compiler) SetImportDir(dir string) {
 	c.importDir = dir
 }
 
+// SetImportPath sets the list of directories searched for file imports
+// that can't be found in the import directory or using the manifest.
+func (c *Compiler) SetImportPath(dirs ...string) {
+	c.importPath = dirs
+}
+
+// SetManifest sets the manifest used to resolve file imports.
+func (c *Compiler) SetManifest(m *Manifest) {
+	c.manifest = m
+}
+
+// SetLockfile sets the lock file used to verify the contents of imported files.
+// If l is not nil, compilation fails if an imported file
+// is missing from the lock file or doesn't match its hash.
+func (c *Compiler) SetLockfile(l *Lockfile) {
+	c.lockfile = l
+}
+
+// ImportedFiles returns the paths of the files
+// imported during the compilation in the order of their compilation.
+func (c *Compiler) ImportedFiles() []string {
+	return slices.Clone(c.importedFiles)
+}
+
+// ResolveModule returns the path of the file imported
+// for the module with the given name, looking for it the same way
+// file imports do: in the import directory, then in the directories
+// of the manifest requirements, and then in the import path.
+func (c *Compiler) ResolveModule(name string) (string, error) {
+	return c.getPathModule(name)
+}
+
+// ImportedSource returns the source of the imported file
+// as it has been read during the compilation.
+// The returned boolean is false if the file hasn't been imported.
+func (c *Compiler) ImportedSource(name string) ([]byte, bool) {
+	src, ok := c.importedSrcs[name]
+	return src, ok
+}
+
+// Imports returns the imports of modules made during the compilation,
+// including the imports made by the imported modules.
+// Each module is listed once per importing file.
+func (c *Compiler) Imports() []Import {
+	return slices.Clone(c.imports)
+}
+
 // SetImportFileExt sets the extension name of the source file for loading
 // local module files.
 //
@@ -702,6 +848,14 @@ func (c *Compiler) SetImportFileExt(exts ...string) error {
 	return nil
 }
 
+// SetModuleFiles sets the files imported instead of the modules
+// with the given names, which take precedence over the builtin modules
+// and the files found in the import directories.
+// The files are imported even if file import is disabled.
+func (c *Compiler) SetModuleFiles(files map[string]string) {
+	c.moduleFiles = files
+}
+
 // GetImportFileExt returns the current list of extension name.
 // Thease are the complementary suffix of the source file to search and load
 // local module files.
@@ -731,6 +885,14 @@ func (c *Compiler) compileSelectorExpr(node *ast.SelectorExpr, withOk bool) erro
 	if err := c.Compile(node.Expr); err != nil {
 		return err
 	}
+	if !withOk {
+		// members of imported source modules are known at compile time
+		if members := c.selectorMembers(node.Expr); members != nil &&
+			!slices.Contains(members.names, node.Sel.Name) {
+			return c.errorf(node.Sel, "module '%s' has no member '%s'",
+				members.module, node.Sel.Name)
+		}
+	}
 	c.emit(node.Sel, bytecode.OpConstant, c.addConstant(String(node.Sel.Name)))
 	returnBool := 0
 	if withOk {
@@ -780,6 +942,9 @@ func (c *Compiler) compileAssign(
 		if !exists {
 			return c.errorf(ident, "unresolved reference '%s'", ident.Name)
 		}
+		if !hasSel {
+			symbol.forgetMembers()
+		}
 	}
 
 	// +=, -=, *=, /=
@@ -949,6 +1114,10 @@ func (c *Compiler) compileAssignDefine(
 
 	if !unpacking {
 		for j := len(rhs) - 1; j >= 0; j-- {
+			if resolved[j].isFunc {
+				// name the function after the variable or selector
+				c.funcName = lhs[j].String()
+			}
 			// compile RHSs
 			if err := c.Compile(rhs[j]); err != nil {
 				return err
@@ -980,6 +1149,13 @@ func (c *Compiler) compileAssignDefine(
 	for j, lr := range resolved {
 		if op == token.Define && (!lr.exists || lr.depth > 0) && !lr.isFunc {
 			lr.symbol = c.symbolTable.Define(lr.ident.Name)
+			if !unpacking {
+				if imp, ok := rhs[j].(*ast.ImportExpr); ok {
+					lr.symbol.members = c.importMembers(imp)
+				}
+			}
+		} else if lr.symbol != nil && !lr.hasSel {
+			lr.symbol.forgetMembers()
 		}
 
 		if lr.hasSel {
@@ -1253,6 +1429,238 @@ func (c *Compiler) compileForInStmt(stmt *ast.ForInStmt, label string) error {
 	return nil
 }
 
+func (c *Compiler) compileTryStmt(stmt *ast.TryStmt) error {
+	// try { body } catch e { catch } finally { finally }
+	//
+	//   TRYBEGIN catch         ; handler is finally_err if there's no catch
+	//   body
+	//   TRYEND
+	//   JMP finally
+	// catch:                   ; the caught error is on the stack
+	//   TRYBEGIN finally_err   ; if there's a finally block
+	//   e := pop()
+	//   catch
+	//   TRYEND
+	//   JMP finally
+	// finally_err:             ; if there's a finally block
+	//   :err := pop()
+	//   finally
+	//   throw :err             ; rethrows the original error
+	// finally:
+	//   finally
+	//
+	// ":err" is a local variable but it will not conflict with other user variables
+	// because character ":" is not allowed in the variable names.
+
+	var jumps []int // jumps to the finally block
+
+	// body
+	bodyHandlerPos := c.emit(stmt, bytecode.OpTryBegin, 0)
+	c.enterTry(true, stmt.Finally)
+	if err := c.Compile(stmt.Body); err != nil {
+		return err
+	}
+	c.leaveTry()
+	c.emit(stmt, bytecode.OpTryEnd)
+	jumps = append(jumps, c.emit(stmt, bytecode.OpJump, 0))
+
+	// catch
+	if stmt.Catch != nil {
+		c.changeOperand(bodyHandlerPos, len(c.currentInstructions()))
+		catchHandlerPos := -1
+		if stmt.Finally != nil {
+			catchHandlerPos = c.emit(stmt.Catch, bytecode.OpTryBegin, 0)
+			c.enterTry(true, stmt.Finally)
+		}
+		if err := c.compileCatch(stmt); err != nil {
+			return err
+		}
+		if stmt.Finally != nil {
+			c.leaveTry()
+			c.emit(stmt.Catch, bytecode.OpTryEnd)
+		}
+		jumps = append(jumps, c.emit(stmt.Catch, bytecode.OpJump, 0))
+		bodyHandlerPos = catchHandlerPos
+	}
+
+	// finally on error
+	if stmt.Finally != nil {
+		c.changeOperand(bodyHandlerPos, len(c.currentInstructions()))
+		c.symbolTable = c.symbolTable.Fork(true)
+		errSymbol := c.symbolTable.Define(":err")
+		c.emitDefine(stmt.Finally, errSymbol)
+		if err := c.Compile(stmt.Finally); err != nil {
+			c.symbolTable = c.symbolTable.Parent(false)
+			return err
+		}
+		c.emitGet(stmt.Finally, errSymbol)
+		c.symbolTable = c.symbolTable.Parent(false)
+		c.emit(stmt.Finally, bytecode.OpThrow, 1)
+	}
+
+	// finally
+	for _, pos := range jumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+	if stmt.Finally != nil {
+		if err := c.Compile(stmt.Finally); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compileCatch compiles the catch block,
+// assigning the caught error on the stack to the catch variable.
+func (c *Compiler) compileCatch(stmt *ast.TryStmt) error {
+	c.symbolTable = c.symbolTable.Fork(true)
+	defer func() {
+		c.symbolTable = c.symbolTable.Parent(false)
+	}()
+	if stmt.CatchIdent != nil && stmt.CatchIdent.Name != "_" {
+		symbol := c.symbolTable.Define(stmt.CatchIdent.Name)
+		c.emitDefine(stmt.CatchIdent, symbol)
+	} else {
+		c.emit(stmt.Catch, bytecode.OpPop)
+	}
+	return c.Compile(stmt.Catch)
+}
+
+// compileTryExits emits instructions for leaving the enclosing try blocks
+// with return, break or continue until there are depth blocks left:
+// exception handlers are removed and finally blocks are executed,
+// starting from the innermost block.
+func (c *Compiler) compileTryExits(node ast.Node, depth int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	defer func() {
+		c.scopes[c.scopeIndex].tries = tries
+	}()
+	for i := len(tries) - 1; i >= depth; i-- {
+		// a finally block must not be executed again
+		// when it is left with return, break or continue
+		c.scopes[c.scopeIndex].tries = slices.Clip(tries[:i])
+		if tries[i].handler {
+			c.emit(node, bytecode.OpTryEnd)
+		}
+		if tries[i].finally != nil {
+			if err := c.Compile(tries[i].finally); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emitDefine emits instructions that pop a value from the stack
+// and assign it to the newly defined symbol.
+func (c *Compiler) emitDefine(node ast.Node, symbol *Symbol) {
+	if symbol.Scope == ScopeGlobal {
+		c.emit(node, bytecode.OpSetGlobal, symbol.Index)
+	} else {
+		symbol.LocalAssigned = true
+		c.emit(node, bytecode.OpDefineLocal, symbol.Index)
+	}
+}
+
+// emitGet emits instructions that push the value of the symbol onto the stack.
+func (c *Compiler) emitGet(node ast.Node, symbol *Symbol) {
+	if symbol.Scope == ScopeGlobal {
+		c.emit(node, bytecode.OpGetGlobal, symbol.Index)
+	} else {
+		c.emit(node, bytecode.OpGetLocal, symbol.Index)
+	}
+}
+
+func (c *Compiler) compileExportStmt(node *ast.ExportStmt) error {
+	if c.scopeIndex != 0 || c.symbolTable.block {
+		return c.errorf(node, "export is only allowed at the top level")
+	}
+	if node.Assign != nil {
+		if err := c.Compile(node.Assign); err != nil {
+			return err
+		}
+	}
+	for _, name := range node.Names {
+		symbol, depth, ok := c.symbolTable.Resolve(name.Name, false)
+		if !ok {
+			return c.errorf(name, "unresolved reference '%s'", name.Name)
+		}
+		if depth != 0 || symbol.Scope == ScopeBuiltin {
+			return c.errorf(name, "cannot export '%s': not defined in this file", name.Name)
+		}
+		if slices.Contains(c.exports, symbol) {
+			return c.errorf(name, "'%s' exported twice", name.Name)
+		}
+		c.exports = append(c.exports, symbol)
+	}
+	return nil
+}
+
+// emitExports emits instructions that return the frozen table of exports.
+func (c *Compiler) emitExports(node ast.Node) {
+	freeze := slices.IndexFunc(Universe, func(v *Variable) bool {
+		return v.name == "freeze"
+	})
+	c.emit(node, bytecode.OpGetBuiltin, freeze)
+	for _, symbol := range c.exports {
+		c.emit(node, bytecode.OpConstant, c.addConstant(String(symbol.Name)))
+		c.emitGet(node, symbol)
+	}
+	c.emit(node, bytecode.OpTable, 2*len(c.exports), 0)
+	c.emit(node, bytecode.OpCall, 1, 0)
+	c.emit(node, bytecode.OpReturn, 1)
+}
+
+// selectorMembers returns the members of the module
+// the expression refers to, or nil if they are unknown.
+func (c *Compiler) selectorMembers(expr ast.Expr) *moduleMembers {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		symbol, _, ok := c.symbolTable.Resolve(expr.Name, false)
+		if !ok || symbol.members == nil || symbol.members.names == nil {
+			return nil
+		}
+		return symbol.members
+	case *ast.ImportExpr:
+		return c.importMembers(expr)
+	case *ast.ParenExpr:
+		return c.selectorMembers(expr.Expr)
+	}
+	return nil
+}
+
+// importMembers returns the members of the imported source module,
+// or nil if they are unknown, i.e. the module is a file without exports.
+// Members of builtin modules are not reported, since accessing
+// a missing member of a builtin module evaluates to nil at runtime.
+// The module must have already been compiled.
+func (c *Compiler) importMembers(node *ast.ImportExpr) *moduleMembers {
+	var names []string
+	mod := c.modules.Get(node.ModuleName)
+	_, overridden := c.moduleFiles[node.ModuleName]
+	if overridden {
+		mod = nil
+	}
+	switch mod.(type) {
+	case SourceModule:
+		names = c.loadModuleExports(node.ModuleName)
+	case nil:
+		if !c.allowFileImport && !overridden {
+			return nil
+		}
+		modulePath, err := c.getPathModule(node.ModuleName)
+		if err != nil {
+			return nil
+		}
+		names = c.loadModuleExports(modulePath)
+	}
+	if names == nil {
+		return nil
+	}
+	return &moduleMembers{module: node.ModuleName, names: names}
+}
+
 func (c *Compiler) checkCyclicImports(
 	node ast.Node,
 	modulePath string,
@@ -1308,6 +1716,16 @@ func (c *Compiler) compileModule(
 	compiledFunc := moduleCompiler.Bytecode().MainFunction
 	compiledFunc.numLocals = symbolTable.MaxSymbols() - numRemovedLocals
 	c.storeCompiledModule(modulePath, compiledFunc)
+	if isFile {
+		c.storeImportedFile(modulePath, src)
+	}
+	if moduleCompiler.exporting {
+		exports := make([]string, len(moduleCompiler.exports))
+		for i, symbol := range moduleCompiler.exports {
+			exports[i] = symbol.Name
+		}
+		c.storeModuleExports(modulePath, exports)
+	}
 
 	return compiledFunc, nil
 }
@@ -1330,8 +1748,47 @@ func (c *Compiler) storeCompiledModule(
 	c.compiledModules[modulePath] = module
 }
 
+func (c *Compiler) storeImportedFile(modulePath string, src []byte) {
+	if c.parent != nil {
+		c.parent.storeImportedFile(modulePath, src)
+		return
+	}
+	if c.importedSrcs == nil {
+		c.importedSrcs = make(map[string][]byte)
+	}
+	c.importedFiles = append(c.importedFiles, modulePath)
+	c.importedSrcs[modulePath] = src
+}
+
+func (c *Compiler) storeImport(imp Import) {
+	if c.parent != nil {
+		c.parent.storeImport(imp)
+		return
+	}
+	if !slices.Contains(c.imports, imp) {
+		c.imports = append(c.imports, imp)
+	}
+}
+
+func (c *Compiler) loadModuleExports(modulePath string) []string {
+	if c.parent != nil {
+		return c.parent.loadModuleExports(modulePath)
+	}
+	return c.moduleExports[modulePath]
+}
+
+func (c *Compiler) storeModuleExports(modulePath string, exports []string) {
+	if c.parent != nil {
+		c.parent.storeModuleExports(modulePath, exports)
+	}
+	c.moduleExports[modulePath] = exports
+}
+
 func (c *Compiler) enterLoop(label string) *loop {
-	loop := &loop{label: label}
+	loop := &loop{
+		label: label,
+		tries: len(c.scopes[c.scopeIndex].tries),
+	}
 	c.loops = append(c.loops, loop)
 	c.loopIndex++
 	if c.trace != nil {
@@ -1348,27 +1805,39 @@ func (c *Compiler) leaveLoop() {
 	c.loopIndex--
 }
 
+func (c *Compiler) enterTry(handler bool, finally *ast.BlockStmt) {
+	c.scopes[c.scopeIndex].tries = append(c.scopes[c.scopeIndex].tries, &tryBlock{
+		handler: handler,
+		finally: finally,
+	})
+}
+
+func (c *Compiler) leaveTry() {
+	tries := c.scopes[c.scopeIndex].tries
+	c.scopes[c.scopeIndex].tries = tries[:len(tries)-1]
+}
+
 func (c *Compiler) currentInstructions() []byte {
 	return c.scopes[c.scopeIndex].instructions
 }
 
-func (c *Compiler) currentSourceMap() map[int]token.Pos {
+func (c *Compiler) currentSourceMap() map[int]sourceRange {
 	return c.scopes[c.scopeIndex].sourceMap
 }
 
-func (c *Compiler) currentDeferMap() []token.Pos {
+func (c *Compiler) currentDeferMap() []sourceRange {
 	return c.scopes[c.scopeIndex].deferMap
 }
 
-func (c *Compiler) addDeferPos(pos token.Pos) int {
+func (c *Compiler) addDeferPos(node ast.Node) int {
 	idx := len(c.scopes[c.scopeIndex].deferMap)
-	c.scopes[c.scopeIndex].deferMap = append(c.scopes[c.scopeIndex].deferMap, pos)
+	c.scopes[c.scopeIndex].deferMap = append(c.scopes[c.scopeIndex].deferMap, sourceRange{node.Pos(), node.End()})
 	return idx
 }
 
 func (c *Compiler) enterScope() {
 	scope := compilationScope{
-		sourceMap: make(map[int]token.Pos),
+		sourceMap: make(map[int]sourceRange),
 		labels:    make(map[string]int),
 	}
 	c.scopes = append(c.scopes, scope)
@@ -1400,10 +1869,19 @@ func (c *Compiler) fork(
 	child.modulePath = modulePath // module file path
 	child.parent = c              // parent to set to current compiler
 	child.allowFileImport = c.allowFileImport
+	child.fs = c.fs
 	child.importDir = c.importDir
 	child.importFileExt = c.importFileExt
+	child.importPath = c.importPath
+	child.moduleFiles = c.moduleFiles
+	child.manifest = c.manifest
+	child.lockfile = c.lockfile
 	if isFile && c.importDir != "" {
-		child.importDir = filepath.Dir(modulePath)
+		if c.fs.IsHost() {
+			child.importDir = filepath.Dir(modulePath)
+		} else {
+			child.importDir = path.Dir(modulePath)
+		}
 	}
 	return child
 }
@@ -1471,7 +1949,7 @@ func (c *Compiler) optimizeFunc(node ast.Node) int {
 		func(pos int, opcode bytecode.Opcode, operands []int) bool {
 			switch opcode {
 			case bytecode.OpJump, bytecode.OpJumpFalsy,
-				bytecode.OpAndJump, bytecode.OpOrJump:
+				bytecode.OpAndJump, bytecode.OpOrJump, bytecode.OpTryBegin:
 				dsts[operands[0]] = true
 			}
 			return true
@@ -1515,7 +1993,7 @@ func (c *Compiler) optimizeFunc(node ast.Node) int {
 		func(pos int, opcode bytecode.Opcode, operands []int) bool {
 			switch opcode {
 			case bytecode.OpJump, bytecode.OpJumpFalsy, bytecode.OpAndJump,
-				bytecode.OpOrJump:
+				bytecode.OpOrJump, bytecode.OpTryBegin:
 				newDst, ok := posMap[operands[0]]
 				if ok {
 					copy(newInsts[pos:], bytecode.MakeInstruction(opcode, newDst))
@@ -1536,7 +2014,7 @@ func (c *Compiler) optimizeFunc(node ast.Node) int {
 	}
 
 	// pass 4. update source map
-	newSourceMap := make(map[int]token.Pos)
+	newSourceMap := make(map[int]sourceRange)
 	for pos, srcPos := range c.scopes[c.scopeIndex].sourceMap {
 		newPos, ok := posMap[pos]
 		if ok {
@@ -1558,7 +2036,7 @@ func (c *Compiler) emit(node ast.Node, opcode bytecode.Opcode, operands ...int)
 	inst := bytecode.MakeInstruction(opcode, operands...)
 	pos := c.addInstruction(inst)
 	if node != nil {
-		c.scopes[c.scopeIndex].sourceMap[pos] = node.Pos()
+		c.scopes[c.scopeIndex].sourceMap[pos] = sourceRange{node.Pos(), node.End()}
 	}
 	if c.trace != nil {
 		c.printTrace(fmt.Sprintf("EMIT: %s",
@@ -1581,22 +2059,92 @@ func (c *Compiler) printTrace(a ...any) {
 	fmt.Fprintln(c.trace, a...)
 }
 
+// getPathModule looks for the module file
+// in the import directory, then in the directories
+// of the manifest requirements, and then in the import path.
 func (c *Compiler) getPathModule(moduleName string) (pathFile string, err error) {
-	for _, ext := range c.importFileExt {
-		nameFile := moduleName
-		if !strings.HasSuffix(nameFile, ext) {
-			nameFile += ext
+	if pathFile, ok := c.moduleFiles[moduleName]; ok {
+		if c.fs.IsHost() {
+			return filepath.Abs(pathFile)
+		}
+		return pathFile, nil
+	}
+	candidates := []string{c.joinPath(c.importDir, moduleName)}
+	if !isRelativeModule(moduleName) {
+		if c.manifest != nil {
+			if r, rest, ok := c.manifest.Lookup(moduleName); ok {
+				if rest == "" {
+					rest = "index"
+				}
+				for _, dir := range c.requirementDirs(r) {
+					candidates = append(candidates, c.joinPath(dir, rest))
+				}
+			}
 		}
-		pathFile, err = filepath.Abs(filepath.Join(c.importDir, nameFile))
-		if err != nil {
-			continue
+		for _, dir := range c.importPath {
+			candidates = append(candidates, c.joinPath(dir, moduleName))
 		}
-		// Check if file exists
-		if _, err := os.Stat(pathFile); !errors.Is(err, os.ErrNotExist) {
-			return pathFile, nil
+	}
+	for _, candidate := range candidates {
+		for _, ext := range c.importFileExt {
+			pathFile = candidate
+			if !strings.HasSuffix(pathFile, ext) {
+				pathFile += ext
+			}
+			if c.fs.IsHost() {
+				pathFile, err = filepath.Abs(pathFile)
+				if err != nil {
+					continue
+				}
+			}
+			// Check if file exists
+			if _, err := c.fs.Stat(pathFile); !errors.Is(err, os.ErrNotExist) {
+				return pathFile, nil
+			}
 		}
 	}
-	return "", fmt.Errorf("module '%s' not found at: %s", moduleName, pathFile)
+	if len(candidates) == 1 {
+		return "", fmt.Errorf("module '%s' not found at: %s", moduleName, pathFile)
+	}
+	return "", fmt.Errorf("module '%s' not found at: %s", moduleName, strings.Join(candidates, ", "))
+}
+
+// requirementDirs returns the directories that may contain
+// the modules of the requirement.
+func (c *Compiler) requirementDirs(r *Requirement) []string {
+	m := c.manifest
+	if r.Prefix != m.Module {
+		vendorDir := c.joinPath(m.Dir, VendorDirName)
+		if _, err := c.fs.Stat(c.joinPath(vendorDir, "modules.txt")); err == nil {
+			return []string{c.joinPath(vendorDir, r.Prefix)}
+		}
+	}
+	if r.Dir != "" {
+		if c.fs.IsHost() && filepath.IsAbs(r.Dir) {
+			return []string{r.Dir}
+		}
+		return []string{c.joinPath(m.Dir, r.Dir)}
+	}
+	dirs := make([]string, 0, len(c.importPath))
+	for _, dir := range c.importPath {
+		dirs = append(dirs, c.joinPath(dir, r.Prefix+"@"+r.Version))
+	}
+	return dirs
+}
+
+func (c *Compiler) joinPath(elem ...string) string {
+	if c.fs.IsHost() {
+		return filepath.Join(elem...)
+	}
+	return rootedPath(path.Join(elem...))
+}
+
+// isRelativeModule reports whether the module name
+// is a path relative to the import directory.
+func isRelativeModule(moduleName string) bool {
+	return moduleName == "." || moduleName == ".." ||
+		strings.HasPrefix(moduleName, "./") || strings.HasPrefix(moduleName, "../") ||
+		path.IsAbs(moduleName) || filepath.IsAbs(moduleName)
 }
 
 // For the given expression, returns the leftmost identifier
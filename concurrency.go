@@ -0,0 +1,542 @@
+package toy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Task represents a function running concurrently in a child runtime.
+type Task struct {
+	rt   *Runtime
+	done chan struct{}
+	res  Value
+	err  error
+}
+
+var TaskType = NewType[*Task]("task", nil)
+
+// Spawn calls fn with the given arguments concurrently in a child runtime.
+//
+// The child runtime shares constants, limits, policy, filesystem, environment
+// and standard streams with r. Immutable values are shared with the child,
+// while mutable globals, arguments, receivers and free variables captured
+// by fn are replaced by their frozen copies, so the child can't modify
+// the values of r and vice versa.
+//
+// The context of the child runtime is derived from the context of r,
+// so the child is aborted when r is aborted.
+// The resource usage of the child is counted together with the usage of r.
+// Spawn returns PolicyError if the policy of r doesn't allow concurrency,
+// and LimitError if the maximum number of running tasks is reached.
+func (r *Runtime) Spawn(fn Value, args ...Value) (*Task, error) {
+	if err := r.CheckConcurrency(); err != nil {
+		return nil, err
+	}
+	if _, ok := fn.(Callable); !ok {
+		return nil, fmt.Errorf("'%s' is not callable", TypeName(fn))
+	}
+	if n := r.usage.tasks.Add(1); r.limits.MaxTasks > 0 && n > int64(r.limits.MaxTasks) {
+		r.usage.tasks.Add(-1)
+		return nil, &LimitError{Kind: LimitTasks, Limit: int64(r.limits.MaxTasks)}
+	}
+	iso := newIsolator()
+	fn = iso.value(fn)
+	args = slices.Clone(args)
+	for i, arg := range args {
+		args[i] = iso.value(arg)
+	}
+	t := &Task{
+		rt:   r.fork(iso),
+		done: make(chan struct{}),
+	}
+	end := t.rt.begin()
+	go func() {
+		defer close(t.done)
+		defer r.usage.tasks.Add(-1)
+		defer end()
+		t.res, t.err = Call(t.rt, fn, args...)
+		// aborted calls return without an error
+		if t.err == nil && atomic.LoadInt64(t.rt.aborting) != 0 {
+			t.res, t.err = nil, ErrAborted
+		}
+	}()
+	return t, nil
+}
+
+// fork creates a child runtime that shares constants
+// and isolated globals with r.
+func (r *Runtime) fork(iso *isolator) *Runtime {
+	globals := make([]Value, len(r.globals))
+	for i, g := range r.globals {
+		globals[i] = iso.value(g)
+	}
+	child := &Runtime{
+		constants:   r.constants,
+		stack:       make([]Value, StackSize),
+		globals:     globals,
+		fileSet:     r.fileSet,
+		frames:      make([]frame, MaxFrames),
+		framesIndex: 1,
+		ip:          -1,
+		aborting:    new(int64),
+		usage:       r.usage,
+		limits:      r.limits,
+		policy:      r.policy,
+		fs:          r.fs,
+		env:         r.env,
+		args:        r.args,
+		stdin:       r.stdin,
+		stdout:      r.stdout,
+		stderr:      r.stderr,
+		ctx:         r.Context(),
+	}
+	child.frames[0].fn = &CompiledFunction{}
+	child.curFrame = &child.frames[0]
+	return child
+}
+
+// isolator replaces mutable values passed to a task by their frozen copies.
+// Functions are copied with their receivers and free variables isolated.
+// The copies are memoized, so that functions referencing each other
+// (or themselves) and free variables shared by several functions
+// remain connected in the task.
+type isolator struct {
+	fns  map[*CompiledFunction]*CompiledFunction
+	ptrs map[*valuePtr]*valuePtr
+}
+
+func newIsolator() *isolator {
+	return &isolator{
+		fns:  make(map[*CompiledFunction]*CompiledFunction),
+		ptrs: make(map[*valuePtr]*valuePtr),
+	}
+}
+
+// value returns the isolated copy of v.
+func (iso *isolator) value(v Value) Value {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case *CompiledFunction:
+		return iso.function(x)
+	case *BuiltinFunction:
+		if x.recv == nil {
+			return x
+		}
+		return x.WithReceiver(iso.value(x.recv))
+	}
+	if Immutable(v) {
+		return v
+	}
+	return Freeze(v.Clone())
+}
+
+// function returns the copy of fn with isolated receiver and free variables.
+func (iso *isolator) function(fn *CompiledFunction) *CompiledFunction {
+	if c, ok := iso.fns[fn]; ok {
+		return c
+	}
+	c := fn.WithReceiver(nil)
+	iso.fns[fn] = c
+	c.receiver = iso.value(fn.receiver)
+	for i, ptr := range fn.free {
+		c.free[i] = iso.pointer(ptr)
+	}
+	return c
+}
+
+// pointer returns the copy of the free variable with the isolated value.
+func (iso *isolator) pointer(ptr *valuePtr) *valuePtr {
+	if c, ok := iso.ptrs[ptr]; ok {
+		return c
+	}
+	c := &valuePtr{p: new(Value)}
+	iso.ptrs[ptr] = c
+	*c.p = iso.value(*ptr.p)
+	return c
+}
+
+// Wait waits for the task to finish and returns its result.
+// If the task has been aborted, Wait returns ErrAborted.
+// If ctx is done before the task has finished, Wait returns ctx.Err().
+func (t *Task) Wait(ctx context.Context) (Value, error) {
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if t.err != nil {
+		// runtime errors are extended with the trace of the waiting runtime,
+		// so each waiter must receive its own copy
+		if rErr := (*RuntimeError)(nil); errors.As(t.err, &rErr) {
+			return nil, &RuntimeError{
+				Err:      rErr.Err,
+				Deferred: slices.Clone(rErr.Deferred),
+				Frames:   slices.Clone(rErr.Frames),
+			}
+		}
+		return nil, t.err
+	}
+	return t.res, nil
+}
+
+// Abort aborts the execution of the task.
+func (t *Task) Abort() {
+	t.rt.Abort()
+}
+
+// Done returns a channel that's closed when the task has finished.
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Task) Type() ValueType { return TaskType }
+func (t *Task) String() string  { return "<task>" }
+func (t *Task) IsFalsy() bool   { return false }
+func (t *Task) Clone() Value    { return t }
+
+func (t *Task) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	switch string(keyStr) {
+	case "done":
+		select {
+		case <-t.done:
+			return True, true, nil
+		default:
+			return False, true, nil
+		}
+	}
+	method, ok := taskMethods[string(keyStr)]
+	if ok {
+		return method.WithReceiver(t), true, nil
+	}
+	return Nil, false, nil
+}
+
+var taskMethods = map[string]*BuiltinFunction{
+	"wait":  NewBuiltinFunction("wait", taskWaitMd),
+	"abort": NewBuiltinFunction("abort", taskAbortMd),
+}
+
+func taskWaitMd(r *Runtime, args ...Value) (Value, error) {
+	recv := args[0].(*Task)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &WrongNumArgumentsError{Got: len(args)}
+	}
+	return recv.Wait(r.Context())
+}
+
+func taskAbortMd(_ *Runtime, args ...Value) (Value, error) {
+	recv := args[0].(*Task)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &WrongNumArgumentsError{Got: len(args)}
+	}
+	recv.Abort()
+	return Nil, nil
+}
+
+// Chan represents a channel for communication between tasks.
+//
+// The underlying Go channel is never closed, the closed state
+// is tracked separately, so that sending to a closed channel
+// results in an error instead of a panic.
+type Chan struct {
+	ch     chan Value
+	mu     sync.Mutex    // guards closing
+	closed chan struct{} // closed when the channel is closed
+}
+
+// NewChan creates a new channel with the given buffer size.
+func NewChan(size int) *Chan {
+	return &Chan{
+		ch:     make(chan Value, size),
+		closed: make(chan struct{}),
+	}
+}
+
+var ChanType = NewType[*Chan]("chan", func(_ *Runtime, args ...Value) (Value, error) {
+	var size int
+	if err := UnpackArgs(args, "size?", &size); err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("negative channel size")
+	}
+	return NewChan(size), nil
+})
+
+func (c *Chan) Type() ValueType { return ChanType }
+func (c *Chan) String() string  { return "<chan>" }
+func (c *Chan) IsFalsy() bool   { return false }
+func (c *Chan) Clone() Value    { return c }
+func (c *Chan) Len() int        { return len(c.ch) }
+
+// isClosed reports whether the channel is closed.
+func (c *Chan) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// errSendOnClosed is returned when sending to a closed channel.
+var errSendOnClosed = errors.New("send on closed channel")
+
+// Send sends the value to the channel.
+// If the channel is closed, or gets closed while Send is blocked,
+// Send returns an error.
+// If ctx is done before the value has been sent, Send returns ctx.Err().
+func (c *Chan) Send(ctx context.Context, value Value) error {
+	if c.isClosed() {
+		return errSendOnClosed
+	}
+	select {
+	case c.ch <- value:
+		return nil
+	case <-c.closed:
+		return errSendOnClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Recv receives a value from the channel.
+// The returned boolean is false if the channel is closed and empty.
+// If ctx is done before a value has been received, Recv returns ctx.Err().
+func (c *Chan) Recv(ctx context.Context) (Value, bool, error) {
+	select {
+	case value := <-c.ch:
+		return value, true, nil
+	case <-c.closed:
+		return c.drain()
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// drain receives a value buffered in the closed channel.
+func (c *Chan) drain() (Value, bool, error) {
+	select {
+	case value := <-c.ch:
+		return value, true, nil
+	default:
+		return Nil, false, nil
+	}
+}
+
+// Close closes the channel.
+func (c *Chan) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClosed() {
+		return errors.New("close of closed channel")
+	}
+	close(c.closed)
+	return nil
+}
+
+// Elements returns an iterator over the values received from the channel
+// until the channel is closed.
+func (c *Chan) Elements() iter.Seq[Value] {
+	return c.elements(context.Background())
+}
+
+// elements is like Elements, but stops when ctx is done.
+func (c *Chan) elements(ctx context.Context) iter.Seq[Value] {
+	return func(yield func(Value) bool) {
+		for {
+			value, ok, err := c.Recv(ctx)
+			if err != nil || !ok || !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Chan) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	switch string(keyStr) {
+	case "cap":
+		return Int(cap(c.ch)), true, nil
+	}
+	method, ok := chanMethods[string(keyStr)]
+	if ok {
+		return method.WithReceiver(c), true, nil
+	}
+	return Nil, false, nil
+}
+
+var chanMethods = map[string]*BuiltinFunction{
+	"send":  NewBuiltinFunction("send", chanSendMd),
+	"recv":  NewBuiltinFunction("recv", chanRecvMd),
+	"close": NewBuiltinFunction("close", chanCloseMd),
+}
+
+func chanSendMd(r *Runtime, args ...Value) (Value, error) {
+	var (
+		recv  = args[0].(*Chan)
+		value Value
+	)
+	if err := UnpackArgs(args[1:], "value", &value); err != nil {
+		return nil, err
+	}
+	if err := recv.Send(r.Context(), value); err != nil {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+func chanRecvMd(r *Runtime, args ...Value) (Value, error) {
+	recv := args[0].(*Chan)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &WrongNumArgumentsError{Got: len(args)}
+	}
+	value, ok, err := recv.Recv(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return Tuple{value, Bool(ok)}, nil
+}
+
+func chanCloseMd(_ *Runtime, args ...Value) (Value, error) {
+	recv := args[0].(*Chan)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &WrongNumArgumentsError{Got: len(args)}
+	}
+	if err := recv.Close(); err != nil {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+func builtinSpawn(r *Runtime, args ...Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, &WrongNumArgumentsError{
+			WantMin: 1,
+			WantMax: -1,
+			Got:     len(args),
+		}
+	}
+	return r.Spawn(args[0], args[1:]...)
+}
+
+// builtinSelect waits until one of the channel operations can proceed.
+// Each argument is either a channel to receive from,
+// a [channel, value] pair to send the value to the channel,
+// or nil to proceed immediately if no other operation is ready.
+// Returns (index, value, ok) where index is the index of the chosen argument,
+// value is the received value, and ok reports whether the value was
+// received or sent successfully.
+func builtinSelect(r *Runtime, args ...Value) (Value, error) {
+	if err := r.CheckConcurrency(); err != nil {
+		return nil, err
+	}
+	var (
+		// each channel operation is accompanied by a case
+		// that proceeds when the channel is closed
+		cases      = make([]reflect.SelectCase, 0, 2*len(args)+1)
+		indexes    = make([]int, 0, 2*len(args)+1)
+		closing    = make([]bool, 0, 2*len(args)+1)
+		hasDefault = false
+	)
+	addCase := func(i int, c reflect.SelectCase) {
+		cases = append(cases, c)
+		indexes = append(indexes, i)
+		closing = append(closing, false)
+	}
+	addClosedCase := func(i int, ch *Chan) {
+		addCase(i, reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(ch.closed),
+		})
+		closing[len(closing)-1] = true
+	}
+	for i, arg := range args {
+		switch x := arg.(type) {
+		case *Chan:
+			addCase(i, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(x.ch),
+			})
+			addClosedCase(i, x)
+		case Sequence:
+			var (
+				ch    *Chan
+				value Value
+			)
+			if err := UnpackArgs(x.Items(), "ch", &ch, "value", &value); err != nil {
+				return nil, fmt.Errorf("cases[%d]: %w", i, err)
+			}
+			if ch.isClosed() {
+				return nil, errSendOnClosed
+			}
+			addCase(i, reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(ch.ch),
+				Send: reflect.ValueOf(&value).Elem(),
+			})
+			addClosedCase(i, ch)
+		case NilValue:
+			if hasDefault {
+				return nil, fmt.Errorf("cases[%d]: multiple nil cases", i)
+			}
+			hasDefault = true
+			addCase(i, reflect.SelectCase{Dir: reflect.SelectDefault})
+		default:
+			return nil, &InvalidArgumentTypeError{
+				Name: fmt.Sprintf("cases[%d]", i),
+				Want: "chan, [chan, value] or nil",
+				Got:  TypeName(arg),
+			}
+		}
+	}
+	ctx := r.Context()
+	addCase(len(args), reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	})
+	chosen, recv, ok := reflect.Select(cases)
+	index := indexes[chosen]
+	if index == len(args) {
+		return nil, ctx.Err()
+	}
+	if closing[chosen] {
+		ch, ok := args[index].(*Chan)
+		if !ok {
+			return nil, errSendOnClosed
+		}
+		value, ok, _ := ch.drain()
+		return Tuple{Int(index), value, Bool(ok)}, nil
+	}
+	switch cases[chosen].Dir {
+	case reflect.SelectRecv:
+		return Tuple{Int(index), recv.Interface().(Value), True}, nil
+	case reflect.SelectSend:
+		return Tuple{Int(index), Nil, True}, nil
+	}
+	return Tuple{Int(index), Nil, Bool(ok)}, nil
+}
@@ -0,0 +1,263 @@
+package toy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrency(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{
+			name: "spawn",
+			src: `
+t := spawn(fn(a, b) { return a + b }, 1, 2)
+return t.wait()`,
+			want: toy.Int(3),
+		},
+		{
+			name: "spawn error",
+			src: `
+t := spawn(fn() { throw "failed" })
+return t.wait()`,
+			err: "failed",
+		},
+		{
+			name: "spawn frozen globals",
+			src: `
+arr := [1]
+t := spawn(fn() { arr[0] = 2 })
+return t.wait()`,
+			err: "immutable",
+		},
+		{
+			name: "spawn frozen arguments",
+			src: `
+t := spawn(fn(arr) { arr[0] = 2 }, [1])
+return t.wait()`,
+			err: "immutable",
+		},
+		{
+			name: "spawn frozen free variables",
+			src: `
+f := fn() {
+	m := {}
+	t := spawn(fn() { m.x = 1 })
+	return t.wait()
+}
+return f()`,
+			err: "immutable",
+		},
+		{
+			name: "spawn isolated free variables",
+			src: `
+f := fn() {
+	x := 1
+	t := spawn(fn() { x = 2; return x })
+	return [t.wait(), x]
+}
+return f()`,
+			want: toy.NewArray([]toy.Value{toy.Int(2), toy.Int(1)}),
+		},
+		{
+			name: "spawn recursive closure",
+			src: `
+f := fn() {
+	g := fn(n) {
+		if n == 0 { return 0 }
+		return n + g(n - 1)
+	}
+	return spawn(g, 3).wait()
+}
+return f()`,
+			want: toy.Int(6),
+		},
+		{
+			name: "channel",
+			src: `
+ch := chan()
+spawn(fn() {
+	for i in range(0, 3) { ch.send(i) }
+	ch.close()
+})
+res := []
+for {
+	v, ok := ch.recv()
+	if !ok { break }
+	res = append(res, v)
+}
+return res`,
+			want: toy.NewArray([]toy.Value{toy.Int(0), toy.Int(1), toy.Int(2)}),
+		},
+		{
+			name: "buffered channel",
+			src: `
+ch := chan(2)
+ch.send(1)
+return len(ch), ch.cap`,
+			want: toy.Tuple{toy.Int(1), toy.Int(2)},
+		},
+		{
+			name: "close of closed channel",
+			src:  `ch := chan(); ch.close(); ch.close()`,
+			err:  "close of closed channel",
+		},
+		{
+			name: "send on closed channel",
+			src:  `ch := chan(1); ch.close(); ch.send(1)`,
+			err:  "send on closed channel",
+		},
+		{
+			name: "select receive",
+			src: `
+a, b := chan(1), chan(1)
+b.send("b")
+return select(a, b)`,
+			want: toy.Tuple{toy.Int(1), toy.String("b"), toy.True},
+		},
+		{
+			name: "select send",
+			src: `
+a := chan(1)
+i, _, ok := select([a, "x"])
+v, _ := a.recv()
+return i, v, ok`,
+			want: toy.Tuple{toy.Int(0), toy.String("x"), toy.True},
+		},
+		{
+			name: "select default",
+			src:  `return select(chan(), nil)`,
+			want: toy.Tuple{toy.Int(1), toy.Nil, toy.False},
+		},
+		{
+			name: "send on channel closed by another task",
+			src: `
+ch := chan()
+t := spawn(fn() { ch.send(1) })
+ch.close()
+return t.wait()`,
+			err: "send on closed channel",
+		},
+		{
+			name: "receive from closed buffered channel",
+			src: `
+ch := chan(2)
+ch.send(1)
+ch.close()
+return [ch.recv(), ch.recv()]`,
+			want: toy.NewArray([]toy.Value{
+				toy.Tuple{toy.Int(1), toy.True},
+				toy.Tuple{toy.Nil, toy.False},
+			}),
+		},
+		{
+			name: "select closed buffered channel",
+			src: `
+a := chan(1)
+a.send(1)
+a.close()
+return select(a)`,
+			want: toy.Tuple{toy.Int(0), toy.Int(1), toy.True},
+		},
+		{
+			name: "select closed",
+			src: `
+a := chan()
+a.close()
+return select(a)`,
+			want: toy.Tuple{toy.Int(0), toy.Nil, toy.False},
+		},
+		{
+			name: "select send on closed channel",
+			src: `
+a := chan(1)
+a.close()
+select([a, 1])`,
+			err: "send on closed channel",
+		},
+		{
+			name: "select multiple defaults",
+			src:  `select(chan(), nil, nil)`,
+			err:  "cases[2]: multiple nil cases",
+		},
+		{
+			name: "select invalid case",
+			src:  `select(1)`,
+			err:  "cases[0]",
+		},
+		{
+			name: "abort",
+			src: `
+ch := chan()
+t := spawn(fn() { ch.recv() })
+t.abort()
+return t.wait()`,
+			err: "task aborted",
+		},
+	}, nil)
+}
+
+func TestTaskAbort(t *testing.T) {
+	script := toy.NewScript([]byte(`
+task := spawn(fn() {
+	for {}
+})`))
+	compiled, err := script.Run()
+	require.NoError(t, err)
+
+	task := compiled.Get("task").Value().(*toy.Task)
+	task.Abort()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = task.Wait(ctx)
+	require.ErrorIs(t, err, toy.ErrAborted)
+}
+
+func TestConcurrencyPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *toy.Policy
+		src    string
+		want   toy.Value
+		err    string
+	}{
+		{
+			name:   "spawn denied",
+			policy: toy.PurePolicy(),
+			src:    `spawn(fn() {})`,
+			err:    "concurrency is denied by policy",
+		},
+		{
+			name:   "select denied",
+			policy: toy.PurePolicy(),
+			src:    `select(nil)`,
+			err:    "concurrency is denied by policy",
+		},
+		{
+			name:   "channels allowed",
+			policy: toy.PurePolicy(),
+			src:    `ch := chan(1); ch.send(1); return ch.recv()`,
+			want:   toy.Tuple{toy.Int(1), toy.True},
+		},
+		{
+			name:   "spawn allowed",
+			policy: toy.FullPolicy(),
+			src:    `return spawn(fn() { return 1 }).wait()`,
+			want:   toy.Int(1),
+		},
+	}
+	for _, tt := range tests {
+		runScriptTests(t, []scriptTest{{
+			name: tt.name,
+			src:  tt.src,
+			want: tt.want,
+			err:  tt.err,
+		}}, func(s *toy.Script) {
+			s.SetPolicy(tt.policy)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package toy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+type contextKey struct{}
+
+func TestRuntimeContext(t *testing.T) {
+	require.Equal(t, context.Background(), (*toy.Runtime)(nil).Context())
+
+	var lastCtx context.Context
+	script := toy.NewScript([]byte(`return value()`))
+	script.Add("value", toy.NewBuiltinFunction("value", func(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+		lastCtx = r.Context()
+		v, _ := lastCtx.Value(contextKey{}).(string)
+		return toy.String(v), nil
+	}))
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "request")
+	require.NoError(t, compiled.RunContext(ctx))
+	require.Equal(t, toy.String("request"), compiled.Result())
+	// the context of the execution is cancelled after it has finished
+	require.ErrorIs(t, lastCtx.Err(), context.Canceled)
+
+	require.NoError(t, compiled.Run())
+	require.Equal(t, toy.String(""), compiled.Result())
+}
+
+func TestRuntimeContextCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "builtin", src: `wait()`},
+		{name: "stdlib", src: `time := import("time"); time.sleep(time.hour)`},
+		{name: "loop", src: `for {}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := toy.NewScript([]byte(tt.src))
+			script.SetImports(stdlib.StdLib)
+			script.Add("wait", toy.NewBuiltinFunction("wait", func(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+				<-r.Context().Done()
+				return nil, r.Context().Err()
+			}))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := script.RunContext(ctx)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled), err)
+		})
+	}
+}
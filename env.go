@@ -0,0 +1,186 @@
+package toy
+
+import (
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Env represents environment variables available to scripts.
+// Env returned by NewEnv is isolated from the process environment,
+// while the default Env of a runtime reads and modifies
+// the environment of the process.
+type Env struct {
+	mu   sync.RWMutex
+	vars map[string]string // nil means the process environment
+}
+
+// hostEnv provides access to the environment of the process.
+var hostEnv = &Env{}
+
+// NewEnv returns a new isolated Env initialized with
+// the given environment variables in the form "key=value",
+// such as returned by os.Environ.
+func NewEnv(environ []string) *Env {
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		vars[key] = value
+	}
+	return &Env{vars: vars}
+}
+
+// Get retrieves the value of the environment variable named by the key.
+// It returns the empty string if the variable is not present.
+func (e *Env) Get(key string) string {
+	value, _ := e.Lookup(key)
+	return value
+}
+
+// Lookup retrieves the value of the environment variable named by the key.
+// If the variable is not present, the returned boolean is false.
+func (e *Env) Lookup(key string) (string, bool) {
+	if e.vars == nil {
+		return os.LookupEnv(key)
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	value, ok := e.vars[key]
+	return value, ok
+}
+
+// Set sets the value of the environment variable named by the key.
+func (e *Env) Set(key, value string) error {
+	if e.vars == nil {
+		return os.Setenv(key, value)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vars[key] = value
+	return nil
+}
+
+// Unset unsets a single environment variable.
+func (e *Env) Unset(key string) error {
+	if e.vars == nil {
+		return os.Unsetenv(key)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.vars, key)
+	return nil
+}
+
+// Clear deletes all environment variables.
+func (e *Env) Clear() {
+	if e.vars == nil {
+		os.Clearenv()
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	clear(e.vars)
+}
+
+// Environ returns a copy of strings representing
+// the environment, in the form "key=value".
+func (e *Env) Environ() []string {
+	if e.vars == nil {
+		return os.Environ()
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	environ := make([]string, 0, len(e.vars))
+	for _, key := range slices.Sorted(maps.Keys(e.vars)) {
+		environ = append(environ, key+"="+e.vars[key])
+	}
+	return environ
+}
+
+// Expand replaces ${var} or $var in the string
+// according to the values of the environment variables.
+// References to undefined variables are replaced by the empty string.
+func (e *Env) Expand(s string) string {
+	return os.Expand(s, e.Get)
+}
+
+// SetEnv sets the environment variables of the runtime.
+// If env is nil, the runtime uses the environment of the process.
+func (r *Runtime) SetEnv(env *Env) {
+	r.env = env
+}
+
+// Env returns the environment variables of the runtime.
+// It is safe to call Env on a nil Runtime,
+// in which case the environment of the process is returned.
+func (r *Runtime) Env() *Env {
+	if r == nil || r.env == nil {
+		return hostEnv
+	}
+	return r.env
+}
+
+// SetArgs sets the command-line arguments of the runtime,
+// starting with the name of the script.
+// If args is nil, the runtime uses os.Args.
+func (r *Runtime) SetArgs(args []string) {
+	r.args = args
+}
+
+// Args returns the command-line arguments of the runtime.
+// It is safe to call Args on a nil Runtime,
+// in which case os.Args is returned.
+func (r *Runtime) Args() []string {
+	if r == nil || r.args == nil {
+		return os.Args
+	}
+	return r.args
+}
+
+// SetStdin sets the standard input of the runtime.
+// If stdin is nil, the runtime uses os.Stdin.
+func (r *Runtime) SetStdin(stdin io.Reader) {
+	r.stdin = stdin
+}
+
+// SetStdout sets the standard output of the runtime.
+// If stdout is nil, the runtime uses os.Stdout.
+func (r *Runtime) SetStdout(stdout io.Writer) {
+	r.stdout = stdout
+}
+
+// SetStderr sets the standard error of the runtime.
+// If stderr is nil, the runtime uses os.Stderr.
+func (r *Runtime) SetStderr(stderr io.Writer) {
+	r.stderr = stderr
+}
+
+// Stdin returns the standard input of the runtime.
+// It is safe to call Stdin on a nil Runtime.
+func (r *Runtime) Stdin() io.Reader {
+	if r == nil || r.stdin == nil {
+		return os.Stdin
+	}
+	return r.stdin
+}
+
+// Stdout returns the standard output of the runtime.
+// It is safe to call Stdout on a nil Runtime.
+func (r *Runtime) Stdout() io.Writer {
+	if r == nil || r.stdout == nil {
+		return os.Stdout
+	}
+	return r.stdout
+}
+
+// Stderr returns the standard error of the runtime.
+// It is safe to call Stderr on a nil Runtime.
+func (r *Runtime) Stderr() io.Writer {
+	if r == nil || r.stderr == nil {
+		return os.Stderr
+	}
+	return r.stderr
+}
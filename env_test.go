@@ -0,0 +1,46 @@
+package toy_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptEnv(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{
+			name: "args",
+			src:  `return import("os").args()`,
+			want: toy.NewArray([]toy.Value{toy.String("script.toy"), toy.String("a")}),
+		},
+		{
+			name: "env",
+			src:  `env := import("os/env"); return env.get("NAME")`,
+			want: toy.String("value"),
+		},
+	}, func(s *toy.Script) {
+		s.SetArgs([]string{"script.toy", "a"})
+		s.SetEnv([]string{"NAME=value"})
+	})
+}
+
+func TestCompiledArgs(t *testing.T) {
+	script := toy.NewScript([]byte(`fmt := import("fmt"); fmt.print(import("os").args())`))
+	script.SetImports(stdlib.StdLib)
+	script.SetArgs([]string{"script"})
+	var stdout bytes.Buffer
+	script.SetStdout(&stdout)
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+
+	require.NoError(t, compiled.Run())
+	require.Equal(t, `["script"]`, stdout.String())
+
+	stdout.Reset()
+	compiled.SetArgs([]string{"compiled", "a"})
+	require.NoError(t, compiled.Clone().Run())
+	require.Equal(t, `["compiled", "a"]`, stdout.String())
+}
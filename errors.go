@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/infastin/toy/token"
 )
 
 var (
@@ -20,6 +22,18 @@ var (
 
 	// ErrDivisionByZero represents a division by zero error.
 	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrLimitExceeded is returned when the runtime exceeds one of its limits.
+	// Use errors.As with LimitError to find out which limit has been exceeded.
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrDenied is returned when a script tries to use
+	// a capability denied by the policy.
+	ErrDenied = errors.New("denied by policy")
+
+	// ErrAborted is returned when waiting for a task
+	// that has been aborted before it has finished.
+	ErrAborted = errors.New("task aborted")
 )
 
 // Exception is a special error type returned by (*Runtime).run()
@@ -29,6 +43,9 @@ type Exception struct {
 }
 
 func (e *Exception) Error() string {
+	if err, ok := e.Value.(*Error); ok {
+		return err.kind.name + ": " + err.String()
+	}
 	return "exception: " + AsString(e.Value)
 }
 
@@ -42,6 +59,84 @@ func NewExceptionMsgf(format string, args ...any) error {
 	return &Exception{Value: String(fmt.Sprintf(format, args...))}
 }
 
+// RuntimeError is returned when an error occurs during the execution.
+// Use errors.As and errors.Is to inspect the errors it contains.
+type RuntimeError struct {
+	// Err is the error that caused the execution to fail.
+	Err error
+	// Deferred contains errors returned by deferred calls
+	// while the call stack was unwinding.
+	Deferred []error
+	// Frames is the stacktrace of the error,
+	// starting from the innermost frame.
+	Frames []Frame
+}
+
+// Frame represents a frame of the stacktrace.
+type Frame struct {
+	// Pos is the position in the source code.
+	Pos token.FilePos
+	// Function is the name of the function, if known.
+	Function string
+	// Module is the path of the module the function is defined in.
+	Module string
+	// Excerpt is the excerpt of the source code, if available.
+	Excerpt *token.Excerpt
+}
+
+func (f Frame) String() string {
+	if f.Function == "" {
+		return "at " + f.Pos.String()
+	}
+	return "at " + f.Function + " (" + f.Pos.String() + ")"
+}
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	b.WriteString("runtime error: ")
+	b.WriteString(e.Err.Error())
+	for i, err := range e.Deferred {
+		if i != len(e.Deferred)-1 {
+			b.WriteString("\n├─ ")
+		} else {
+			b.WriteString("\n└─ ")
+		}
+		b.WriteString(err.Error())
+	}
+	if len(e.Frames) != 0 && e.Frames[0].Excerpt != nil {
+		b.WriteString("\n\n")
+		b.WriteString(e.Frames[0].Excerpt.String())
+	}
+	if len(e.Frames) != 0 {
+		b.WriteString("\n\nstacktrace:")
+		for i, frame := range e.Frames {
+			if i != len(e.Frames)-1 {
+				b.WriteString("\n├─ ")
+			} else {
+				b.WriteString("\n└─ ")
+			}
+			b.WriteString(frame.String())
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the error that caused the execution to fail
+// followed by the errors returned by deferred calls.
+func (e *RuntimeError) Unwrap() []error {
+	return append([]error{e.Err}, e.Deferred...)
+}
+
+// Thrown returns the value thrown by the throw keyword
+// that caused the execution to fail.
+// The returned boolean is false if the error is not an exception.
+func (e *RuntimeError) Thrown() (Value, bool) {
+	if exc := (*Exception)(nil); errors.As(e.Err, &exc) {
+		return exc.Value, true
+	}
+	return nil, false
+}
+
 // InvalidKeyTypeError represents an invalid key type error.
 type InvalidKeyTypeError struct {
 	Want string
@@ -106,3 +201,60 @@ func (e *WrongNumArgumentsError) Error() string {
 	b.WriteString(strconv.Itoa(e.Got))
 	return b.String()
 }
+
+// LimitKind represents a kind of a runtime resource limit.
+type LimitKind int
+
+const (
+	LimitInstructions LimitKind = iota
+	LimitAllocs
+	LimitCollectionLen
+	LimitCallDepth
+	LimitTasks
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitInstructions:
+		return "instruction"
+	case LimitAllocs:
+		return "allocation"
+	case LimitCollectionLen:
+		return "collection length"
+	case LimitCallDepth:
+		return "call depth"
+	case LimitTasks:
+		return "task"
+	}
+	return "unknown"
+}
+
+// LimitError represents an error returned
+// when the runtime exceeds one of its limits.
+// Errors of this type can't be caught by try keyword.
+type LimitError struct {
+	Kind  LimitKind
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded (%d)", e.Kind.String(), e.Limit)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// PolicyError represents an error returned
+// when a script tries to use a capability denied by the policy.
+type PolicyError struct {
+	Subject string // what was denied, e.g. "access to 'os.remove'"
+}
+
+func (e *PolicyError) Error() string {
+	return e.Subject + " is denied by policy"
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrDenied
+}
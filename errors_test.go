@@ -0,0 +1,284 @@
+package toy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/fstest"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeErrorExcerpt(t *testing.T) {
+	type excerpt struct {
+		line       int
+		start, end int
+	}
+	tests := []struct {
+		name string
+		src  string
+		want []excerpt // excerpts of the frames, starting from the innermost one
+	}{
+		{
+			name: "binary",
+			src:  `x := 1 + "a"`,
+			want: []excerpt{{1, 6, 13}},
+		},
+		{
+			name: "call",
+			src:  "f := fn(a) { return a / 0 }\nx := f(1)",
+			want: []excerpt{{1, 21, 26}, {2, 6, 10}},
+		},
+		{
+			name: "unary",
+			src:  "s := \"a\"\nx := -s",
+			want: []excerpt{{2, 6, 8}},
+		},
+		{
+			name: "deferred",
+			src:  "f := fn() { defer fn(a) { return a / 0 }(1) }\nf()",
+			want: []excerpt{{1, 34, 39}, {1, 19, 44}, {2, 1, 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := toy.NewScript([]byte(tt.src)).RunContext(context.Background())
+			var rerr *toy.RuntimeError
+			require.True(t, errors.As(err, &rerr), "unexpected error: %v", err)
+			var got []excerpt
+			for _, frame := range rerr.Frames {
+				require.NotNil(t, frame.Excerpt)
+				got = append(got, excerpt{frame.Excerpt.Line, frame.Excerpt.Start, frame.Excerpt.End})
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRuntimeErrorMultilineExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "two lines",
+			src:  "x := 1 +\n  \"a\"",
+			want: " 1 | x := 1 +\n   |      ^^^\n 2 |   \"a\"\n   |   ^^^",
+		},
+		{
+			name: "blank line",
+			src:  "x := 1 +\n\n\"a\"",
+			want: " 1 | x := 1 +\n   |      ^^^\n 2 | \n 3 | \"a\"\n   | ^^^",
+		},
+		{
+			name: "omitted lines",
+			src:  "x := [\n1,\n2,\n3,\n4,\n5,\n6\n] + 1",
+			want: " 1 | x := [\n   |      ^\n 2 | 1,\n   | ^^\n   | ...\n 7 | 6\n   | ^\n 8 | ] + 1\n   | ^^^^^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := toy.NewScript([]byte(tt.src)).RunContext(context.Background())
+			var rerr *toy.RuntimeError
+			require.True(t, errors.As(err, &rerr), "unexpected error: %v", err)
+			require.NotNil(t, rerr.Frames[0].Excerpt)
+			require.Equal(t, tt.want, rerr.Frames[0].Excerpt.String())
+		})
+	}
+}
+
+func TestParseErrorExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "end of file",
+			src:  "x := (1 +",
+			want: "parse error: expected operand, found 'EOF'\n└─ at (main):1:10\n\n 1 | x := (1 +\n   |          ^",
+		},
+		{
+			name: "end of file after newline",
+			src:  "x := (1 +\n",
+			want: "parse error: expected operand, found 'EOF'\n└─ at (main):1:11\n\n 1 | x := (1 +\n   |          ^",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := toy.NewScript([]byte(tt.src)).Compile()
+			require.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		is       error    // error expected to be in the chain
+		thrown   string   // string representation of the thrown value
+		deferred []string // messages of the deferred errors
+		msg      string   // substring of the error message
+	}{
+		{
+			name:   "thrown value",
+			src:    `throw {code: 42}`,
+			thrown: "{code: 42}",
+			msg:    "runtime error: exception: {code: 42}",
+		},
+		{
+			name: "not thrown",
+			src:  `x := 1 / 0`,
+			is:   toy.ErrDivisionByZero,
+			msg:  "runtime error: division by zero",
+		},
+		{
+			name: "go error",
+			src:  `fail()`,
+			is:   io.EOF,
+			msg:  "failed: EOF",
+		},
+		{
+			name: "caught and rethrown",
+			src:  `try { fail() } catch err { throw err }`,
+			is:   io.EOF,
+		},
+		{
+			name:     "deferred",
+			src:      `f := fn() { defer fn() { throw "d1" }(); defer fn() { fail() }(); throw "main" }; f()`,
+			is:       io.EOF,
+			thrown:   `"main"`,
+			deferred: []string{"error during call to 'function': failed: EOF", "exception: d1"},
+			msg:      "runtime error: exception: main\n├─ error during call to 'function': failed: EOF\n└─ exception: d1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := toy.NewScript([]byte(tt.src))
+			script.Add("fail", toy.NewBuiltinFunction("fail", func(*toy.Runtime, ...toy.Value) (toy.Value, error) {
+				return nil, fmt.Errorf("failed: %w", io.EOF)
+			}))
+			_, err := script.RunContext(context.Background())
+			var rerr *toy.RuntimeError
+			require.True(t, errors.As(err, &rerr), "unexpected error: %v", err)
+			require.NotEmpty(t, rerr.Frames)
+
+			if tt.is != nil {
+				require.ErrorIs(t, err, tt.is)
+			}
+			thrown, ok := rerr.Thrown()
+			if tt.thrown != "" {
+				require.True(t, ok)
+				require.Equal(t, tt.thrown, thrown.String())
+				var exc *toy.Exception
+				require.True(t, errors.As(err, &exc))
+			} else {
+				require.False(t, ok)
+				require.Nil(t, thrown)
+			}
+
+			var deferred []string
+			for _, err := range rerr.Deferred {
+				deferred = append(deferred, err.Error())
+			}
+			require.Equal(t, tt.deferred, deferred)
+			unwrapped := rerr.Unwrap()
+			require.Len(t, unwrapped, 1+len(rerr.Deferred))
+			require.Equal(t, rerr.Err, unwrapped[0])
+
+			require.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestRuntimeErrorFrames(t *testing.T) {
+	type frame struct {
+		function string
+		module   string
+	}
+	fsys := fstest.MapFS{
+		"lib/mod.toy": {Data: []byte(`
+export boom := fn() { throw "boom" }
+export call := fn(f) { f() }`)},
+	}
+	tests := []struct {
+		name string
+		src  string
+		want []frame // frames starting from the innermost one
+	}{
+		{
+			name: "variable",
+			src:  `f := fn() { throw "x" }; f()`,
+			want: []frame{{"f", "(main)"}, {"", "(main)"}},
+		},
+		{
+			name: "local variable",
+			src:  `f := fn() { g := fn() { throw "x" }; g() }; f()`,
+			want: []frame{{"g", "(main)"}, {"f", "(main)"}, {"", "(main)"}},
+		},
+		{
+			name: "table key",
+			src:  `t := {g: fn() { throw "x" }}; t.g()`,
+			want: []frame{{"g", "(main)"}, {"", "(main)"}},
+		},
+		{
+			name: "selector",
+			src:  `t := {}; t.h = fn() { throw "x" }; t.h()`,
+			want: []frame{{"t.h", "(main)"}, {"", "(main)"}},
+		},
+		{
+			name: "anonymous",
+			src:  `fn() { throw "x" }()`,
+			want: []frame{{"", "(main)"}, {"", "(main)"}},
+		},
+		{
+			name: "module member",
+			src:  `m := import("/lib/mod"); m.boom()`,
+			want: []frame{{"boom", "/lib/mod.toy"}, {"", "(main)"}},
+		},
+		{
+			name: "callback",
+			src:  `m := import("/lib/mod"); cb := fn() { throw "x" }; m.call(cb)`,
+			want: []frame{{"cb", "(main)"}, {"call", "/lib/mod.toy"}, {"", "(main)"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := toy.NewScript([]byte(tt.src))
+			script.SetFS(fsys)
+			script.EnableFileImport(true)
+			_, err := script.RunContext(context.Background())
+			var rerr *toy.RuntimeError
+			require.True(t, errors.As(err, &rerr), "unexpected error: %v", err)
+			var got []frame
+			for _, f := range rerr.Frames {
+				got = append(got, frame{f.Function, f.Module})
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompiledFunctionString(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`f := fn() {}; return f`, "<compiled-function f>"},
+		{`return {g: fn() {}}.g`, "<compiled-function g>"},
+		{`t := {}; t.h = fn() {}; return t.h`, "<compiled-function t.h>"},
+		{`return fn() {}`, "<compiled-function>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			compiled, err := toy.NewScript([]byte(tt.src)).Run()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, compiled.Result().String())
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package toy
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+
+	"github.com/infastin/toy/token"
+)
+
+// ErrorKind represents a kind of errors.
+// Kinds allow scripts to tell errors apart,
+// e.g. a missing file from an invalid configuration.
+//
+// Calling a kind with a message and an optional cause
+// creates a new error of this kind.
+type ErrorKind struct {
+	name   string
+	target error // Go errors matching target belong to the kind
+}
+
+// NewErrorKind creates a new kind of errors with the given name.
+func NewErrorKind(name string) *ErrorKind {
+	return &ErrorKind{name: name}
+}
+
+var ErrorKindType = NewType[*ErrorKind]("errorKind", func(_ *Runtime, args ...Value) (Value, error) {
+	var name string
+	if err := UnpackArgs(args, "name", &name); err != nil {
+		return nil, err
+	}
+	return NewErrorKind(name), nil
+})
+
+// Predefined kinds of errors.
+var (
+	// ErrorKindError is the kind of errors created by scripts.
+	ErrorKindError = NewErrorKind("error")
+	// ErrorKindException is the kind of thrown values that are not errors.
+	ErrorKindException = NewErrorKind("exception")
+	// ErrorKindRuntime is the kind of runtime errors
+	// that do not belong to any other kind.
+	ErrorKindRuntime = NewErrorKind("runtime")
+	// ErrorKindInvalidType is the kind of errors caused by values of unexpected types.
+	ErrorKindInvalidType = NewErrorKind("type")
+	// ErrorKindNotExist is the kind of errors caused by missing files.
+	ErrorKindNotExist = &ErrorKind{name: "notExist", target: fs.ErrNotExist}
+	// ErrorKindExist is the kind of errors caused by already existing files.
+	ErrorKindExist = &ErrorKind{name: "exist", target: fs.ErrExist}
+	// ErrorKindPermission is the kind of errors caused by insufficient permissions.
+	ErrorKindPermission = &ErrorKind{name: "permission", target: fs.ErrPermission}
+	// ErrorKindDenied is the kind of errors caused by the policy.
+	ErrorKindDenied = &ErrorKind{name: "denied", target: ErrDenied}
+)
+
+// errorKindsByTarget is checked in order to find the kind of a Go error.
+var errorKindsByTarget = []*ErrorKind{
+	ErrorKindDenied,
+	ErrorKindNotExist,
+	ErrorKindExist,
+	ErrorKindPermission,
+}
+
+// Name returns the name of the kind.
+func (k *ErrorKind) Name() string { return k.name }
+
+func (k *ErrorKind) Type() ValueType { return ErrorKindType }
+func (k *ErrorKind) String() string  { return "<errorKind " + k.name + ">" }
+func (k *ErrorKind) IsFalsy() bool   { return false }
+func (k *ErrorKind) Clone() Value    { return k }
+
+func (k *ErrorKind) Call(_ *Runtime, args ...Value) (Value, error) {
+	var (
+		msg   string
+		cause Value = Nil
+	)
+	if err := UnpackArgs(args, "msg", &msg, "cause?", &cause); err != nil {
+		return nil, err
+	}
+	switch x := cause.(type) {
+	case *Error:
+		return NewError(k, msg, x), nil
+	case NilValue:
+		return NewError(k, msg, nil), nil
+	default:
+		return nil, &InvalidArgumentTypeError{
+			Name: "cause",
+			Want: "error or nil",
+			Got:  TypeName(cause),
+		}
+	}
+}
+
+func (k *ErrorKind) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	switch string(keyStr) {
+	case "name":
+		return String(k.name), true, nil
+	}
+	return Nil, false, nil
+}
+
+// kindOf returns the kind of the Go error.
+func kindOf(err error) *ErrorKind {
+	var (
+		keyErr   *InvalidKeyTypeError
+		valueErr *InvalidValueTypeError
+		argErr   *InvalidArgumentTypeError
+	)
+	switch {
+	case errors.As(err, &keyErr),
+		errors.As(err, &valueErr),
+		errors.As(err, &argErr),
+		errors.Is(err, ErrInvalidOperation),
+		errors.Is(err, ErrNotConvertible):
+		return ErrorKindInvalidType
+	}
+	for _, kind := range errorKindsByTarget {
+		if errors.Is(err, kind.target) {
+			return kind
+		}
+	}
+	return ErrorKindRuntime
+}
+
+// Error represents an error value.
+//
+// Errors are created by scripts using error kinds
+// and by catching exceptions with try.
+// Throwing an error that has been caught rethrows the original exception,
+// so the stacktrace where it has occurred is preserved.
+type Error struct {
+	kind  *ErrorKind
+	msg   string
+	value Value
+	cause *Error
+	trace []Frame
+	err   error  // the caught Go error, if any
+	orig  *Error // the error this one is a copy of, if any
+}
+
+// NewError creates a new error of the given kind with an optional cause.
+func NewError(kind *ErrorKind, msg string, cause *Error) *Error {
+	return &Error{
+		kind:  kind,
+		msg:   msg,
+		value: Nil,
+		cause: cause,
+	}
+}
+
+// newErrorFrom turns the error returned by a call into an error value.
+func newErrorFrom(err error) *Error {
+	var frames []Frame
+	if rErr := (*RuntimeError)(nil); errors.As(err, &rErr) {
+		frames = rErr.Frames
+	}
+	if exc := (*Exception)(nil); errors.As(err, &exc) {
+		if e, ok := exc.Value.(*Error); ok {
+			// a thrown error: extend it with the trace
+			// of the place it has been thrown from
+			c := *e
+			c.trace = frames
+			c.err = err
+			c.orig = e.root()
+			return &c
+		}
+		return &Error{
+			kind:  ErrorKindException,
+			msg:   exc.Error(),
+			value: exc.Value,
+			trace: frames,
+			err:   err,
+		}
+	}
+	msg := err.Error()
+	if rErr := (*RuntimeError)(nil); errors.As(err, &rErr) {
+		// we only care about the initial error
+		msg = rErr.Err.Error()
+	}
+	return &Error{
+		kind:  kindOf(err),
+		msg:   msg,
+		value: Nil,
+		trace: frames,
+		err:   err,
+	}
+}
+
+var ErrorType = NewType[*Error]("error", func(_ *Runtime, args ...Value) (Value, error) {
+	return ErrorKindError.Call(nil, args...)
+})
+
+// Kind returns the kind of the error.
+func (e *Error) Kind() *ErrorKind { return e.kind }
+
+// Message returns the message of the error.
+func (e *Error) Message() string { return e.msg }
+
+// Value returns the thrown value, or Nil if the error
+// has not been created from a thrown value.
+func (e *Error) Value() Value { return e.value }
+
+// Cause returns the error that caused this one, or nil.
+func (e *Error) Cause() *Error { return e.cause }
+
+// Trace returns the stacktrace of the place the error has been caught from,
+// starting from the innermost frame.
+func (e *Error) Trace() []Frame { return slices.Clone(e.trace) }
+
+// Is reports whether any error in the chain of causes
+// is of the given kind.
+func (e *Error) Is(kind *ErrorKind) bool {
+	for ; e != nil; e = e.cause {
+		if e.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// throw returns the Go error that throwing e should produce.
+func (e *Error) throw() error {
+	if e.err != nil {
+		// rethrow the original error, preserving its trace
+		if rErr := (*RuntimeError)(nil); errors.As(e.err, &rErr) {
+			return &RuntimeError{
+				Err:      rErr.Err,
+				Deferred: slices.Clone(rErr.Deferred),
+				Frames:   slices.Clone(rErr.Frames),
+			}
+		}
+		return e.err
+	}
+	return &Exception{Value: e}
+}
+
+func (e *Error) root() *Error {
+	if e.orig != nil {
+		return e.orig
+	}
+	return e
+}
+
+func (e *Error) Type() ValueType { return ErrorType }
+func (e *Error) IsFalsy() bool   { return false }
+func (e *Error) Clone() Value    { return e }
+
+func (e *Error) String() string {
+	if e.cause != nil {
+		return e.msg + ": " + e.cause.String()
+	}
+	return e.msg
+}
+
+func (e *Error) Compare(op token.Token, rhs Value) (bool, error) {
+	y, ok := rhs.(*Error)
+	if !ok {
+		return false, ErrInvalidOperation
+	}
+	switch op {
+	case token.Equal:
+		return e.root() == y.root(), nil
+	case token.NotEqual:
+		return e.root() != y.root(), nil
+	}
+	return false, ErrInvalidOperation
+}
+
+func (e *Error) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	switch string(keyStr) {
+	case "msg":
+		return String(e.String()), true, nil
+	case "val":
+		return e.value, true, nil
+	case "kind":
+		return e.kind, true, nil
+	case "cause":
+		if e.cause == nil {
+			return Nil, true, nil
+		}
+		return e.cause, true, nil
+	case "trace":
+		trace := make([]Value, 0, len(e.trace))
+		for _, frame := range e.trace {
+			t := NewTable(3)
+			if frame.Function != "" {
+				t.SetProperty(String("function"), String(frame.Function))
+			}
+			t.SetProperty(String("module"), String(frame.Module))
+			t.SetProperty(String("pos"), String(frame.Pos.String()))
+			trace = append(trace, t.Freeze())
+		}
+		return NewArray(trace).Freeze(), true, nil
+	}
+	return Nil, false, nil
+}
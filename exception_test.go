@@ -0,0 +1,122 @@
+package toy_test
+
+import (
+	"testing"
+
+	"github.com/infastin/toy"
+)
+
+func TestErrorsModule(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{
+			name: "new",
+			src:  `errors := import("errors"); e := errors.new("boom"); return [e.msg, e.kind == errors.Error, e.cause]`,
+			want: toy.NewArray([]toy.Value{toy.String("boom"), toy.True, toy.Nil}),
+		},
+		{
+			name: "custom kind",
+			src: `
+errors := import("errors")
+Config := errors.kind("config")
+e := Config("invalid config")
+return [errors.is(e, Config), errors.is(e, errors.Error), e.kind == Config]`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.False, toy.True}),
+		},
+		{
+			name: "kind with cause",
+			src:  `errors := import("errors"); e := errors.Error("outer", errors.new("inner")); return [e.msg, e.cause.msg]`,
+			want: toy.NewArray([]toy.Value{toy.String("outer: inner"), toy.String("inner")}),
+		},
+		{
+			name: "wrap",
+			src: `
+errors := import("errors")
+NotFound := errors.kind("notFound")
+inner := NotFound("missing")
+e := errors.wrap(inner, "load")
+return [e.msg, errors.unwrap(e) == inner, errors.is(e, NotFound), errors.is(e, inner)]`,
+			want: toy.NewArray([]toy.Value{toy.String("load: missing"), toy.True, toy.True, toy.True}),
+		},
+		{
+			name: "wrap nil",
+			src:  `errors := import("errors"); return errors.wrap(nil, "msg")`,
+			want: toy.Nil,
+		},
+		{
+			name: "unwrap without cause",
+			src:  `errors := import("errors"); return errors.unwrap(errors.new("x"))`,
+			want: toy.Nil,
+		},
+		{
+			name: "is other error",
+			src:  `errors := import("errors"); return errors.is(errors.new("x"), errors.new("x"))`,
+			want: toy.False,
+		},
+		{
+			name: "is invalid target",
+			src:  `errors := import("errors"); errors.is(errors.new("x"), 1)`,
+			err:  "invalid type for argument 'target': want 'error or errorKind', got 'int'",
+		},
+		{
+			name: "wrap invalid error",
+			src:  `errors := import("errors"); errors.wrap(1, "msg")`,
+			err:  "invalid type for argument 'err': want 'error or nil', got 'int'",
+		},
+		{
+			name: "not exist",
+			src: `
+errors := import("errors")
+os := import("os")
+_, err := try os.readfile("/nonexistent")
+return [errors.is(err, errors.NotExist), errors.is(err, errors.Permission)]`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.False}),
+		},
+		{
+			name: "trace of builtin error",
+			src: `
+os := import("os")
+f := fn() {
+	_, err := try os.readfile("/nonexistent")
+	return err
+}
+trace := f().trace
+return [len(trace), trace[0].function, trace[0].pos]`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.String("f"), toy.String("(main):4:12")}),
+		},
+		{
+			name: "trace of caught builtin error",
+			src: `
+os := import("os")
+try { os.readfile("/nonexistent") } catch err { return err.trace[0].pos }`,
+			want: toy.String("(main):3:7"),
+		},
+		{
+			name: "thrown value",
+			src:  `errors := import("errors"); try { throw 1 } catch e { return [errors.is(e, errors.Exception), e.val] }`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.Int(1)}),
+		},
+		{
+			name: "type error",
+			src:  `errors := import("errors"); _, err := try fn() { return 1 + "a" }(); return errors.is(err, errors.Type)`,
+			want: toy.True,
+		},
+		{
+			name: "runtime error",
+			src:  `errors := import("errors"); _, err := try fn() { return 1 / 0 }(); return [errors.is(err, errors.Runtime), err.msg]`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.String("division by zero")}),
+		},
+		{
+			name: "rethrow preserves trace",
+			src: `
+errors := import("errors")
+f := fn() { throw errors.new("x") }
+orig := nil
+try {
+	try { f() } catch e { orig = e; throw e }
+} catch e {
+	return [e == orig, e.trace[0].function]
+}`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.String("f")}),
+		},
+	}, nil)
+}
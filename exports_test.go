@@ -0,0 +1,84 @@
+package toy_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/infastin/toy"
+)
+
+func TestExports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/math.toy": {Data: []byte(`
+export pi := 3
+area := fn(r) { return pi * r * r }
+export area
+hidden := 42
+`)},
+		"lib/plain.toy":   {Data: []byte(`return {name: "plain"}`)},
+		"lib/return.toy":  {Data: []byte("export x := 1\nreturn x")},
+		"lib/nested.toy":  {Data: []byte("if true { export x := 1 }")},
+		"lib/twice.toy":   {Data: []byte("export x := 1\nexport x")},
+		"lib/missing.toy": {Data: []byte("export x")},
+	}
+	runScriptTests(t, []scriptTest{
+		{
+			name: "exported members",
+			src:  `m := import("/lib/math"); return m.area(2) + m.pi`,
+			want: toy.Int(15),
+		},
+		{
+			name: "unexported member",
+			src:  `m := import("/lib/math"); return m.hidden`,
+			err:  "module '/lib/math' has no member 'hidden'",
+		},
+		{
+			name: "unexported member of import expression",
+			src:  `return import("/lib/math").hidden`,
+			err:  "module '/lib/math' has no member 'hidden'",
+		},
+		{
+			name: "member with ok",
+			src:  `m := import("/lib/math"); v, ok := m.hidden; return v, ok`,
+			want: toy.Tuple{toy.Nil, toy.False},
+		},
+		{
+			name: "reassigned module",
+			src:  `m := import("/lib/math"); m = {hidden: 1}; return m.hidden`,
+			want: toy.Int(1),
+		},
+		{
+			name: "module without exports",
+			src:  `m := import("/lib/plain"); return m.missing`,
+			want: toy.Nil,
+		},
+		{
+			name: "missing member of builtin module",
+			src:  `fmt := import("fmt"); return fmt.missing`,
+			want: toy.Nil,
+		},
+		{
+			name: "return in module with exports",
+			src:  `return import("/lib/return")`,
+			err:  "return is not allowed in a module with exports",
+		},
+		{
+			name: "nested export",
+			src:  `return import("/lib/nested")`,
+			err:  "export is only allowed at the top level",
+		},
+		{
+			name: "exported twice",
+			src:  `return import("/lib/twice")`,
+			err:  "'x' exported twice",
+		},
+		{
+			name: "undefined export",
+			src:  `return import("/lib/missing")`,
+			err:  "unresolved reference 'x'",
+		},
+	}, func(s *toy.Script) {
+		s.SetFS(fsys)
+		s.EnableFileImport(true)
+	})
+}
@@ -0,0 +1,484 @@
+package toy
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WritableFS is a filesystem that supports creation,
+// modification and removal of files.
+//
+// Additionally, WritableFS may implement any of the following methods,
+// that have the same signature as the corresponding functions of the os package:
+// Lstat, Readlink, Link, Symlink, Chmod, Chown, Lchown and Truncate.
+type WritableFS interface {
+	fs.FS
+	// OpenFile opens the named file with the specified flag (os.O_RDONLY etc.).
+	// If the file does not exist, and the os.O_CREATE flag is passed,
+	// it is created with mode perm (before umask).
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+	// Mkdir creates a new directory with the specified name and permission bits.
+	Mkdir(name string, perm fs.FileMode) error
+	// Remove removes the named file or (empty) directory.
+	Remove(name string) error
+	// Rename renames (moves) oldpath to newpath.
+	Rename(oldpath, newpath string) error
+}
+
+// FS provides access to the filesystem used by the runtime.
+//
+// Names are interpreted as host paths if the runtime uses the host filesystem.
+// Otherwise, names are slash-separated paths resolved against
+// the root of the virtual filesystem: "/a/b", "a/b" and "../a/b"
+// all refer to the same file.
+//
+// Operations that modify files fail with errors.ErrUnsupported
+// if the virtual filesystem doesn't implement WritableFS
+// or the corresponding optional method.
+type FS struct {
+	fsys fs.FS // nil means the host filesystem
+}
+
+// hostFS provides access to the host filesystem.
+var hostFS = &FS{}
+
+// NewFS returns FS that provides access to the given filesystem.
+// If fsys is nil, FS provides access to the host filesystem.
+func NewFS(fsys fs.FS) *FS {
+	if fsys == nil {
+		return hostFS
+	}
+	return &FS{fsys: fsys}
+}
+
+// SetFS sets the filesystem used by the runtime.
+// If fsys is nil, the runtime uses the host filesystem.
+func (r *Runtime) SetFS(fsys fs.FS) {
+	r.fs = NewFS(fsys)
+}
+
+// FS returns the filesystem used by the runtime.
+// It is safe to call FS on a nil Runtime,
+// in which case the host filesystem is returned.
+func (r *Runtime) FS() *FS {
+	if r == nil || r.fs == nil {
+		return hostFS
+	}
+	return r.fs
+}
+
+// IsHost reports whether fsys provides access to the host filesystem.
+func (fsys *FS) IsHost() bool {
+	return fsys.fsys == nil
+}
+
+// Clean returns the shortest name equivalent to the given one.
+// If fsys is virtual, the returned name is valid for use with fs.FS.
+func (fsys *FS) Clean(name string) string {
+	if fsys.fsys == nil {
+		return filepath.Clean(name)
+	}
+	return fsPath(name)
+}
+
+// Open opens the named file for reading.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if fsys.fsys == nil {
+		return os.Open(name)
+	}
+	return fsys.fsys.Open(fsPath(name))
+}
+
+// OpenFile opens the named file with the specified flag and permission bits.
+func (fsys *FS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	if fsys.fsys == nil {
+		return os.OpenFile(name, flag, perm)
+	}
+	if flag == os.O_RDONLY {
+		return fsys.fsys.Open(fsPath(name))
+	}
+	wfs, err := fsys.writable("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return wfs.OpenFile(fsPath(name), flag, perm)
+}
+
+// Create creates or truncates the named file.
+func (fsys *FS) Create(name string) (fs.File, error) {
+	return fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// ReadFile reads the named file and returns its contents.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	if fsys.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(fsys.fsys, fsPath(name))
+}
+
+// WriteFile writes data to the named file, creating it if necessary.
+func (fsys *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if fsys.fsys == nil {
+		return os.WriteFile(name, data, perm)
+	}
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	w, ok := f.(io.Writer)
+	if !ok {
+		f.Close()
+		return &fs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+	}
+	_, err = w.Write(data)
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return err
+}
+
+// ReadDir reads the named directory
+// and returns all its directory entries sorted by filename.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if fsys.fsys == nil {
+		return os.ReadDir(name)
+	}
+	return fs.ReadDir(fsys.fsys, fsPath(name))
+}
+
+// Stat returns a FileInfo describing the named file.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	if fsys.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(fsys.fsys, fsPath(name))
+}
+
+// Lstat returns a FileInfo describing the named file
+// without following symbolic links.
+// If the virtual filesystem doesn't support symbolic links, Lstat is the same as Stat.
+func (fsys *FS) Lstat(name string) (fs.FileInfo, error) {
+	if fsys.fsys == nil {
+		return os.Lstat(name)
+	}
+	if lfs, ok := fsys.fsys.(interface {
+		Lstat(name string) (fs.FileInfo, error)
+	}); ok {
+		return lfs.Lstat(fsPath(name))
+	}
+	return fs.Stat(fsys.fsys, fsPath(name))
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fsys *FS) Readlink(name string) (string, error) {
+	if fsys.fsys == nil {
+		return os.Readlink(name)
+	}
+	if lfs, ok := fsys.fsys.(interface {
+		Readlink(name string) (string, error)
+	}); ok {
+		return lfs.Readlink(fsPath(name))
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.ErrUnsupported}
+}
+
+// EvalSymlinks returns the path name after the evaluation of any symbolic links.
+// If the virtual filesystem doesn't support symbolic links,
+// EvalSymlinks returns the cleaned name of the existing file.
+func (fsys *FS) EvalSymlinks(name string) (string, error) {
+	if fsys.fsys == nil {
+		return filepath.EvalSymlinks(name)
+	}
+	if _, err := fs.Stat(fsys.fsys, fsPath(name)); err != nil {
+		return "", err
+	}
+	return rootedPath(name), nil
+}
+
+// Glob returns the names of all files matching pattern.
+func (fsys *FS) Glob(pattern string) ([]string, error) {
+	if fsys.fsys == nil {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(fsys.fsys, fsPath(pattern))
+}
+
+// Mkdir creates a new directory with the specified name and permission bits.
+func (fsys *FS) Mkdir(name string, perm fs.FileMode) error {
+	if fsys.fsys == nil {
+		return os.Mkdir(name, perm)
+	}
+	wfs, err := fsys.writable("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return wfs.Mkdir(fsPath(name), perm)
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents.
+func (fsys *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if fsys.fsys == nil {
+		return os.MkdirAll(name, perm)
+	}
+	wfs, err := fsys.writable("mkdir", name)
+	if err != nil {
+		return err
+	}
+	name = fsPath(name)
+	if name == "." {
+		return nil
+	}
+	dir := ""
+	for _, elem := range strings.Split(name, "/") {
+		dir = path.Join(dir, elem)
+		info, err := fs.Stat(wfs, dir)
+		if err == nil {
+			if !info.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: errors.New("not a directory")}
+			}
+			continue
+		}
+		if err := wfs.Mkdir(dir, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// MkdirTemp creates a new temporary directory in the directory dir
+// and returns the pathname of the new directory.
+// If dir is the empty string, MkdirTemp uses the default directory for temporary files.
+func (fsys *FS) MkdirTemp(dir, pattern string) (string, error) {
+	if fsys.fsys == nil {
+		return os.MkdirTemp(dir, pattern)
+	}
+	for range 10000 {
+		name := tempName(dir, pattern)
+		err := fsys.Mkdir(name, 0700)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+	return "", &fs.PathError{Op: "mkdirtemp", Path: path.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+// CreateTemp creates a new temporary file in the directory dir,
+// opens the file for reading and writing, and returns the resulting file and its name.
+// If dir is the empty string, CreateTemp uses the default directory for temporary files.
+func (fsys *FS) CreateTemp(dir, pattern string) (fs.File, string, error) {
+	if fsys.fsys == nil {
+		f, err := os.CreateTemp(dir, pattern)
+		if err != nil {
+			return nil, "", err
+		}
+		return f, f.Name(), nil
+	}
+	for range 10000 {
+		name := tempName(dir, pattern)
+		f, err := fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return f, name, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, "", err
+		}
+	}
+	return nil, "", &fs.PathError{Op: "createtemp", Path: path.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+// Remove removes the named file or (empty) directory.
+func (fsys *FS) Remove(name string) error {
+	if fsys.fsys == nil {
+		return os.Remove(name)
+	}
+	wfs, err := fsys.writable("remove", name)
+	if err != nil {
+		return err
+	}
+	return wfs.Remove(fsPath(name))
+}
+
+// RemoveAll removes the named file and any children it contains.
+func (fsys *FS) RemoveAll(name string) error {
+	if fsys.fsys == nil {
+		return os.RemoveAll(name)
+	}
+	wfs, err := fsys.writable("remove", name)
+	if err != nil {
+		return err
+	}
+	var names []string
+	err = fs.WalkDir(wfs, fsPath(name), func(name string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// children are visited after their parents
+	for _, name := range slices.Backward(names) {
+		if err := wfs.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath.
+func (fsys *FS) Rename(oldpath, newpath string) error {
+	if fsys.fsys == nil {
+		return os.Rename(oldpath, newpath)
+	}
+	wfs, err := fsys.writable("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	return wfs.Rename(fsPath(oldpath), fsPath(newpath))
+}
+
+// Link creates newname as a hard link to the oldname file.
+func (fsys *FS) Link(oldname, newname string) error {
+	if fsys.fsys == nil {
+		return os.Link(oldname, newname)
+	}
+	if lfs, ok := fsys.fsys.(interface {
+		Link(oldname, newname string) error
+	}); ok {
+		return lfs.Link(fsPath(oldname), fsPath(newname))
+	}
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (fsys *FS) Symlink(oldname, newname string) error {
+	if fsys.fsys == nil {
+		return os.Symlink(oldname, newname)
+	}
+	if lfs, ok := fsys.fsys.(interface {
+		Symlink(oldname, newname string) error
+	}); ok {
+		return lfs.Symlink(oldname, fsPath(newname))
+	}
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errors.ErrUnsupported}
+}
+
+// Chmod changes the mode of the named file to mode.
+func (fsys *FS) Chmod(name string, mode fs.FileMode) error {
+	if fsys.fsys == nil {
+		return os.Chmod(name, mode)
+	}
+	if cfs, ok := fsys.fsys.(interface {
+		Chmod(name string, mode fs.FileMode) error
+	}); ok {
+		return cfs.Chmod(fsPath(name), mode)
+	}
+	return &fs.PathError{Op: "chmod", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fsys *FS) Chown(name string, uid, gid int) error {
+	if fsys.fsys == nil {
+		return os.Chown(name, uid, gid)
+	}
+	if cfs, ok := fsys.fsys.(interface {
+		Chown(name string, uid, gid int) error
+	}); ok {
+		return cfs.Chown(fsPath(name), uid, gid)
+	}
+	return &fs.PathError{Op: "chown", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Lchown changes the numeric uid and gid of the named file
+// without following symbolic links.
+func (fsys *FS) Lchown(name string, uid, gid int) error {
+	if fsys.fsys == nil {
+		return os.Lchown(name, uid, gid)
+	}
+	if cfs, ok := fsys.fsys.(interface {
+		Lchown(name string, uid, gid int) error
+	}); ok {
+		return cfs.Lchown(fsPath(name), uid, gid)
+	}
+	return &fs.PathError{Op: "lchown", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Truncate changes the size of the named file.
+func (fsys *FS) Truncate(name string, size int64) error {
+	if fsys.fsys == nil {
+		return os.Truncate(name, size)
+	}
+	if tfs, ok := fsys.fsys.(interface {
+		Truncate(name string, size int64) error
+	}); ok {
+		return tfs.Truncate(fsPath(name), size)
+	}
+	return &fs.PathError{Op: "truncate", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Chdir changes the current working directory to the named directory.
+// Virtual filesystems don't have the current working directory.
+func (fsys *FS) Chdir(dir string) error {
+	if fsys.fsys == nil {
+		return os.Chdir(dir)
+	}
+	return &fs.PathError{Op: "chdir", Path: dir, Err: errors.ErrUnsupported}
+}
+
+// Getwd returns a rooted path name corresponding to the current directory.
+// The current directory of a virtual filesystem is always its root.
+func (fsys *FS) Getwd() (string, error) {
+	if fsys.fsys == nil {
+		return os.Getwd()
+	}
+	return "/", nil
+}
+
+// writable returns the underlying filesystem if it implements WritableFS.
+func (fsys *FS) writable(op, name string) (WritableFS, error) {
+	wfs, ok := fsys.fsys.(WritableFS)
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: errors.ErrUnsupported}
+	}
+	return wfs, nil
+}
+
+// fsPath converts the given name to a name valid for use with fs.FS.
+func fsPath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// rootedPath converts the given name to a rooted slash-separated path.
+func rootedPath(name string) string {
+	return path.Clean("/" + filepath.ToSlash(name))
+}
+
+// tempName returns a random name of a temporary file in the given directory.
+func tempName(dir, pattern string) string {
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndexByte(pattern, '*'); i != -1 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	return path.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+}
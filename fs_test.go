@@ -0,0 +1,224 @@
+package toy_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+// memFS is a writable in-memory filesystem.
+type memFS struct {
+	fstest.MapFS
+}
+
+func (m memFS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+	f, ok := m.MapFS[name]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case ok && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok:
+		f = &fstest.MapFile{Mode: perm, ModTime: time.Now()}
+		m.MapFS[name] = f
+	}
+	if flag&os.O_TRUNC != 0 {
+		f.Data = nil
+	}
+	return &memFile{file: f, name: name}, nil
+}
+
+func (m memFS) Mkdir(name string, perm fs.FileMode) error {
+	if _, ok := m.MapFS[name]; ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	m.MapFS[name] = &fstest.MapFile{Mode: fs.ModeDir | perm}
+	return nil
+}
+
+func (m memFS) Remove(name string) error {
+	if _, ok := m.MapFS[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.MapFS, name)
+	return nil
+}
+
+func (m memFS) Rename(oldpath, newpath string) error {
+	f, ok := m.MapFS[oldpath]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrNotExist}
+	}
+	delete(m.MapFS, oldpath)
+	m.MapFS[newpath] = f
+	return nil
+}
+
+// memFile is an open file of memFS.
+type memFile struct {
+	file   *fstest.MapFile
+	name   string
+	offset int
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.offset >= len(f.file.Data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.file.Data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return fs.Stat(fstest.MapFS{f.name: f.file}, f.name)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.file.Data = append(f.file.Data, p...)
+	return len(p), nil
+}
+
+func (f *memFile) Close() error { return nil }
+
+func TestFS(t *testing.T) {
+	fsys := toy.NewFS(fstest.MapFS{
+		"a/b.txt": {Data: []byte("b")},
+		"a/c.txt": {Data: []byte("c")},
+	})
+	require.False(t, fsys.IsHost())
+	require.True(t, toy.NewFS(nil).IsHost())
+
+	for _, name := range []string{"/a/b.txt", "a/b.txt", "../a/b.txt", "/a/../a/./b.txt"} {
+		data, err := fsys.ReadFile(name)
+		require.NoError(t, err, name)
+		require.Equal(t, "b", string(data), name)
+	}
+	require.Equal(t, "a/b.txt", fsys.Clean("/a/./b.txt"))
+	require.Equal(t, ".", fsys.Clean("/"))
+
+	entries, err := fsys.ReadDir("/a")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "b.txt", entries[0].Name())
+
+	info, err := fsys.Stat("a")
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+
+	matches, err := fsys.Glob("/a/*.txt")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a/b.txt", "a/c.txt"}, matches)
+
+	resolved, err := fsys.EvalSymlinks("a/b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "/a/b.txt", resolved)
+
+	wd, err := fsys.Getwd()
+	require.NoError(t, err)
+	require.Equal(t, "/", wd)
+
+	_, err = fsys.ReadFile("/missing")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// the filesystem is read-only
+	require.ErrorIs(t, fsys.WriteFile("/a/d.txt", nil, 0o644), errors.ErrUnsupported)
+	require.ErrorIs(t, fsys.Mkdir("/d", 0o755), errors.ErrUnsupported)
+	require.ErrorIs(t, fsys.Remove("/a/b.txt"), errors.ErrUnsupported)
+	require.ErrorIs(t, fsys.Symlink("/a/b.txt", "/l"), errors.ErrUnsupported)
+	require.ErrorIs(t, fsys.Chdir("/a"), errors.ErrUnsupported)
+}
+
+func TestWritableFS(t *testing.T) {
+	m := memFS{MapFS: fstest.MapFS{}}
+	fsys := toy.NewFS(m)
+
+	require.NoError(t, fsys.MkdirAll("/a/b", 0o755))
+	require.True(t, m.MapFS["a"].Mode.IsDir())
+	require.True(t, m.MapFS["a/b"].Mode.IsDir())
+
+	require.NoError(t, fsys.WriteFile("/a/b/c.txt", []byte("c"), 0o644))
+	data, err := fsys.ReadFile("a/b/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, "c", string(data))
+
+	require.NoError(t, fsys.Rename("/a/b/c.txt", "/a/d.txt"))
+	_, err = fsys.Stat("/a/b/c.txt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	f, name, err := fsys.CreateTemp("/a", "tmp*.txt")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.True(t, strings.HasPrefix(name, "/a/tmp"), name)
+	require.Equal(t, ".txt", path.Ext(name))
+
+	require.NoError(t, fsys.RemoveAll("/a"))
+	require.Empty(t, m.MapFS)
+}
+
+func TestScriptFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/config.txt": {Data: []byte("value")},
+		"lib/util.toy":    {Data: []byte(`return {name: "util"}`)},
+	}
+	runScriptTests(t, []scriptTest{
+		{
+			name: "readfile",
+			src:  `os := import("os"); return string(os.readfile("/data/config.txt"))`,
+			want: toy.String("value"),
+		},
+		{
+			name: "relative readfile",
+			src:  `os := import("os"); return string(os.readfile("data/config.txt"))`,
+			want: toy.String("value"),
+		},
+		{
+			name: "readdir",
+			src:  `os := import("os"); return len(os.readdir("/"))`,
+			want: toy.Int(2),
+		},
+		{
+			name: "missing file",
+			src:  `os := import("os"); os.readfile("/etc/passwd")`,
+			err:  "file does not exist",
+		},
+		{
+			name: "read-only",
+			src:  `os := import("os"); os.writefile("/data/new.txt", "x")`,
+			err:  "unsupported operation",
+		},
+		{
+			name: "file import",
+			src:  `return import("/lib/util").name`,
+			want: toy.String("util"),
+		},
+	}, func(s *toy.Script) {
+		s.SetFS(fsys)
+		s.EnableFileImport(true)
+	})
+
+	m := memFS{MapFS: fstest.MapFS{}}
+	runScriptTests(t, []scriptTest{
+		{
+			name: "writefile",
+			src: `
+os := import("os")
+os.mkdir("/out")
+os.writefile("/out/data.txt", "data")
+return string(os.readfile("out/data.txt"))`,
+			want: toy.String("data"),
+		},
+	}, func(s *toy.Script) {
+		s.SetFS(m)
+	})
+	require.Equal(t, "data", string(m.MapFS["out/data.txt"].Data))
+}
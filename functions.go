@@ -75,14 +75,37 @@ type CompiledFunction struct {
 	numParameters int
 	numOptionals  int
 	varArgs       bool
-	sourceMap     map[int]token.Pos
-	deferMap      []token.Pos
+	sourceMap     map[int]sourceRange
+	deferMap      []sourceRange
 	free          []*valuePtr
+	name          string // name the function literal is bound to, if any
+	module        string // path of the module the function is defined in
 }
 
 func (f *CompiledFunction) Type() ValueType { return FunctionType }
-func (f *CompiledFunction) String() string  { return "<compiled-function>" }
-func (f *CompiledFunction) IsFalsy() bool   { return false }
+func (f *CompiledFunction) String() string {
+	if f.name == "" {
+		return "<compiled-function>"
+	}
+	return fmt.Sprintf("<compiled-function %s>", f.name)
+}
+
+func (f *CompiledFunction) IsFalsy() bool { return false }
+
+// Name returns the name the function literal is bound to,
+// such as the name of the variable or the table key.
+// Returns an empty string for anonymous functions.
+func (f *CompiledFunction) Name() string { return f.name }
+
+// Module returns the path of the module the function is defined in.
+// Returns an empty string for functions defined in the main script.
+func (f *CompiledFunction) Module() string { return f.module }
+
+// FormatInstructions returns human readable string representations
+// of the compiled instructions of the function.
+func (f *CompiledFunction) FormatInstructions() []string {
+	return bytecode.FormatInstructions(f.instructions, 0)
+}
 
 func (f *CompiledFunction) Clone() Value {
 	return &CompiledFunction{
@@ -94,6 +117,8 @@ func (f *CompiledFunction) Clone() Value {
 		sourceMap:     f.sourceMap,
 		deferMap:      f.deferMap,
 		free:          slices.Clone(f.free), // DO NOT Clone() of elements; these are variable pointers
+		name:          f.name,
+		module:        f.module,
 	}
 }
 
@@ -168,6 +193,8 @@ func (f *CompiledFunction) WithReceiver(recv Value) *CompiledFunction {
 		sourceMap:     f.sourceMap,
 		deferMap:      f.deferMap,
 		free:          slices.Clone(f.free),
+		name:          f.name,
+		module:        f.module,
 	}
 }
 
@@ -177,13 +204,19 @@ func (f *CompiledFunction) NumParameters() int   { return f.numParameters }
 func (f *CompiledFunction) NumOptionals() int    { return f.numOptionals }
 func (f *CompiledFunction) VarArgs() bool        { return f.varArgs }
 
-// sourcePos returns the source position of the instruction at ip.
-func (f *CompiledFunction) sourcePos(ip int) token.Pos {
+// sourceRange is the range of the source code
+// an instruction has been compiled from.
+type sourceRange struct {
+	pos, end token.Pos
+}
+
+// sourceAt returns the source range of the instruction at ip.
+func (f *CompiledFunction) sourceAt(ip int) sourceRange {
 	for ip >= 0 {
-		if p, ok := f.sourceMap[ip]; ok {
-			return p
+		if src, ok := f.sourceMap[ip]; ok {
+			return src
 		}
 		ip--
 	}
-	return token.NoPos
+	return sourceRange{}
 }
@@ -0,0 +1,459 @@
+package toy
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/infastin/toy/token"
+)
+
+// TypeBuilder declares a Toy type for values of the Go type T.
+// Use NewTypeBuilder to create a TypeBuilder,
+// declare the behavior of the type with its methods
+// and call Build to obtain the resulting HostType.
+//
+//	var PointType = toy.NewTypeBuilder[*Point]("geo.Point").
+//		Constructor(newPoint).
+//		String(func(p *Point) string { return fmt.Sprintf("geo.Point(%g, %g)", p.X, p.Y) }).
+//		Property("x", func(p *Point) toy.Value { return toy.Float(p.X) }).
+//		Method("dist", pointDist).
+//		Build()
+type TypeBuilder[T any] struct {
+	t *HostType[T]
+}
+
+// NewTypeBuilder creates a new TypeBuilder for the type with the given name.
+func NewTypeBuilder[T any](name string) *TypeBuilder[T] {
+	return &TypeBuilder[T]{
+		t: &HostType[T]{
+			name:       name,
+			properties: make(map[string]func(T) Value),
+			setters:    make(map[string]func(T, Value) error),
+			methods:    make(map[string]*BuiltinFunction),
+			binaryOps:  make(map[token.Token]func(T, Value, bool) (Value, error)),
+			unaryOps:   make(map[token.Token]func(T) (Value, error)),
+		},
+	}
+}
+
+// Constructor sets the function called when the type is called.
+// If no constructor is set, calling the type with a single argument
+// returns the argument if it is already of this type,
+// or tries to convert the argument into T.
+func (b *TypeBuilder[T]) Constructor(fn func(r *Runtime, args ...Value) (T, error)) *TypeBuilder[T] {
+	b.t.constructor = fn
+	return b
+}
+
+// String sets the function returning the string representation of values.
+// By default, the name of the type in angle brackets is used.
+func (b *TypeBuilder[T]) String(fn func(x T) string) *TypeBuilder[T] {
+	b.t.str = fn
+	return b
+}
+
+// IsFalsy sets the function reporting whether a value is falsy.
+// By default, values are never falsy.
+func (b *TypeBuilder[T]) IsFalsy(fn func(x T) bool) *TypeBuilder[T] {
+	b.t.isFalsy = fn
+	return b
+}
+
+// Clone sets the function returning a deep-copy of a value.
+// By default, Clone returns the same value,
+// which is only allowed for types without setters.
+func (b *TypeBuilder[T]) Clone(fn func(x T) T) *TypeBuilder[T] {
+	b.t.clone = fn
+	return b
+}
+
+// Property declares a property with the given name.
+func (b *TypeBuilder[T]) Property(name string, get func(x T) Value) *TypeBuilder[T] {
+	b.t.properties[name] = get
+	return b
+}
+
+// Setter makes the property with the given name assignable.
+// Values of types with setters are mutable, so the type must declare Clone.
+// Properties of frozen values are not assignable,
+// and methods of frozen values are called on a copy of the value.
+func (b *TypeBuilder[T]) Setter(name string, set func(x T, value Value) error) *TypeBuilder[T] {
+	b.t.setters[name] = set
+	return b
+}
+
+// Method declares a method with the given name.
+// Properties take precedence over methods with the same name.
+func (b *TypeBuilder[T]) Method(name string, fn func(r *Runtime, recv T, args ...Value) (Value, error)) *TypeBuilder[T] {
+	t := b.t
+	b.t.methods[name] = NewBuiltinFunction(name, func(r *Runtime, args ...Value) (Value, error) {
+		recv, _ := t.Unwrap(args[0])
+		return fn(r, recv, args[1:]...)
+	})
+	return b
+}
+
+// BinaryOp declares a binary operation with the given operator.
+// See HasBinaryOp for details.
+func (b *TypeBuilder[T]) BinaryOp(op token.Token, fn func(x T, other Value, right bool) (Value, error)) *TypeBuilder[T] {
+	b.t.binaryOps[op] = fn
+	return b
+}
+
+// UnaryOp declares an unary operation with the given operator.
+func (b *TypeBuilder[T]) UnaryOp(op token.Token, fn func(x T) (Value, error)) *TypeBuilder[T] {
+	b.t.unaryOps[op] = fn
+	return b
+}
+
+// Compare sets the function comparing values with other values.
+// See Comparable for details.
+func (b *TypeBuilder[T]) Compare(fn func(op token.Token, x T, rhs Value) (bool, error)) *TypeBuilder[T] {
+	b.t.compare = fn
+	return b
+}
+
+// Hash makes values hashable using the given function.
+func (b *TypeBuilder[T]) Hash(fn func(x T) uint64) *TypeBuilder[T] {
+	b.t.hash = fn
+	return b
+}
+
+// Len makes values sized using the given function.
+func (b *TypeBuilder[T]) Len(fn func(x T) int) *TypeBuilder[T] {
+	b.t.len = fn
+	return b
+}
+
+// Elements makes values iterable using the given function.
+func (b *TypeBuilder[T]) Elements(fn func(x T) iter.Seq[Value]) *TypeBuilder[T] {
+	b.t.elements = fn
+	b.t.entries = nil
+	return b
+}
+
+// Entries makes values key-value iterable using the given function.
+// Entries and Elements are mutually exclusive.
+func (b *TypeBuilder[T]) Entries(fn func(x T) iter.Seq2[Value, Value]) *TypeBuilder[T] {
+	b.t.entries = fn
+	b.t.elements = nil
+	return b
+}
+
+// Convert sets the function converting values to other types.
+// See Convertible for details.
+func (b *TypeBuilder[T]) Convert(fn func(x T, p any) error) *TypeBuilder[T] {
+	b.t.convert = fn
+	return b
+}
+
+// Build returns the declared type.
+// The builder must not be used after calling Build.
+// Build panics if the type declares setters, but not Clone.
+func (b *TypeBuilder[T]) Build() *HostType[T] {
+	t := b.t
+	if len(t.setters) != 0 && t.clone == nil {
+		panic(fmt.Sprintf("type %s declares setters, but not Clone", t.name))
+	}
+	b.t = nil
+	t.variant = 0
+	if t.hash != nil {
+		t.variant |= hostHashable
+	}
+	if t.len != nil {
+		t.variant |= hostSized
+	}
+	if t.elements != nil {
+		t.variant |= hostIterable
+	}
+	if t.entries != nil {
+		t.variant |= hostKVIterable
+	}
+	return t
+}
+
+// HostType is a Toy type for values of the Go type T
+// declared with TypeBuilder.
+type HostType[T any] struct {
+	name        string
+	constructor func(r *Runtime, args ...Value) (T, error)
+	str         func(T) string
+	isFalsy     func(T) bool
+	clone       func(T) T
+	properties  map[string]func(T) Value
+	setters     map[string]func(T, Value) error
+	methods     map[string]*BuiltinFunction
+	binaryOps   map[token.Token]func(T, Value, bool) (Value, error)
+	unaryOps    map[token.Token]func(T) (Value, error)
+	compare     func(token.Token, T, Value) (bool, error)
+	hash        func(T) uint64
+	len         func(T) int
+	elements    func(T) iter.Seq[Value]
+	entries     func(T) iter.Seq2[Value, Value]
+	convert     func(T, any) error
+	variant     int
+}
+
+const (
+	hostHashable = 1 << iota
+	hostSized
+	hostIterable
+	hostKVIterable
+)
+
+func (t *HostType[T]) Type() ValueType { return nil }
+func (t *HostType[T]) String() string  { return fmt.Sprintf("<%s>", t.name) }
+func (t *HostType[T]) IsFalsy() bool   { return false }
+func (t *HostType[T]) Clone() Value    { return t }
+func (t *HostType[T]) Name() string    { return t.name }
+
+func (t *HostType[T]) Call(r *Runtime, args ...Value) (Value, error) {
+	if t.constructor != nil {
+		x, err := t.constructor(r, args...)
+		if err != nil {
+			return nil, err
+		}
+		return t.New(x), nil
+	}
+	if len(args) != 1 {
+		return nil, &WrongNumArgumentsError{
+			WantMin: 1,
+			WantMax: 1,
+			Got:     len(args),
+		}
+	}
+	if _, ok := t.Unwrap(args[0]); ok {
+		return args[0], nil
+	}
+	c, ok := args[0].(Convertible)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not convertible", TypeName(args[0]))
+	}
+	var x T
+	if err := c.Convert(&x); err != nil {
+		return nil, fmt.Errorf("failed to convert '%s' to '%s': %w",
+			TypeName(args[0]), t.name, err)
+	}
+	return t.New(x), nil
+}
+
+// New wraps x into a Toy value of this type.
+func (t *HostType[T]) New(x T) Value {
+	v := &hostValue[T]{typ: t, value: x}
+	var (
+		h = hostHash[T]{v}
+		s = hostLen[T]{v}
+		e = hostElements[T]{v}
+		k = hostEntries[T]{v}
+	)
+	switch t.variant {
+	case hostHashable:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+		}{v, h}
+	case hostSized:
+		v.self = struct {
+			*hostValue[T]
+			hostLen[T]
+		}{v, s}
+	case hostHashable | hostSized:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+			hostLen[T]
+		}{v, h, s}
+	case hostIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostElements[T]
+		}{v, e}
+	case hostHashable | hostIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+			hostElements[T]
+		}{v, h, e}
+	case hostSized | hostIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostLen[T]
+			hostElements[T]
+		}{v, s, e}
+	case hostHashable | hostSized | hostIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+			hostLen[T]
+			hostElements[T]
+		}{v, h, s, e}
+	case hostKVIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostEntries[T]
+		}{v, k}
+	case hostHashable | hostKVIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+			hostEntries[T]
+		}{v, h, k}
+	case hostSized | hostKVIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostLen[T]
+			hostEntries[T]
+		}{v, s, k}
+	case hostHashable | hostSized | hostKVIterable:
+		v.self = struct {
+			*hostValue[T]
+			hostHash[T]
+			hostLen[T]
+			hostEntries[T]
+		}{v, h, s, k}
+	default:
+		v.self = v
+	}
+	return v.self
+}
+
+// Unwrap returns the Go value wrapped into v.
+// The returned boolean is false if v is not of this type.
+func (t *HostType[T]) Unwrap(v Value) (T, bool) {
+	if h, ok := v.(interface{ host() *hostValue[T] }); ok {
+		if hv := h.host(); hv.typ == t {
+			return hv.value, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// hostValue is a value of HostType.
+// Depending on the declared behavior,
+// it is embedded into a struct implementing additional interfaces.
+type hostValue[T any] struct {
+	typ       *HostType[T]
+	value     T
+	self      Value // outermost value
+	immutable bool
+}
+
+func (v *hostValue[T]) host() *hostValue[T] { return v }
+func (v *hostValue[T]) unwrap() any         { return v.value }
+
+func (v *hostValue[T]) Type() ValueType { return v.typ }
+
+func (v *hostValue[T]) String() string {
+	if v.typ.str == nil {
+		return fmt.Sprintf("<%s>", v.typ.name)
+	}
+	return v.typ.str(v.value)
+}
+
+func (v *hostValue[T]) IsFalsy() bool {
+	return v.typ.isFalsy != nil && v.typ.isFalsy(v.value)
+}
+
+func (v *hostValue[T]) Clone() Value {
+	if v.typ.clone == nil {
+		return v.self
+	}
+	return v.typ.New(v.typ.clone(v.value))
+}
+
+func (v *hostValue[T]) Freeze() Value {
+	v.immutable = true
+	return v.self
+}
+
+// Immutable reports whether the value is frozen.
+// Values of types without setters are always immutable.
+func (v *hostValue[T]) Immutable() bool {
+	return v.immutable || len(v.typ.setters) == 0
+}
+
+func (v *hostValue[T]) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	if get, ok := v.typ.properties[string(keyStr)]; ok {
+		return get(v.value), true, nil
+	}
+	if method, ok := v.typ.methods[string(keyStr)]; ok {
+		if v.immutable && v.typ.clone != nil {
+			// methods can modify the value
+			return method.WithReceiver(v.Clone()), true, nil
+		}
+		return method.WithReceiver(v.self), true, nil
+	}
+	return Nil, false, nil
+}
+
+func (v *hostValue[T]) SetProperty(key, value Value) error {
+	keyStr, ok := key.(String)
+	if !ok {
+		return &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	set, ok := v.typ.setters[string(keyStr)]
+	if !ok {
+		return fmt.Errorf("property '%s' is not assignable", string(keyStr))
+	}
+	if v.immutable {
+		return fmt.Errorf("cannot assign to immutable %s", v.typ.name)
+	}
+	return set(v.value, value)
+}
+
+func (v *hostValue[T]) BinaryOp(op token.Token, other Value, right bool) (Value, error) {
+	fn, ok := v.typ.binaryOps[op]
+	if !ok {
+		return nil, ErrInvalidOperation
+	}
+	return fn(v.value, other, right)
+}
+
+func (v *hostValue[T]) UnaryOp(op token.Token) (Value, error) {
+	fn, ok := v.typ.unaryOps[op]
+	if !ok {
+		return nil, ErrInvalidOperation
+	}
+	return fn(v.value)
+}
+
+func (v *hostValue[T]) Compare(op token.Token, rhs Value) (bool, error) {
+	if v.typ.compare == nil {
+		return false, ErrInvalidOperation
+	}
+	return v.typ.compare(op, v.value, rhs)
+}
+
+func (v *hostValue[T]) Convert(p any) error {
+	if v.typ.convert == nil {
+		return ErrNotConvertible
+	}
+	return v.typ.convert(v.value, p)
+}
+
+type hostHash[T any] struct{ hv *hostValue[T] }
+
+func (h hostHash[T]) Hash() uint64 { return h.hv.typ.hash(h.hv.value) }
+
+type hostLen[T any] struct{ hv *hostValue[T] }
+
+func (s hostLen[T]) Len() int { return s.hv.typ.len(s.hv.value) }
+
+type hostElements[T any] struct{ hv *hostValue[T] }
+
+func (e hostElements[T]) Elements() iter.Seq[Value] { return e.hv.typ.elements(e.hv.value) }
+
+type hostEntries[T any] struct{ hv *hostValue[T] }
+
+func (k hostEntries[T]) Entries() iter.Seq2[Value, Value] { return k.hv.typ.entries(k.hv.value) }
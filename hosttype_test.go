@@ -0,0 +1,314 @@
+package toy_test
+
+import (
+	"fmt"
+	"iter"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/token"
+	"github.com/stretchr/testify/require"
+)
+
+type counter struct {
+	n int
+}
+
+var counterType *toy.HostType[*counter]
+
+func init() {
+	counterType = toy.NewTypeBuilder[*counter]("counter").
+		Constructor(func(_ *toy.Runtime, args ...toy.Value) (*counter, error) {
+			var n int
+			if err := toy.UnpackArgs(args, "n?", &n); err != nil {
+				return nil, err
+			}
+			return &counter{n: n}, nil
+		}).
+		String(func(c *counter) string { return fmt.Sprintf("counter(%d)", c.n) }).
+		IsFalsy(func(c *counter) bool { return c.n == 0 }).
+		Clone(func(c *counter) *counter { return &counter{n: c.n} }).
+		Property("n", func(c *counter) toy.Value { return toy.Int(c.n) }).
+		Property("double", func(c *counter) toy.Value { return toy.Int(2 * c.n) }).
+		Setter("n", func(c *counter, value toy.Value) error {
+			return toy.Unpack(&c.n, value)
+		}).
+		Method("inc", func(_ *toy.Runtime, c *counter, args ...toy.Value) (toy.Value, error) {
+			if len(args) != 0 {
+				return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+			}
+			c.n++
+			return toy.Int(c.n), nil
+		}).
+		BinaryOp(token.Add, func(c *counter, other toy.Value, right bool) (toy.Value, error) {
+			n, ok := other.(toy.Int)
+			if !ok {
+				return nil, toy.ErrInvalidOperation
+			}
+			return counterType.New(&counter{n: c.n + int(n)}), nil
+		}).
+		UnaryOp(token.Sub, func(c *counter) (toy.Value, error) {
+			return counterType.New(&counter{n: -c.n}), nil
+		}).
+		Compare(func(op token.Token, c *counter, rhs toy.Value) (bool, error) {
+			other, ok := counterType.Unwrap(rhs)
+			if !ok {
+				return false, toy.ErrInvalidOperation
+			}
+			switch op {
+			case token.Equal:
+				return c.n == other.n, nil
+			case token.Less:
+				return c.n < other.n, nil
+			}
+			return false, toy.ErrInvalidOperation
+		}).
+		Convert(func(c *counter, p any) error {
+			switch p := p.(type) {
+			case *toy.Int:
+				*p = toy.Int(c.n)
+			case *toy.String:
+				*p = toy.String(fmt.Sprint(c.n))
+			default:
+				return toy.ErrNotConvertible
+			}
+			return nil
+		}).
+		Build()
+}
+
+func TestTypeBuilder(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{
+			name: "constructor",
+			src:  `return counter(5)`,
+			want: counterType.New(&counter{n: 5}),
+		},
+		{
+			name: "constructor error",
+			src:  `counter("x")`,
+			err:  "invalid type for argument 'n'",
+		},
+		{
+			name: "type",
+			src:  `return [typename(counter()), type(counter()) == counter]`,
+			want: toy.NewArray([]toy.Value{toy.String("counter"), toy.True}),
+		},
+		{
+			name: "falsy",
+			src:  `return [!counter(), !counter(1)]`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.False}),
+		},
+		{
+			name: "property",
+			src:  `c := counter(2); return [c.n, c.double, c.missing]`,
+			want: toy.NewArray([]toy.Value{toy.Int(2), toy.Int(4), toy.Nil}),
+		},
+		{
+			name: "setter",
+			src:  `c := counter(); c.n = 3; return c.double`,
+			want: toy.Int(6),
+		},
+		{
+			name: "setter error",
+			src:  `c := counter(); c.n = "x"`,
+			err:  "want 'int', got 'string'",
+		},
+		{
+			name: "not assignable",
+			src:  `c := counter(); c.double = 1`,
+			err:  "property 'double' is not assignable",
+		},
+		{
+			name: "method",
+			src:  `c := counter(); c.inc(); return c.inc()`,
+			want: toy.Int(2),
+		},
+		{
+			name: "method error",
+			src:  `counter().inc(1)`,
+			err:  "want 0 argument(s), got 1",
+		},
+		{
+			name: "binary operator",
+			src:  `return counter(1) + 2`,
+			want: counterType.New(&counter{n: 3}),
+		},
+		{
+			name: "invalid binary operator",
+			src:  `counter(1) * 2`,
+			err:  "invalid operation",
+		},
+		{
+			name: "unary operator",
+			src:  `return -counter(1)`,
+			want: counterType.New(&counter{n: -1}),
+		},
+		{
+			name: "compare",
+			src:  `return [counter(1) == counter(1), counter(1) < counter(2), counter(2) < counter(1)]`,
+			want: toy.NewArray([]toy.Value{toy.True, toy.True, toy.False}),
+		},
+		{
+			name: "convert",
+			src:  `return [int(counter(4)), string(counter(4))]`,
+			want: toy.NewArray([]toy.Value{toy.Int(4), toy.String("4")}),
+		},
+		{
+			name: "clone",
+			src:  `c := counter(1); d := clone(c); d.inc(); return [c.n, d.n]`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.Int(2)}),
+		},
+		{
+			name: "frozen setter",
+			src:  `c := freeze(counter(1)); c.n = 2`,
+			err:  "cannot assign to immutable counter",
+		},
+		{
+			name: "frozen method",
+			src:  `c := freeze(counter(1)); return [c.inc(), c.n]`,
+			want: toy.NewArray([]toy.Value{toy.Int(2), toy.Int(1)}),
+		},
+		{
+			name: "spawn",
+			src:  `c := counter(1); spawn(fn() { c.n = 2 }).wait()`,
+			err:  "cannot assign to immutable counter",
+		},
+	}, func(s *toy.Script) {
+		s.Add("counter", counterType)
+	})
+}
+
+func TestHostTypeConversion(t *testing.T) {
+	typ := toy.NewTypeBuilder[toy.Float]("num").Build()
+	runScriptTests(t, []scriptTest{
+		{
+			name: "same type",
+			src:  `return num(x)`,
+			want: typ.New(1.5),
+		},
+		{
+			name: "not convertible",
+			src:  `num("x")`,
+			err:  "failed to convert 'string' to 'num'",
+		},
+		{
+			name: "from convertible",
+			src:  `return num(3)`,
+			want: typ.New(3),
+		},
+		{
+			name: "wrong number of arguments",
+			src:  `num()`,
+			err:  "want 1 argument(s), got 0",
+		},
+	}, func(s *toy.Script) {
+		s.Add("num", typ)
+		s.Add("x", typ.New(1.5))
+	})
+
+	v := typ.New(5)
+	require.Equal(t, "<num>", v.String())
+	require.False(t, v.IsFalsy())
+	require.True(t, toy.Immutable(v))
+	require.Same(t, v.(toy.Freezable), v.Clone().(toy.Freezable))
+	x, ok := typ.Unwrap(v)
+	require.True(t, ok)
+	require.Equal(t, toy.Float(5), x)
+	_, ok = typ.Unwrap(toy.Int(5))
+	require.False(t, ok)
+	_, ok = typ.Unwrap(toy.NewTypeBuilder[toy.Float]("other").Build().New(5))
+	require.False(t, ok)
+}
+
+func TestTypeBuilderSetterWithoutClone(t *testing.T) {
+	require.PanicsWithValue(t, "type broken declares setters, but not Clone", func() {
+		toy.NewTypeBuilder[*counter]("broken").
+			Setter("n", func(*counter, toy.Value) error { return nil }).
+			Build()
+	})
+}
+
+func TestHostTypeVariants(t *testing.T) {
+	elems := func(x []int) iter.Seq[toy.Value] {
+		return func(yield func(toy.Value) bool) {
+			for _, e := range x {
+				if !yield(toy.Int(e)) {
+					return
+				}
+			}
+		}
+	}
+	entries := func(x []int) iter.Seq2[toy.Value, toy.Value] {
+		return func(yield func(toy.Value, toy.Value) bool) {
+			for i, e := range x {
+				if !yield(toy.Int(i), toy.Int(e)) {
+					return
+				}
+			}
+		}
+	}
+	const (
+		iterNone = iota
+		iterElements
+		iterEntries
+	)
+	for _, hashable := range []bool{false, true} {
+		for _, sized := range []bool{false, true} {
+			for _, iteration := range []int{iterNone, iterElements, iterEntries} {
+				name := fmt.Sprintf("hashable=%t,sized=%t,iteration=%d", hashable, sized, iteration)
+				t.Run(name, func(t *testing.T) {
+					b := toy.NewTypeBuilder[[]int]("ints")
+					if hashable {
+						b.Hash(func(x []int) uint64 { return uint64(len(x)) })
+					}
+					if sized {
+						b.Len(func(x []int) int { return len(x) })
+					}
+					switch iteration {
+					case iterElements:
+						b.Elements(elems)
+					case iterEntries:
+						b.Entries(entries)
+					}
+					typ := b.Build()
+					v := typ.New([]int{1, 2})
+
+					x, ok := typ.Unwrap(v)
+					require.True(t, ok)
+					require.Equal(t, []int{1, 2}, x)
+					require.Equal(t, toy.ValueType(typ), v.Type())
+
+					h, ok := v.(toy.Hashable)
+					require.Equal(t, hashable, ok)
+					if ok {
+						require.Equal(t, uint64(2), h.Hash())
+					}
+					s, ok := v.(toy.Sized)
+					require.Equal(t, sized, ok)
+					if ok {
+						require.Equal(t, 2, s.Len())
+					}
+					e, ok := v.(toy.Iterable)
+					require.Equal(t, iteration == iterElements, ok)
+					if ok {
+						var got []toy.Value
+						for x := range e.Elements() {
+							got = append(got, x)
+						}
+						require.Equal(t, []toy.Value{toy.Int(1), toy.Int(2)}, got)
+					}
+					k, ok := v.(toy.KVIterable)
+					require.Equal(t, iteration == iterEntries, ok)
+					if ok {
+						got := make(map[toy.Value]toy.Value)
+						for key, value := range k.Entries() {
+							got[key] = value
+						}
+						require.Equal(t, map[toy.Value]toy.Value{toy.Int(0): toy.Int(1), toy.Int(1): toy.Int(2)}, got)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package toy
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Limits represents resource limits of a runtime.
+// A zero value of a field means that the corresponding resource is not limited.
+//
+// The instructions, allocations and tasks are counted
+// together for the runtime and all the tasks spawned from it,
+// so spawning tasks doesn't increase the budget of the script.
+type Limits struct {
+	// MaxInstructions is the maximum number of instructions
+	// the runtime is allowed to execute.
+	MaxInstructions int64
+	// MaxAllocs is the maximum total number of bytes the runtime is allowed
+	// to allocate for strings, bytes, arrays, tuples and tables.
+	MaxAllocs int64
+	// MaxCollectionLen is the maximum length of a single string (in bytes),
+	// bytes, array, tuple or table.
+	MaxCollectionLen int
+	// MaxCallDepth is the maximum depth of nested function calls.
+	MaxCallDepth int
+	// MaxTasks is the maximum number of tasks running concurrently.
+	MaxTasks int
+}
+
+// usage represents the usage of the limited resources,
+// which is shared by a runtime and the tasks spawned from it.
+type usage struct {
+	insts  atomic.Int64 // number of executed instructions
+	allocs atomic.Int64 // number of allocated bytes
+	tasks  atomic.Int64 // number of running tasks
+}
+
+const (
+	// valueSize is the size of Value interface.
+	valueSize = int64(unsafe.Sizeof(Value(nil)))
+	// entrySize is the size of a single table entry.
+	entrySize = int64(unsafe.Sizeof(entry{}))
+)
+
+// SetLimits sets resource limits of the runtime.
+func (r *Runtime) SetLimits(limits Limits) {
+	r.limits = limits
+}
+
+// Limits returns resource limits of the runtime.
+func (r *Runtime) Limits() Limits {
+	return r.limits
+}
+
+// Alloc accounts for n bytes allocated by the runtime.
+// It returns LimitError if the total number of allocated bytes
+// exceeds the allocation limit.
+//
+// Builtin functions that allocate memory proportionally
+// to their input should call Alloc before allocating.
+// It is safe to call Alloc on a nil Runtime.
+func (r *Runtime) Alloc(n int64) error {
+	if r == nil || r.limits.MaxAllocs <= 0 {
+		return nil
+	}
+	if r.usage.allocs.Add(n) > r.limits.MaxAllocs {
+		return &LimitError{Kind: LimitAllocs, Limit: r.limits.MaxAllocs}
+	}
+	return nil
+}
+
+// CheckLen returns LimitError if n exceeds the collection length limit.
+// It is safe to call CheckLen on a nil Runtime.
+func (r *Runtime) CheckLen(n int) error {
+	if r == nil || r.limits.MaxCollectionLen <= 0 {
+		return nil
+	}
+	if n > r.limits.MaxCollectionLen {
+		return &LimitError{Kind: LimitCollectionLen, Limit: int64(r.limits.MaxCollectionLen)}
+	}
+	return nil
+}
+
+// growArray checks the length of the array after adding n elements to it
+// and accounts for the memory allocated for these elements.
+func (r *Runtime) growArray(arr *Array, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := r.CheckLen(len(arr.elems) + n); err != nil {
+		return err
+	}
+	return r.Alloc(int64(n) * valueSize)
+}
+
+// track checks the length of the newly created value
+// and accounts for the memory allocated for it.
+func (r *Runtime) track(v Value) error {
+	n, size := collectionSize(v)
+	if n < 0 {
+		return nil
+	}
+	if err := r.CheckLen(n); err != nil {
+		return err
+	}
+	return r.Alloc(size)
+}
+
+// collectionSize returns the length of the given value
+// and the approximate number of bytes occupied by its elements.
+// Returns -1 if the value is not a collection.
+func collectionSize(v Value) (n int, size int64) {
+	switch x := v.(type) {
+	case String:
+		return len(x), int64(len(x))
+	case Bytes:
+		return len(x), int64(len(x))
+	case *Array:
+		return len(x.elems), int64(len(x.elems)) * valueSize
+	case Tuple:
+		return len(x), int64(len(x)) * valueSize
+	case *Table:
+		return x.Len(), int64(x.Len()) * entrySize
+	}
+	return -1, 0
+}
@@ -0,0 +1,150 @@
+package toy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits toy.Limits
+		src    string
+		want   toy.Value
+		err    string
+	}{
+		{
+			name:   "instructions",
+			limits: toy.Limits{MaxInstructions: 1000},
+			src:    `for {}`,
+			err:    "instruction limit exceeded (1000)",
+		},
+		{
+			name:   "instructions within limit",
+			limits: toy.Limits{MaxInstructions: 1000},
+			src:    `s := 0; for i in range(0, 10) { s += i }; return s`,
+			want:   toy.Int(45),
+		},
+		{
+			name:   "allocs",
+			limits: toy.Limits{MaxAllocs: 1024},
+			src:    `s := []; for { s = append(s, 1) }`,
+			err:    "allocation limit exceeded (1024)",
+		},
+		{
+			name:   "allocs of strings",
+			limits: toy.Limits{MaxAllocs: 1024},
+			src:    `s := "x"; for { s += s }`,
+			err:    "allocation limit exceeded (1024)",
+		},
+		{
+			name:   "allocs within limit",
+			limits: toy.Limits{MaxAllocs: 1024},
+			src:    `return len(append([], 1, 2, 3))`,
+			want:   toy.Int(3),
+		},
+		{
+			name:   "collection length of array",
+			limits: toy.Limits{MaxCollectionLen: 3},
+			src:    `return append([1, 2, 3], 4)`,
+			err:    "collection length limit exceeded (3)",
+		},
+		{
+			name:   "collection length of string",
+			limits: toy.Limits{MaxCollectionLen: 3},
+			src:    `return "ab" + "cd"`,
+			err:    "collection length limit exceeded (3)",
+		},
+		{
+			name:   "collection length of table",
+			limits: toy.Limits{MaxCollectionLen: 3},
+			src:    `t := {}; for i in range(0, 10) { t[i] = i }`,
+			err:    "collection length limit exceeded (3)",
+		},
+		{
+			name:   "collection length within limit",
+			limits: toy.Limits{MaxCollectionLen: 3},
+			src:    `return "ab" + "c"`,
+			want:   toy.String("abc"),
+		},
+		{
+			name:   "call depth",
+			limits: toy.Limits{MaxCallDepth: 10},
+			src:    `f := fn(n) { return 1 + f(n + 1) }; f(0)`,
+			err:    "call depth limit exceeded (10)",
+		},
+		{
+			name:   "call depth within limit",
+			limits: toy.Limits{MaxCallDepth: 10},
+			src:    `f := fn(n) { if n == 0 { return 0 }; return n + f(n - 1) }; return f(5)`,
+			want:   toy.Int(15),
+		},
+		{
+			name:   "instructions shared with tasks",
+			limits: toy.Limits{MaxInstructions: 2000},
+			src: `
+tasks := []
+for i in range(0, 5) {
+	tasks = append(tasks, spawn(fn() { for j in range(0, 100) {} }))
+}
+for t in tasks { t.wait() }`,
+			err: "instruction limit exceeded (2000)",
+		},
+		{
+			name:   "tasks",
+			limits: toy.Limits{MaxTasks: 2},
+			src:    `ch := chan(); for i in range(0, 3) { spawn(fn() { ch.recv() }) }`,
+			err:    "task limit exceeded (2)",
+		},
+		{
+			name:   "tasks within limit",
+			limits: toy.Limits{MaxTasks: 1},
+			src:    `s := 0; for i in range(0, 3) { s += spawn(fn(n) { return n }, i).wait() }; return s`,
+			want:   toy.Int(3),
+		},
+		{
+			name:   "try expression",
+			limits: toy.Limits{MaxCallDepth: 10},
+			src:    `f := fn(n) { return 1 + f(n + 1) }; _, err := try f(0); return err`,
+			err:    "call depth limit exceeded (10)",
+		},
+		{
+			name:   "try statement",
+			limits: toy.Limits{MaxInstructions: 1000},
+			src:    `try { for {} } catch { return "caught" }`,
+			err:    "instruction limit exceeded (1000)",
+		},
+		{
+			name:   "try statement in function",
+			limits: toy.Limits{MaxAllocs: 1024},
+			src:    `f := fn() { s := []; for { s = append(s, 1) } }; try { f() } catch err { return err }`,
+			err:    "allocation limit exceeded (1024)",
+		},
+	}
+	for _, tt := range tests {
+		runScriptTests(t, []scriptTest{{
+			name: tt.name,
+			src:  tt.src,
+			want: tt.want,
+			err:  tt.err,
+		}}, func(s *toy.Script) {
+			s.SetLimits(tt.limits)
+		})
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	script := toy.NewScript([]byte(`for {}`))
+	script.SetLimits(toy.Limits{MaxInstructions: 100})
+	_, err := script.RunContext(context.Background())
+	require.ErrorIs(t, err, toy.ErrLimitExceeded)
+
+	var limitErr *toy.LimitError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, toy.LimitInstructions, limitErr.Kind)
+	require.Equal(t, int64(100), limitErr.Limit)
+}
@@ -0,0 +1,154 @@
+package toy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LockFileName is the name of the lock file.
+const LockFileName = "toy.lock"
+
+// Lockfile represents a toy.lock file, which records
+// SHA-256 hashes of the contents of imported files.
+//
+// Each line of the lock file contains the path of a file,
+// relative to the directory of the lock file, and its hash:
+//
+//	lib/util.toy sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+//
+// If imported files are read from a virtual filesystem (see Script.SetFS),
+// Dir is a slash-separated path resolved against the root of the filesystem,
+// e.g. "/" or "." for the root itself.
+type Lockfile struct {
+	Dir    string            // directory containing the lock file
+	Hashes map[string]string // hex-encoded hashes by slash-separated paths
+}
+
+// NewLockfile creates an empty lock file located in dir.
+func NewLockfile(dir string) *Lockfile {
+	return &Lockfile{
+		Dir:    dir,
+		Hashes: make(map[string]string),
+	}
+}
+
+// ParseLockfile parses the contents of the lock file located in dir.
+func ParseLockfile(dir string, data []byte) (*Lockfile, error) {
+	l := NewLockfile(dir)
+	var line int
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		i := strings.LastIndexByte(text, ' ')
+		if i == -1 {
+			return nil, fmt.Errorf("%s:%d: missing hash", LockFileName, line)
+		}
+		name, hash := strings.TrimSpace(text[:i]), text[i+1:]
+		hash, ok := strings.CutPrefix(hash, "sha256:")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unsupported hash '%s'", LockFileName, line, text[i+1:])
+		}
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: malformed hash '%s'", LockFileName, line, hash)
+		}
+		if _, ok := l.Hashes[name]; ok {
+			return nil, fmt.Errorf("%s:%d: repeated file '%s'", LockFileName, line, name)
+		}
+		l.Hashes[name] = hash
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ReadLockfile reads the lock file from the host filesystem.
+func ReadLockfile(name string) (*Lockfile, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	return ParseLockfile(dir, data)
+}
+
+// Add records the hash of the contents of the file.
+func (l *Lockfile) Add(name string, src []byte) error {
+	rel, err := l.rel(hostFS, name)
+	if err != nil {
+		return err
+	}
+	l.Hashes[rel] = hashSource(src)
+	return nil
+}
+
+// Verify checks that the contents of the file match the recorded hash.
+func (l *Lockfile) Verify(name string, src []byte) error {
+	return l.verify(hostFS, name, src)
+}
+
+// verify checks that the contents of the file
+// located in the given filesystem match the recorded hash.
+func (l *Lockfile) verify(fsys *FS, name string, src []byte) error {
+	rel, err := l.rel(fsys, name)
+	if err != nil {
+		return err
+	}
+	hash, ok := l.Hashes[rel]
+	if !ok {
+		return fmt.Errorf("'%s' is missing from %s", rel, LockFileName)
+	}
+	if got := hashSource(src); got != hash {
+		return fmt.Errorf("checksum mismatch for '%s': want sha256:%s, got sha256:%s", rel, hash, got)
+	}
+	return nil
+}
+
+// rel returns the slash-separated path of the file
+// relative to the directory of the lock file.
+func (l *Lockfile) rel(fsys *FS, name string) (string, error) {
+	if !fsys.IsHost() {
+		dir, name := fsPath(l.Dir), fsPath(name)
+		if dir == "." {
+			return name, nil
+		}
+		if rel, ok := strings.CutPrefix(name, dir+"/"); ok {
+			return rel, nil
+		}
+		return "", fmt.Errorf("'%s' is outside of the lock file directory '%s'", rootedPath(name), rootedPath(dir))
+	}
+	rel, err := filepath.Rel(l.Dir, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Format returns the contents of the lock file.
+func (l *Lockfile) Format() []byte {
+	var b bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(l.Hashes)) {
+		fmt.Fprintf(&b, "%s sha256:%s\n", name, l.Hashes[name])
+	}
+	return b.Bytes()
+}
+
+func hashSource(src []byte) string {
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package toy_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseLockfile(t *testing.T) {
+	hash := sha256Hex("export 1")
+
+	l, err := toy.ParseLockfile("/app", []byte("\nlib/b.toy sha256:"+hash+"\na.toy sha256:"+hash+"\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a.toy": hash, "lib/b.toy": hash}, l.Hashes)
+	require.Equal(t, "a.toy sha256:"+hash+"\nlib/b.toy sha256:"+hash+"\n", string(l.Format()))
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"a.toy", "toy.lock:1: missing hash"},
+		{"a.toy md5:" + hash, "toy.lock:1: unsupported hash 'md5:" + hash + "'"},
+		{"a.toy sha256:abc", "toy.lock:1: malformed hash 'abc'"},
+		{"a.toy sha256:" + hash + "\na.toy sha256:" + hash, "toy.lock:2: repeated file 'a.toy'"},
+	}
+	for _, tt := range tests {
+		_, err := toy.ParseLockfile("/app", []byte(tt.data))
+		require.EqualError(t, err, tt.err, tt.data)
+	}
+}
+
+func TestLockfileVerify(t *testing.T) {
+	dir := t.TempDir()
+	l := toy.NewLockfile(dir)
+	require.NoError(t, l.Add(filepath.Join(dir, "lib", "a.toy"), []byte("a")))
+	require.Equal(t, map[string]string{"lib/a.toy": sha256Hex("a")}, l.Hashes)
+
+	require.NoError(t, l.Verify(filepath.Join(dir, "lib", "a.toy"), []byte("a")))
+	require.ErrorContains(t, l.Verify(filepath.Join(dir, "lib", "a.toy"), []byte("b")), "checksum mismatch for 'lib/a.toy'")
+	require.EqualError(t, l.Verify(filepath.Join(dir, "b.toy"), []byte("b")), "'b.toy' is missing from toy.lock")
+}
+
+func TestLockfileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/u.toy":     {Data: []byte(`return 42`)},
+		"app/lib/v.toy": {Data: []byte(`return 43`)},
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		hashes map[string]string
+		src    string
+		err    string
+	}{
+		{
+			name:   "root",
+			dir:    "/",
+			hashes: map[string]string{"lib/u.toy": sha256Hex(`return 42`)},
+			src:    `return import("/lib/u")`,
+		},
+		{
+			name:   "dot",
+			dir:    ".",
+			hashes: map[string]string{"lib/u.toy": sha256Hex(`return 42`)},
+			src:    `return import("./lib/u")`,
+		},
+		{
+			name:   "subdirectory",
+			dir:    "/app",
+			hashes: map[string]string{"lib/v.toy": sha256Hex(`return 43`)},
+			src:    `return import("/app/lib/v")`,
+		},
+		{
+			name:   "mismatch",
+			dir:    "/",
+			hashes: map[string]string{"lib/u.toy": sha256Hex(`return 0`)},
+			src:    `return import("/lib/u")`,
+			err:    "checksum mismatch for 'lib/u.toy'",
+		},
+		{
+			name:   "missing",
+			dir:    "/",
+			hashes: map[string]string{},
+			src:    `return import("/lib/u")`,
+			err:    "'lib/u.toy' is missing from toy.lock",
+		},
+		{
+			name:   "outside",
+			dir:    "/app",
+			hashes: map[string]string{},
+			src:    `return import("/lib/u")`,
+			err:    "'/lib/u.toy' is outside of the lock file directory '/app'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := toy.NewScript([]byte(tt.src))
+			script.SetFS(fsys)
+			script.EnableFileImport(true)
+			script.SetLockfile(&toy.Lockfile{Dir: tt.dir, Hashes: tt.hashes})
+			compiled, err := script.Run()
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEqual(t, toy.Nil, compiled.Result())
+		})
+	}
+}
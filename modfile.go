@@ -0,0 +1,249 @@
+package toy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ManifestFileName is the name of the manifest file.
+const ManifestFileName = "toy.mod"
+
+// VendorDirName is the name of the directory containing vendored requirements.
+const VendorDirName = "vendor"
+
+// Manifest represents a toy.mod file, which maps module prefixes
+// to local directories or versions.
+//
+// The manifest consists of directives, one per line:
+//
+//	// comment
+//	module company/app
+//	require shared/log => ../../libs/log
+//	require shared/http v1.2.0
+//	require (
+//		shared/json => ../../libs/json
+//	)
+//
+// The module directive declares the prefix of the project itself,
+// so the project files can be imported as "company/app/path/to/file".
+// A require directive maps a prefix either to a local directory,
+// relative to the directory of the manifest, or to a version,
+// which is looked up as "prefix@version" in the import path.
+//
+// If the directory of the manifest contains "vendor/modules.txt",
+// the requirements are loaded from "vendor/<prefix>" instead.
+type Manifest struct {
+	Dir      string         // directory containing the manifest
+	Module   string         // prefix of the project; or empty
+	Requires []*Requirement // requirements
+}
+
+// Requirement represents a require directive of the manifest.
+type Requirement struct {
+	Prefix  string // prefix of the imported modules
+	Dir     string // local directory; or empty
+	Version string // version; or empty
+}
+
+// Location returns the location of the requirement
+// that is added to the manifest file.
+func (r *Requirement) Location() string {
+	if r.Dir != "" {
+		return "=> " + r.Dir
+	}
+	return r.Version
+}
+
+// ParseManifest parses the contents of the manifest located in dir.
+func ParseManifest(dir string, data []byte) (*Manifest, error) {
+	m := &Manifest{Dir: dir}
+	var (
+		block bool
+		line  int
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line++
+		text := sc.Text()
+		if i := strings.Index(text, "//"); i != -1 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if block {
+			if len(fields) == 1 && fields[0] == ")" {
+				block = false
+				continue
+			}
+			if err := m.addRequire(fields); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", ManifestFileName, line, err)
+			}
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: usage: module prefix", ManifestFileName, line)
+			}
+			if m.Module != "" {
+				return nil, fmt.Errorf("%s:%d: repeated module directive", ManifestFileName, line)
+			}
+			if err := checkModulePrefix(fields[1]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", ManifestFileName, line, err)
+			}
+			m.Module = fields[1]
+		case "require":
+			if len(fields) == 2 && fields[1] == "(" {
+				block = true
+				continue
+			}
+			if err := m.addRequire(fields[1:]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", ManifestFileName, line, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown directive '%s'", ManifestFileName, line, fields[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if block {
+		return nil, fmt.Errorf("%s:%d: unterminated require block", ManifestFileName, line)
+	}
+	return m, nil
+}
+
+func (m *Manifest) addRequire(fields []string) error {
+	var r *Requirement
+	switch {
+	case len(fields) == 3 && fields[1] == "=>":
+		r = &Requirement{Prefix: fields[0], Dir: fields[2]}
+	case len(fields) == 2 && fields[1] != "=>":
+		r = &Requirement{Prefix: fields[0], Version: fields[1]}
+	default:
+		return errors.New("usage: require prefix => dir | require prefix version")
+	}
+	if err := checkModulePrefix(r.Prefix); err != nil {
+		return err
+	}
+	if m.Require(r.Prefix) != nil {
+		return fmt.Errorf("repeated requirement '%s'", r.Prefix)
+	}
+	m.Requires = append(m.Requires, r)
+	return nil
+}
+
+func checkModulePrefix(prefix string) error {
+	if strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") ||
+		slices.ContainsFunc(strings.Split(prefix, "/"), func(elem string) bool {
+			return elem == "" || elem == "." || elem == ".."
+		}) {
+		return fmt.Errorf("malformed module prefix '%s'", prefix)
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest file from the host filesystem.
+func ReadManifest(name string) (*Manifest, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(dir, data)
+}
+
+// FindManifest looks for the manifest file in dir and its parents
+// on the host filesystem. Returns nil if there's none.
+func FindManifest(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		m, err := ReadManifest(filepath.Join(dir, ManifestFileName))
+		if err == nil {
+			return m, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Require returns the requirement with the given prefix, or nil.
+func (m *Manifest) Require(prefix string) *Requirement {
+	i := slices.IndexFunc(m.Requires, func(r *Requirement) bool {
+		return r.Prefix == prefix
+	})
+	if i == -1 {
+		return nil
+	}
+	return m.Requires[i]
+}
+
+// Lookup returns the requirement with the longest prefix
+// matching the module name, and the rest of the name.
+// If the name matches the module directive, the returned requirement
+// refers to the directory of the manifest.
+func (m *Manifest) Lookup(name string) (r *Requirement, rest string, ok bool) {
+	for _, req := range m.Requires {
+		if (r == nil || len(req.Prefix) > len(r.Prefix)) && hasModulePrefix(name, req.Prefix) {
+			r = req
+		}
+	}
+	if m.Module != "" && (r == nil || len(m.Module) > len(r.Prefix)) && hasModulePrefix(name, m.Module) {
+		r = &Requirement{Prefix: m.Module, Dir: "."}
+	}
+	if r == nil {
+		return nil, "", false
+	}
+	return r, strings.TrimPrefix(strings.TrimPrefix(name, r.Prefix), "/"), true
+}
+
+func hasModulePrefix(name, prefix string) bool {
+	return name == prefix || strings.HasPrefix(name, prefix+"/")
+}
+
+// Format returns the contents of the manifest file.
+func (m *Manifest) Format() []byte {
+	var b bytes.Buffer
+	if m.Module != "" {
+		fmt.Fprintf(&b, "module %s\n", m.Module)
+	}
+	switch len(m.Requires) {
+	case 0:
+	case 1:
+		if b.Len() != 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "require %s %s\n", m.Requires[0].Prefix, m.Requires[0].Location())
+	default:
+		if b.Len() != 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("require (\n")
+		for _, r := range m.Requires {
+			fmt.Fprintf(&b, "\t%s %s\n", r.Prefix, r.Location())
+		}
+		b.WriteString(")\n")
+	}
+	return b.Bytes()
+}
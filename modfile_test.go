@@ -0,0 +1,186 @@
+package toy_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *toy.Manifest
+		err  string
+	}{
+		{
+			name: "empty",
+			data: "// nothing here\n",
+			want: &toy.Manifest{Dir: "/app"},
+		},
+		{
+			name: "directives",
+			data: `module company/app // the project
+require shared/log => ../libs/log
+require shared/http v1.2.0
+require (
+	shared/json => ../libs/json
+)
+`,
+			want: &toy.Manifest{
+				Dir:    "/app",
+				Module: "company/app",
+				Requires: []*toy.Requirement{
+					{Prefix: "shared/log", Dir: "../libs/log"},
+					{Prefix: "shared/http", Version: "v1.2.0"},
+					{Prefix: "shared/json", Dir: "../libs/json"},
+				},
+			},
+		},
+		{
+			name: "repeated module",
+			data: "module a\nmodule b\n",
+			err:  "toy.mod:2: repeated module directive",
+		},
+		{
+			name: "repeated requirement",
+			data: "require a v1\nrequire a => ../a\n",
+			err:  "toy.mod:2: repeated requirement 'a'",
+		},
+		{
+			name: "malformed prefix",
+			data: "require a/../b v1\n",
+			err:  "toy.mod:1: malformed module prefix 'a/../b'",
+		},
+		{
+			name: "malformed require",
+			data: "require a => \n",
+			err:  "toy.mod:1: usage: require prefix => dir | require prefix version",
+		},
+		{
+			name: "unknown directive",
+			data: "replace a => b\n",
+			err:  "toy.mod:1: unknown directive 'replace'",
+		},
+		{
+			name: "unterminated block",
+			data: "require (\n\ta v1\n",
+			err:  "toy.mod:2: unterminated require block",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := toy.ParseManifest("/app", []byte(tt.data))
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestManifestLookup(t *testing.T) {
+	m, err := toy.ParseManifest("/app", []byte(`module company/app
+require company/app/vendored => ../vendored
+require shared v1.0.0
+require shared/log => ../log
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		prefix string
+		rest   string
+		ok     bool
+	}{
+		{name: "company/app", prefix: "company/app", rest: ""},
+		{name: "company/app/lib/util", prefix: "company/app", rest: "lib/util"},
+		{name: "company/app/vendored/x", prefix: "company/app/vendored", rest: "x"},
+		{name: "shared/http", prefix: "shared", rest: "http"},
+		{name: "shared/log/json", prefix: "shared/log", rest: "json"},
+		{name: "sharedlog"},
+		{name: "company"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, rest, ok := m.Lookup(tt.name)
+			if tt.prefix == "" {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tt.prefix, r.Prefix)
+			require.Equal(t, tt.rest, rest)
+		})
+	}
+}
+
+func TestManifestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "module", data: "module company/app\n"},
+		{name: "single require", data: "module company/app\n\nrequire shared/log => ../log\n"},
+		{name: "require block", data: "require (\n\tshared/log => ../log\n\tshared/http v1.2.0\n)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := toy.ParseManifest("/app", []byte(tt.data))
+			require.NoError(t, err)
+			require.Equal(t, tt.data, string(m.Format()))
+		})
+	}
+}
+
+func TestManifestImports(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/lib/util.toy":          {Data: []byte(`return {name: "util"}`)},
+		"libs/log/index.toy":        {Data: []byte(`return {name: "log"}`)},
+		"libs/log/json.toy":         {Data: []byte(`return {name: "log/json"}`)},
+		"deps/shared/http@v1/x.toy": {Data: []byte(`return {name: "http/x"}`)},
+	}
+	m, err := toy.ParseManifest("/app", []byte(`module company/app
+require shared/log => ../libs/log
+require shared/http v1
+`))
+	require.NoError(t, err)
+
+	runScriptTests(t, []scriptTest{
+		{
+			name: "module",
+			src:  `return import("company/app/lib/util").name`,
+			want: toy.String("util"),
+		},
+		{
+			name: "local requirement",
+			src:  `return import("shared/log").name`,
+			want: toy.String("log"),
+		},
+		{
+			name: "local requirement file",
+			src:  `return import("shared/log/json").name`,
+			want: toy.String("log/json"),
+		},
+		{
+			name: "versioned requirement",
+			src:  `return import("shared/http/x").name`,
+			want: toy.String("http/x"),
+		},
+		{
+			name: "missing",
+			src:  `return import("shared/log/missing")`,
+			err:  "module 'shared/log/missing' not found",
+		},
+	}, func(s *toy.Script) {
+		s.SetFS(fsys)
+		s.SetManifest(m)
+		s.SetImportPath("/deps")
+		s.EnableFileImport(true)
+	})
+}
@@ -108,6 +108,9 @@ func (m *BuiltinModule) Property(key Value) (value Value, found bool, err error)
 	if !ok {
 		return Nil, false, nil
 	}
+	if d, ok := field.(*deniedMember); ok {
+		return nil, false, d.err
+	}
 	return field, true, nil
 }
 
@@ -21,17 +21,23 @@ var stmtStart = map[token.Token]bool{
 	token.If:       true,
 	token.Return:   true,
 	token.Defer:    true,
+	token.Try:      true,
 	token.Throw:    true,
+	token.Export:   true,
 }
 
 // Error represents a parser error.
 type Error struct {
-	Pos token.FilePos
-	Msg string
+	Pos     token.FilePos
+	Msg     string
+	Excerpt *token.Excerpt // source excerpt, if available
 }
 
 func (e Error) Error() string {
 	if e.Pos.Filename != "" || e.Pos.IsValid() {
+		if e.Excerpt != nil {
+			return fmt.Sprintf("parse error: %s\n└─ at %s\n\n%s", e.Msg, e.Pos, e.Excerpt)
+		}
 		return fmt.Sprintf("parse error: %s\n└─ at %s", e.Msg, e.Pos)
 	}
 	return fmt.Sprintf("parse error: %s", e.Msg)
@@ -42,7 +48,7 @@ type ErrorList []*Error
 
 // Add adds a new parser error to the collection.
 func (p *ErrorList) Add(pos token.FilePos, msg string) {
-	*p = append(*p, &Error{pos, msg})
+	*p = append(*p, &Error{Pos: pos, Msg: msg})
 }
 
 // Len returns the number of elements in the collection.
@@ -119,6 +125,7 @@ func NewParser(file *token.File, src []byte, trace io.Writer) *Parser {
 	p.scanner = NewScanner(p.file, src,
 		func(pos token.FilePos, msg string) {
 			p.errors.Add(pos, msg)
+			p.errors[len(p.errors)-1].Excerpt = file.Excerpt(pos.Offset, pos.Offset+1)
 		}, 0)
 	p.next()
 	return p
@@ -738,6 +745,9 @@ func (p *Parser) parseStmt() (stmt ast.Stmt) {
 	if p.trace {
 		defer untracep(tracep(p, "Statement"))
 	}
+	if p.token == token.Try && p.peek() == token.LBrace {
+		return p.parseTryStmt()
+	}
 	switch p.token {
 	case // simple statements
 		token.Func, token.Ident, token.Int, token.Float, token.Char,
@@ -760,6 +770,8 @@ func (p *Parser) parseStmt() (stmt ast.Stmt) {
 		return p.parseDeferStmt()
 	case token.Throw:
 		return p.parseThrowStmt()
+	case token.Export:
+		return p.parseExportStmt()
 	case token.If:
 		return p.parseIfStmt()
 	case token.For:
@@ -1004,6 +1016,68 @@ func (p *Parser) parseDeferStmt() ast.Stmt {
 	return &ast.DeferStmt{DeferPos: pos, CallExpr: call}
 }
 
+func (p *Parser) parseTryStmt() ast.Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "TryStmt"))
+	}
+
+	stmt := &ast.TryStmt{TryPos: p.expect(token.Try)}
+	stmt.Body = p.parseBlockStmt()
+
+	if p.token == token.Catch {
+		stmt.CatchPos = p.pos
+		p.next()
+		if p.token == token.Ident {
+			stmt.CatchIdent = p.parseIdent()
+		}
+		stmt.Catch = p.parseBlockStmt()
+	}
+	if p.token == token.Finally {
+		stmt.FinallyPos = p.pos
+		p.next()
+		stmt.Finally = p.parseBlockStmt()
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.errorExpected(p.pos, "catch or finally")
+	}
+
+	p.expectSemi()
+	return stmt
+}
+
+func (p *Parser) parseExportStmt() ast.Stmt {
+	if p.trace {
+		defer untracep(tracep(p, "ExportStmt"))
+	}
+
+	stmt := &ast.ExportStmt{ExportPos: p.expect(token.Export)}
+	for {
+		stmt.Names = append(stmt.Names, p.parseIdent())
+		if p.token != token.Comma {
+			break
+		}
+		p.next()
+	}
+
+	if p.token == token.Define {
+		pos := p.pos
+		p.next()
+		lhs := make([]ast.Expr, len(stmt.Names))
+		for i, name := range stmt.Names {
+			lhs[i] = name
+		}
+		stmt.Assign = &ast.AssignStmt{
+			LHS:      lhs,
+			RHS:      p.parseExprList(),
+			Token:    token.Define,
+			TokenPos: pos,
+		}
+	}
+
+	p.expectSemi()
+	return stmt
+}
+
 func (p *Parser) parseThrowStmt() ast.Stmt {
 	if p.trace {
 		defer untracep(tracep(p, "ThrowStmt"))
@@ -1252,6 +1326,11 @@ func (p *Parser) error(pos token.Pos, msg string) {
 		panic(bailout{})
 	}
 	p.errors.Add(filePos, msg)
+	end := filePos.Offset + 1
+	if pos == p.pos && p.tokenLit != "" {
+		end = filePos.Offset + len(p.tokenLit)
+	}
+	p.errors[len(p.errors)-1].Excerpt = p.file.Excerpt(filePos.Offset, end)
 }
 
 func (p *Parser) errorExpected(pos token.Pos, msg string) {
@@ -1286,6 +1365,14 @@ func (p *Parser) next() {
 	p.token, p.tokenLit, p.pos = p.scanner.Scan()
 }
 
+// peek returns the token following the current one without consuming it.
+func (p *Parser) peek() token.Token {
+	s := *p.scanner
+	s.errorHandler = nil
+	tok, _, _ := s.Scan()
+	return tok
+}
+
 func (p *Parser) printTrace(a ...interface{}) {
 	const (
 		dots = ". . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . "
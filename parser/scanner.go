@@ -54,6 +54,7 @@ func NewScanner(
 		panic(fmt.Sprintf("file size (%d) does not match src len (%d)",
 			file.Size, len(src)))
 	}
+	file.SetSource(src)
 
 	s := &Scanner{
 		file:         file,
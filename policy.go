@@ -0,0 +1,333 @@
+package toy
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FSAccess represents a level of access to the filesystem.
+type FSAccess int
+
+const (
+	FSNoAccess FSAccess = iota
+	FSReadOnly
+	FSReadWrite
+)
+
+func (a FSAccess) String() string {
+	switch a {
+	case FSNoAccess:
+		return "no"
+	case FSReadOnly:
+		return "read"
+	case FSReadWrite:
+		return "write"
+	}
+	return "unknown"
+}
+
+// Policy restricts access of scripts to the capabilities of the host.
+// A nil Policy allows everything.
+type Policy struct {
+	// Allow is a list of builtin module members scripts are allowed to use.
+	// Each entry is either a module name ("os" or "os.*")
+	// or a module member ("os.readfile").
+	// If Allow is empty, all members are allowed.
+	Allow []string
+	// Deny is a list of builtin module members scripts are not allowed to use.
+	// Entries have the same format as in Allow.
+	// Deny takes precedence over Allow.
+	Deny []string
+	// FS is the level of access to the filesystem.
+	FS FSAccess
+	// Root restricts access to the filesystem to the given directory.
+	// If empty, the whole filesystem is accessible.
+	Root string
+	// Network allows network lookups.
+	Network bool
+	// Concurrency allows spawning tasks and selecting on channels.
+	Concurrency bool
+}
+
+// PurePolicy returns a policy that denies access to the filesystem,
+// network, environment and process information, and denies concurrency.
+func PurePolicy() *Policy {
+	return &Policy{
+		Deny:        []string{"os", "os/env", "os/user"},
+		FS:          FSNoAccess,
+		Network:     false,
+		Concurrency: false,
+	}
+}
+
+// ReadOnlyFSPolicy returns a policy that allows to read files
+// located in the root directory and denies network access
+// and modification of the environment.
+// If root is empty, the whole filesystem is readable.
+func ReadOnlyFSPolicy(root string) *Policy {
+	return &Policy{
+		Deny:        []string{"os/env.set", "os/env.unset", "os/env.clear"},
+		FS:          FSReadOnly,
+		Root:        root,
+		Network:     false,
+		Concurrency: true,
+	}
+}
+
+// FullPolicy returns a policy that allows everything.
+func FullPolicy() *Policy {
+	return &Policy{
+		FS:          FSReadWrite,
+		Network:     true,
+		Concurrency: true,
+	}
+}
+
+// Allowed reports whether the policy allows
+// to use the given member of the builtin module.
+func (p *Policy) Allowed(module, member string) bool {
+	if p == nil {
+		return true
+	}
+	match := func(pattern string) bool {
+		if pattern == module || pattern == module+".*" {
+			return true
+		}
+		return pattern == module+"."+member
+	}
+	if slices.ContainsFunc(p.Deny, match) {
+		return false
+	}
+	return len(p.Allow) == 0 || slices.ContainsFunc(p.Allow, match)
+}
+
+// CheckFS returns PolicyError if the policy doesn't allow
+// to access the file with the given name on the host filesystem.
+func (p *Policy) CheckFS(name string, access FSAccess) error {
+	return p.checkFS(hostFS, name, access)
+}
+
+func (p *Policy) checkFS(fsys *FS, name string, access FSAccess) error {
+	if p == nil {
+		return nil
+	}
+	if p.FS < access {
+		if p.FS == FSNoAccess {
+			return &PolicyError{Subject: "filesystem access"}
+		}
+		return &PolicyError{Subject: access.String() + " access to '" + name + "'"}
+	}
+	if p.Root == "" {
+		return nil
+	}
+	var inside bool
+	if fsys.IsHost() {
+		root, err := resolvePath(p.Root)
+		if err != nil {
+			return err
+		}
+		path, err := resolvePath(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		inside = err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	} else {
+		root, path := fsPath(p.Root), fsPath(name)
+		inside = root == "." || path == root || strings.HasPrefix(path, root+"/")
+	}
+	if !inside {
+		return &PolicyError{Subject: "access to '" + name + "' outside of '" + p.Root + "'"}
+	}
+	return nil
+}
+
+// CheckSymlink returns PolicyError if the policy doesn't allow
+// to create the symbolic link newname to oldname on the host filesystem.
+// A relative oldname is resolved against the directory of the link.
+func (p *Policy) CheckSymlink(oldname, newname string) error {
+	return p.checkSymlink(hostFS, oldname, newname)
+}
+
+func (p *Policy) checkSymlink(fsys *FS, oldname, newname string) error {
+	if p == nil {
+		return nil
+	}
+	target := oldname
+	if fsys.IsHost() {
+		if !filepath.IsAbs(target) {
+			// the target must not be cleaned, since ".." in it
+			// is applied to the resolved directory of the link
+			dir, _ := filepath.Split(newname)
+			target = dir + target
+		}
+	} else if target = filepath.ToSlash(target); !path.IsAbs(target) {
+		target = path.Join(rootedPath(path.Dir(filepath.ToSlash(newname))), target)
+	}
+	if err := p.checkFS(fsys, target, FSReadOnly); err != nil {
+		return err
+	}
+	return p.checkFS(fsys, newname, FSReadWrite)
+}
+
+// CheckNetwork returns PolicyError if the policy doesn't allow network access.
+func (p *Policy) CheckNetwork() error {
+	if p == nil || p.Network {
+		return nil
+	}
+	return &PolicyError{Subject: "network access"}
+}
+
+// CheckConcurrency returns PolicyError if the policy
+// doesn't allow spawning tasks and selecting on channels.
+func (p *Policy) CheckConcurrency() error {
+	if p == nil || p.Concurrency {
+		return nil
+	}
+	return &PolicyError{Subject: "concurrency"}
+}
+
+// Modules returns ModuleGetter that applies the policy
+// to the builtin modules returned by the given ModuleGetter.
+func (p *Policy) Modules(modules ModuleGetter) ModuleGetter {
+	return &policyModules{
+		policy:  p,
+		modules: modules,
+		cache:   make(map[string]*BuiltinModule),
+	}
+}
+
+// SetPolicy sets the policy of the runtime.
+func (r *Runtime) SetPolicy(p *Policy) {
+	r.policy = p
+}
+
+// Policy returns the policy of the runtime.
+// Returns nil if the runtime has no policy.
+func (r *Runtime) Policy() *Policy {
+	return r.policy
+}
+
+// CheckFS returns PolicyError if the policy of the runtime
+// doesn't allow to access the file with the given name.
+// It is safe to call CheckFS on a nil Runtime.
+func (r *Runtime) CheckFS(name string, access FSAccess) error {
+	if r == nil {
+		return nil
+	}
+	return r.policy.checkFS(r.FS(), name, access)
+}
+
+// CheckSymlink returns PolicyError if the policy of the runtime
+// doesn't allow to create the symbolic link newname to oldname.
+// A relative oldname is resolved against the directory of the link.
+// It is safe to call CheckSymlink on a nil Runtime.
+func (r *Runtime) CheckSymlink(oldname, newname string) error {
+	if r == nil {
+		return nil
+	}
+	return r.policy.checkSymlink(r.FS(), oldname, newname)
+}
+
+// CheckNetwork returns PolicyError if the policy of the runtime
+// doesn't allow network access.
+// It is safe to call CheckNetwork on a nil Runtime.
+func (r *Runtime) CheckNetwork() error {
+	if r == nil {
+		return nil
+	}
+	return r.policy.CheckNetwork()
+}
+
+// CheckConcurrency returns PolicyError if the policy of the runtime
+// doesn't allow spawning tasks and selecting on channels.
+// It is safe to call CheckConcurrency on a nil Runtime.
+func (r *Runtime) CheckConcurrency() error {
+	if r == nil {
+		return nil
+	}
+	return r.policy.CheckConcurrency()
+}
+
+// policyModules is a ModuleGetter that applies the policy to builtin modules.
+type policyModules struct {
+	policy  *Policy
+	modules ModuleGetter
+	cache   map[string]*BuiltinModule
+}
+
+func (m *policyModules) Get(name string) Importable {
+	mod := m.modules.Get(name)
+	bm, ok := mod.(*BuiltinModule)
+	if !ok {
+		return mod
+	}
+	if res, ok := m.cache[name]; ok {
+		return res
+	}
+	res := &BuiltinModule{
+		Name:    bm.Name,
+		Members: make(map[string]Value, len(bm.Members)),
+	}
+	for member, value := range bm.Members {
+		if m.policy.Allowed(name, member) {
+			res.Members[member] = value
+		} else {
+			res.Members[member] = &deniedMember{
+				err: &PolicyError{Subject: "access to '" + name + "." + member + "'"},
+			}
+		}
+	}
+	m.cache[name] = res
+	return res
+}
+
+// deniedMember represents a builtin module member denied by the policy.
+type deniedMember struct {
+	err error
+}
+
+func (v *deniedMember) Type() ValueType { return nil }
+func (v *deniedMember) String() string  { return "<denied>" }
+func (v *deniedMember) IsFalsy() bool   { return true }
+func (v *deniedMember) Clone() Value    { return v }
+
+// resolvePath returns the absolute path of the file with the given name
+// with all symbolic links of its longest existing prefix evaluated.
+// Like the operating system, resolvePath applies ".." to the resolved
+// parent directory, so "link/.." is the parent of the link's target.
+func resolvePath(name string) (string, error) {
+	if !filepath.IsAbs(name) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		name = wd + string(filepath.Separator) + name
+	}
+	vol := filepath.VolumeName(name)
+	resolved := vol + string(filepath.Separator)
+	elems := strings.Split(filepath.ToSlash(name[len(vol):]), "/")
+	for i, elem := range elems {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next, err := filepath.EvalSymlinks(filepath.Join(resolved, elem))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+			// the rest of the path doesn't exist, so it has no symbolic links
+			return filepath.Join(append([]string{resolved}, elems[i:]...)...), nil
+		}
+		resolved = next
+	}
+	return resolved, nil
+}
@@ -0,0 +1,171 @@
+package toy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *toy.Policy
+		module string
+		member string
+		want   bool
+	}{
+		{"nil policy", nil, "os", "readfile", true},
+		{"empty policy", &toy.Policy{}, "os", "readfile", true},
+		{"allow module", &toy.Policy{Allow: []string{"os"}}, "os", "readfile", true},
+		{"allow module wildcard", &toy.Policy{Allow: []string{"os.*"}}, "os", "readfile", true},
+		{"allow member", &toy.Policy{Allow: []string{"os.readfile"}}, "os", "readfile", true},
+		{"allow other member", &toy.Policy{Allow: []string{"os.readfile"}}, "os", "remove", false},
+		{"allow other module", &toy.Policy{Allow: []string{"fmt"}}, "os", "readfile", false},
+		{"allow prefix", &toy.Policy{Allow: []string{"o"}}, "os", "readfile", false},
+		{"allow submodule", &toy.Policy{Allow: []string{"os"}}, "os/env", "get", false},
+		{"deny module", &toy.Policy{Deny: []string{"os"}}, "os", "readfile", false},
+		{"deny module wildcard", &toy.Policy{Deny: []string{"os.*"}}, "os", "readfile", false},
+		{"deny member", &toy.Policy{Deny: []string{"os.remove"}}, "os", "remove", false},
+		{"deny other member", &toy.Policy{Deny: []string{"os.remove"}}, "os", "readfile", true},
+		{"deny over allow", &toy.Policy{Allow: []string{"os"}, Deny: []string{"os.remove"}}, "os", "remove", false},
+		{"pure policy", toy.PurePolicy(), "os", "readfile", false},
+		{"pure policy other module", toy.PurePolicy(), "fmt", "println", true},
+		{"read-only policy", toy.ReadOnlyFSPolicy(""), "os/env", "set", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.policy.Allowed(tt.module, tt.member))
+		})
+	}
+}
+
+func TestPolicyCheckFS(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(outside, "inner"), 0o755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "inner"), filepath.Join(root, "deep")))
+
+	policy := &toy.Policy{FS: toy.FSReadWrite, Root: root}
+	tests := []struct {
+		name   string
+		policy *toy.Policy
+		file   string
+		access toy.FSAccess
+		denied bool
+	}{
+		{"inside", policy, filepath.Join(root, "a.txt"), toy.FSReadWrite, false},
+		{"root itself", policy, root, toy.FSReadOnly, false},
+		{"missing file inside", policy, filepath.Join(root, "sub", "x", "y"), toy.FSReadWrite, false},
+		{"dot dot", policy, filepath.Join(root, "..", "outside"), toy.FSReadOnly, true},
+		{"unclean dot dot", policy, root + "/sub/../../outside", toy.FSReadOnly, true},
+		{"sibling with common prefix", policy, root + "2", toy.FSReadOnly, true},
+		{"symlink", policy, filepath.Join(root, "escape", "a.txt"), toy.FSReadOnly, true},
+		{"dot dot after symlink", policy, root + "/deep/../a.txt", toy.FSReadOnly, true},
+		{"no access", &toy.Policy{FS: toy.FSNoAccess}, filepath.Join(root, "a.txt"), toy.FSReadOnly, true},
+		{"read-only", &toy.Policy{FS: toy.FSReadOnly}, filepath.Join(root, "a.txt"), toy.FSReadWrite, true},
+		{"read-only read", &toy.Policy{FS: toy.FSReadOnly}, filepath.Join(root, "a.txt"), toy.FSReadOnly, false},
+		{"nil policy", nil, "/etc/passwd", toy.FSReadWrite, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckFS(tt.file, tt.access)
+			if tt.denied {
+				require.ErrorIs(t, err, toy.ErrDenied)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckSymlink(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "outside", "inner"), 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "outside", "inner"), filepath.Join(root, "deep")))
+
+	policy := &toy.Policy{FS: toy.FSReadWrite, Root: root}
+	tests := []struct {
+		name    string
+		oldname string
+		newname string
+		denied  bool
+	}{
+		{"absolute inside", filepath.Join(root, "a.txt"), filepath.Join(root, "link"), false},
+		{"absolute outside", "/etc/passwd", filepath.Join(root, "link"), true},
+		{"relative to link", "a.txt", filepath.Join(root, "sub", "link"), false},
+		{"relative parent", "../a.txt", filepath.Join(root, "sub", "link"), false},
+		{"relative escape", "../../outside", filepath.Join(root, "sub", "link"), true},
+		{"relative to symlinked dir", "../a.txt", filepath.Join(root, "deep", "link"), true},
+		{"link outside", filepath.Join(root, "a.txt"), filepath.Join(dir, "link"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.CheckSymlink(tt.oldname, tt.newname)
+			if tt.denied {
+				require.ErrorIs(t, err, toy.ErrDenied)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyScript(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/data.txt":    {Data: []byte("data")},
+		"secret/data.txt": {Data: []byte("secret")},
+	}
+	runScriptTests(t, []scriptTest{
+		{
+			name: "denied member",
+			src:  `os := import("os"); os.remove("/app/data.txt")`,
+			err:  "access to 'os.remove' is denied by policy",
+		},
+		{
+			name: "allowed member",
+			src:  `os := import("os"); return string(os.readfile("/app/data.txt"))`,
+			want: toy.String("data"),
+		},
+		{
+			name: "outside of root",
+			src:  `os := import("os"); os.readfile("/secret/data.txt")`,
+			err:  "access to '/secret/data.txt' outside of '/app' is denied by policy",
+		},
+		{
+			name: "dot dot outside of root",
+			src:  `os := import("os"); os.readfile("/app/../secret/data.txt")`,
+			err:  "outside of '/app' is denied by policy",
+		},
+		{
+			name: "symlink escape",
+			src:  `os := import("os"); os.symlink("../secret/data.txt", "/app/link")`,
+			err:  "outside of '/app' is denied by policy",
+		},
+		{
+			name: "network",
+			src:  `net := import("net"); net.lookupIP("localhost")`,
+			err:  "network access is denied by policy",
+		},
+		{
+			name: "denied module",
+			src:  `env := import("os/env"); env.set("A", "B")`,
+			err:  "access to 'os/env.set' is denied by policy",
+		},
+	}, func(s *toy.Script) {
+		s.SetFS(fsys)
+		s.SetPolicy(&toy.Policy{
+			Deny: []string{"os.remove", "os/env"},
+			FS:   toy.FSReadOnly,
+			Root: "/app",
+		})
+	})
+}
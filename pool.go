@@ -0,0 +1,71 @@
+package toy
+
+import "sync"
+
+// CompiledPool is a pool of reusable instances of the compiled script.
+// It is intended for executing the same script many times in parallel,
+// avoiding the cost of Compiled.Clone and runtime allocation.
+//
+// Instances returned by Get have their globals reset to the values
+// of the Compiled passed to NewCompiledPool. Immutable globals are shared
+// between all instances. Mutable globals are shared as well until
+// an instance accesses them, either from the script or through
+// the methods of Compiled, at which point the instance gets its own copy.
+// Thus, the cost of a run includes cloning only the mutable globals
+// the script actually uses, and Put doesn't clone anything.
+//
+// CompiledPool is safe for concurrent use by multiple goroutines.
+type CompiledPool struct {
+	template *Compiled
+	mutable  []int // indexes of mutable globals
+	pool     sync.Pool
+}
+
+// NewCompiledPool creates a new pool of instances of the compiled script.
+// The pool takes a snapshot of the globals and configuration of c,
+// so subsequent changes to c do not affect the pool.
+func NewCompiledPool(c *Compiled) *CompiledPool {
+	p := &CompiledPool{template: c.Clone()}
+	for idx, g := range p.template.globals {
+		if g != nil && !Immutable(g) {
+			p.mutable = append(p.mutable, idx)
+		}
+	}
+	p.pool.New = func() any {
+		c := p.template.withGlobals(make([]Value, len(p.template.globals)))
+		c.shared = make([]bool, len(c.globals))
+		c.runtime = NewRuntime(c.bytecode, c.globals)
+		c.runtime.shared = c.shared
+		p.resetGlobals(c)
+		return c
+	}
+	return p
+}
+
+// Get returns an instance of the compiled script ready to be executed.
+// The instance should be returned to the pool with Put when it is no longer needed.
+func (p *CompiledPool) Get() *Compiled {
+	return p.pool.Get().(*Compiled)
+}
+
+// Put returns the instance obtained from Get to the pool, resetting its globals.
+// The instance must not be used after calling Put.
+func (p *CompiledPool) Put(c *Compiled) {
+	if c == nil || c.runtime == nil || c.bytecode != p.template.bytecode {
+		return
+	}
+	c.lock.Lock()
+	p.resetGlobals(c)
+	c.result = nil
+	c.lock.Unlock()
+	p.pool.Put(c)
+}
+
+// resetGlobals resets the globals of c to the globals of the template,
+// sharing the mutable ones until they are accessed.
+func (p *CompiledPool) resetGlobals(c *Compiled) {
+	copy(c.globals, p.template.globals)
+	for _, idx := range p.mutable {
+		c.shared[idx] = true
+	}
+}
@@ -0,0 +1,113 @@
+package toy_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompiledPoolResult(t *testing.T) {
+	script := toy.NewScript([]byte(`
+if value {
+	return "result"
+}`))
+	script.Add("value", toy.True)
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+
+	pool := toy.NewCompiledPool(compiled)
+	c := pool.Get()
+	require.NoError(t, c.Run())
+	require.Equal(t, toy.String("result"), c.Result())
+	pool.Put(c)
+
+	c = pool.Get()
+	require.Equal(t, toy.Nil, c.Result())
+	require.NoError(t, c.Set("value", toy.False))
+	require.NoError(t, c.Run())
+	require.Equal(t, toy.Nil, c.Result())
+	pool.Put(c)
+}
+
+func TestCompiledPool(t *testing.T) {
+	script := toy.NewScript([]byte(`
+counter += 1
+items = append(items, counter)
+state.key = counter
+result := [counter, len(items), state.key]`))
+	script.Add("counter", toy.Int(0))
+	script.Add("items", toy.NewArray(nil))
+	script.Add("state", toy.NewTable(0))
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+
+	pool := toy.NewCompiledPool(compiled)
+	for range 3 {
+		c := pool.Get()
+		require.NoError(t, c.Run())
+		// every run starts from the initial values
+		require.Equal(t, "[1, 1, 1]", toy.AsString(c.Get("result").Value()))
+		pool.Put(c)
+	}
+
+	// the template isn't affected by the runs
+	require.Equal(t, toy.Int(0), compiled.Get("counter").Value())
+	require.Equal(t, "{}", toy.AsString(compiled.Get("state").Value()))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				c := pool.Get()
+				assert.NoError(t, c.Run())
+				assert.Equal(t, "[1, 1, 1]", toy.AsString(c.Get("result").Value()))
+				pool.Put(c)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCompiledPoolSharedGlobals(t *testing.T) {
+	script := toy.NewScript([]byte(`used.key = "script"`))
+	script.Add("used", toy.NewTable(0))
+	script.Add("unused", toy.NewTable(0))
+	script.Add("updated", toy.NewTable(0))
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+
+	pool := toy.NewCompiledPool(compiled)
+	c := pool.Get()
+	require.NoError(t, c.Run())
+	// globals not used by the script are copied when accessed from Go
+	unused := c.Get("unused").Value().(*toy.Table)
+	require.NoError(t, unused.SetProperty(toy.String("key"), toy.String("get")))
+	require.NoError(t, c.UpdateGlobals(func(globals []toy.Value) error {
+		for _, g := range globals {
+			if table, ok := g.(*toy.Table); ok && table.Len() == 0 {
+				if err := table.SetProperty(toy.String("key"), toy.String("update")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+	require.Equal(t, `{key: "script"}`, toy.AsString(c.Get("used").Value()))
+	require.Equal(t, `{key: "get"}`, toy.AsString(c.Get("unused").Value()))
+	require.Equal(t, `{key: "update"}`, toy.AsString(c.Get("updated").Value()))
+	pool.Put(c)
+
+	for _, name := range []string{"used", "unused", "updated"} {
+		require.Equal(t, "{}", toy.AsString(compiled.Get(name).Value()), name)
+	}
+	c = pool.Get()
+	for _, name := range []string{"used", "unused", "updated"} {
+		require.Equal(t, "{}", toy.AsString(c.Get(name).Value()), name)
+	}
+	pool.Put(c)
+}
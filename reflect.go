@@ -0,0 +1,487 @@
+package toy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// valueOfFuncs contains functions registered with RegisterValueOf.
+var valueOfFuncs sync.Map // reflect.Type -> func(reflect.Value) (Value, error)
+
+// RegisterValueOf registers a function used by ValueOf
+// to convert Go values of type T into Toy values.
+// It is typically called from init functions of packages
+// that define Toy values for Go types, such as time.Time.
+func RegisterValueOf[T any](fn func(x T) (Value, error)) {
+	valueOfFuncs.Store(reflect.TypeFor[T](), func(rv reflect.Value) (Value, error) {
+		return fn(rv.Interface().(T))
+	})
+}
+
+// ValueOf converts a Go value into a Toy value.
+//
+// Values implementing Value are returned as is,
+// and values of types registered with RegisterValueOf
+// are converted using the registered functions.
+// Otherwise, the conversion depends on the kind of the value:
+//
+//   - nil, nil pointers, slices, maps and functions are converted to nil.
+//   - Booleans, integers, floats and strings are converted to
+//     bool, int, float and string respectively.
+//   - Byte slices and arrays are converted to bytes.
+//   - Slices and arrays are converted to arrays.
+//   - Maps are converted to tables.
+//   - Structs and pointers to structs are converted to Object.
+//   - Other pointers are dereferenced.
+//   - Functions are converted to builtin functions (see NewGoFunction).
+//
+// Slices, arrays and maps are converted recursively and
+// do not share memory with the original value.
+func ValueOf(x any) (Value, error) {
+	if x == nil {
+		return Nil, nil
+	}
+	if v, ok := x.(Value); ok {
+		return v, nil
+	}
+	return valueOf(reflect.ValueOf(x))
+}
+
+func valueOf(rv reflect.Value) (Value, error) {
+	if !rv.IsValid() {
+		return Nil, nil
+	}
+	if rv.CanInterface() {
+		if fn, ok := valueOfFuncs.Load(rv.Type()); ok {
+			return fn.(func(reflect.Value) (Value, error))(rv)
+		}
+		if rv.Type().Implements(reflect.TypeFor[Value]()) {
+			if isNil(rv) {
+				return Nil, nil
+			}
+			return rv.Interface().(Value), nil
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d of type %s overflows int", u, rv.Type())
+		}
+		return Int(u), nil
+	case reflect.Float32, reflect.Float64:
+		return Float(rv.Float()), nil
+	case reflect.String:
+		return String(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return Nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make(Bytes, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		elems := make([]Value, rv.Len())
+		for i := range rv.Len() {
+			elem, err := valueOf(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			elems[i] = elem
+		}
+		return NewArray(elems), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return Nil, nil
+		}
+		t := NewTable(rv.Len())
+		for key, value := range rv.Seq2() {
+			k, err := valueOf(key)
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", key, err)
+			}
+			v, err := valueOf(value)
+			if err != nil {
+				return nil, fmt.Errorf("[%v]: %w", key, err)
+			}
+			if err := t.SetProperty(k, v); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return Nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Struct {
+			return &Object{ptr: rv}, nil
+		}
+		return valueOf(rv.Elem())
+	case reflect.Interface:
+		if rv.IsNil() {
+			return Nil, nil
+		}
+		return valueOf(rv.Elem())
+	case reflect.Struct:
+		// copy the struct, so that methods
+		// with pointer receivers can be called
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return &Object{ptr: ptr}, nil
+	case reflect.Func:
+		if rv.IsNil() {
+			return Nil, nil
+		}
+		name := "func"
+		if fn := runtime.FuncForPC(rv.Pointer()); fn != nil {
+			name = fn.Name()
+			name = name[strings.LastIndexByte(name, '/')+1:]
+		}
+		return NewGoFunction(name, rv.Interface()), nil
+	}
+	return nil, fmt.Errorf("unsupported Go type: %s", rv.Type())
+}
+
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// NewGoFunction creates a new BuiltinFunction that calls the Go function fn.
+//
+// Arguments are converted into the parameter types of fn using Unpack.
+// If the first parameter of fn is *Runtime or context.Context,
+// it receives the runtime calling the function or its context respectively.
+// Results are converted using ValueOf, and multiple results are returned as Tuple.
+// If the last result of fn is an error, it is returned as the error of the call.
+//
+// NewGoFunction panics if fn is not a function.
+func NewGoFunction(name string, fn any) *BuiltinFunction {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("not a function: %T", fn))
+	}
+	return newGoFunction(name, rv)
+}
+
+func newGoFunction(name string, fn reflect.Value) *BuiltinFunction {
+	ft := fn.Type()
+	return NewBuiltinFunction(name, func(r *Runtime, args ...Value) (Value, error) {
+		in := make([]reflect.Value, 0, ft.NumIn())
+		first := 0
+		if ft.NumIn() != 0 {
+			switch ft.In(0) {
+			case reflect.TypeFor[*Runtime]():
+				in = append(in, reflect.ValueOf(r))
+				first = 1
+			case reflect.TypeFor[context.Context]():
+				in = append(in, reflect.ValueOf(r.Context()))
+				first = 1
+			}
+		}
+		nparams := ft.NumIn() - first
+		if ft.IsVariadic() {
+			if len(args) < nparams-1 {
+				return nil, &WrongNumArgumentsError{
+					WantMin: nparams - 1,
+					WantMax: -1,
+					Got:     len(args),
+				}
+			}
+		} else if len(args) != nparams {
+			return nil, &WrongNumArgumentsError{
+				WantMin: nparams,
+				WantMax: nparams,
+				Got:     len(args),
+			}
+		}
+		for i, arg := range args {
+			var pt reflect.Type
+			if ft.IsVariadic() && i >= nparams-1 {
+				pt = ft.In(ft.NumIn() - 1).Elem()
+			} else {
+				pt = ft.In(first + i)
+			}
+			pv, err := unpackValue(pt, arg)
+			if err != nil {
+				name := fmt.Sprintf("#%d", i+1)
+				if e, ok := err.(*InvalidValueTypeError); ok {
+					return nil, &InvalidArgumentTypeError{
+						Name: name,
+						Sel:  e.Sel,
+						Want: e.Want,
+						Got:  e.Got,
+					}
+				}
+				return nil, fmt.Errorf("invalid value for argument '%s': %w", name, err)
+			}
+			in = append(in, pv)
+		}
+		out := fn.Call(in)
+		if n := len(out); n != 0 && ft.Out(n-1) == reflect.TypeFor[error]() {
+			if err := out[n-1]; !err.IsNil() {
+				return nil, err.Interface().(error)
+			}
+			out = out[:n-1]
+		}
+		switch len(out) {
+		case 0:
+			return Nil, nil
+		case 1:
+			return valueOf(out[0])
+		}
+		res := make(Tuple, len(out))
+		for i, o := range out {
+			v, err := valueOf(o)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	})
+}
+
+// unpackValue unpacks v into a new Go value of the given type.
+func unpackValue(t reflect.Type, v Value) (_ reflect.Value, err error) {
+	if v == Nil && isNil(reflect.Zero(t)) {
+		return reflect.Zero(t), nil
+	}
+	defer func() {
+		// Unpack panics if the type is not supported
+		if p := recover(); p != nil {
+			err = fmt.Errorf("unsupported Go type: %s", t)
+		}
+	}()
+	ptr := reflect.New(t)
+	if err := Unpack(ptr.Interface(), v); err != nil {
+		return reflect.Value{}, err
+	}
+	return ptr.Elem(), nil
+}
+
+// Object is a Toy value that wraps a Go struct.
+// Fields of the struct are accessible as properties named
+// according to the struct tags used by Unpack,
+// or according to the names of the fields if the tags are absent.
+// Exported methods of the struct are accessible as properties
+// and can be called from Toy code.
+//
+// Object shares memory with the wrapped struct.
+// Clone copies the struct, but pointers, slices and maps
+// stored in its fields still share memory with the original.
+// Fields of a frozen object can't be set, and its methods
+// are called on a copy of the struct.
+type Object struct {
+	ptr       reflect.Value // pointer to struct
+	immutable bool
+}
+
+var ObjectType = NewType[*Object]("object", nil)
+
+// Interface returns the pointer to the wrapped struct.
+func (o *Object) Interface() any { return o.ptr.Interface() }
+
+func (o *Object) Type() ValueType { return ObjectType }
+func (o *Object) IsFalsy() bool   { return false }
+
+func (o *Object) Clone() Value {
+	ptr := reflect.New(o.ptr.Type().Elem())
+	ptr.Elem().Set(o.ptr.Elem())
+	return &Object{ptr: ptr}
+}
+
+func (o *Object) Freeze() Value {
+	o.immutable = true
+	return o
+}
+
+func (o *Object) Immutable() bool { return o.immutable }
+
+func (o *Object) String() string {
+	var b strings.Builder
+	b.WriteString(o.ptr.Type().Elem().String())
+	b.WriteByte('{')
+	first := true
+	for key, value := range o.Entries() {
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		b.WriteString(string(key.(String)))
+		b.WriteString(": ")
+		b.WriteString(value.String())
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (o *Object) Len() int {
+	return len(objectFieldsOf(o.ptr.Type().Elem()).names)
+}
+
+func (o *Object) Entries() iter.Seq2[Value, Value] {
+	return func(yield func(Value, Value) bool) {
+		fields := objectFieldsOf(o.ptr.Type().Elem())
+		for _, name := range fields.names {
+			fv, err := o.ptr.Elem().FieldByIndexErr(fields.index[name])
+			if err != nil {
+				continue // nil embedded pointer
+			}
+			value, err := valueOf(fv)
+			if err != nil {
+				value = String(fmt.Sprint(fv))
+			}
+			if !yield(String(name), value) {
+				return
+			}
+		}
+	}
+}
+
+func (o *Object) Items() []Tuple {
+	var items []Tuple
+	for key, value := range o.Entries() {
+		items = append(items, Tuple{key, value})
+	}
+	return items
+}
+
+func (o *Object) Property(key Value) (value Value, found bool, err error) {
+	keyStr, ok := key.(String)
+	if !ok {
+		return nil, false, &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	name := string(keyStr)
+	if index, ok := objectFieldsOf(o.ptr.Type().Elem()).index[name]; ok {
+		fv, err := o.ptr.Elem().FieldByIndexErr(index)
+		if err != nil {
+			return Nil, true, nil
+		}
+		value, err := valueOf(fv)
+		if err != nil {
+			return nil, false, fmt.Errorf("property '%s': %w", name, err)
+		}
+		return value, true, nil
+	}
+	ptr := o.ptr
+	if o.immutable {
+		// methods can modify the struct
+		ptr = o.Clone().(*Object).ptr
+	}
+	if m := ptr.MethodByName(name); m.IsValid() {
+		return newGoFunction(o.ptr.Type().Elem().Name()+"."+name, m), true, nil
+	}
+	return Nil, false, nil
+}
+
+func (o *Object) SetProperty(key, value Value) error {
+	if o.immutable {
+		return errors.New("cannot assign to immutable object")
+	}
+	keyStr, ok := key.(String)
+	if !ok {
+		return &InvalidKeyTypeError{
+			Want: "string",
+			Got:  TypeName(key),
+		}
+	}
+	name := string(keyStr)
+	index, ok := objectFieldsOf(o.ptr.Type().Elem()).index[name]
+	if !ok {
+		return fmt.Errorf("'%s' has no field '%s'", o.ptr.Type().Elem(), name)
+	}
+	fv, err := o.ptr.Elem().FieldByIndexErr(index)
+	if err != nil {
+		return err
+	}
+	v, err := unpackValue(fv.Type(), value)
+	if err != nil {
+		if e, ok := err.(*InvalidValueTypeError); ok {
+			return &InvalidValueTypeError{
+				Sel:  "." + name + e.Sel,
+				Want: e.Want,
+				Got:  e.Got,
+			}
+		}
+		return fmt.Errorf("invalid value for '%s': %w", name, err)
+	}
+	fv.Set(v)
+	return nil
+}
+
+// objectFields describes fields of a struct accessible from Toy code.
+type objectFields struct {
+	names []string         // in the order of declaration
+	index map[string][]int // field name to field index
+}
+
+var objectFieldsCache sync.Map // reflect.Type -> *objectFields
+
+// objectFieldsOf returns fields of the struct type t.
+// Fields are extracted the same way unpackToStruct does,
+// except that exported fields without tags are included as well.
+func objectFieldsOf(t reflect.Type) *objectFields {
+	if fields, ok := objectFieldsCache.Load(t); ok {
+		return fields.(*objectFields)
+	}
+	fields := &objectFields{index: make(map[string][]int)}
+	var extract func(t reflect.Type, index []int)
+	extract = func(t reflect.Type, index []int) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name := field.Tag.Get(UnpackStructTag)
+			if name == "-" || name == "..." {
+				continue
+			}
+			fieldIndex := append(index[:len(index):len(index)], i)
+			if field.Anonymous && name == "" {
+				ft := field.Type
+				for ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					extract(ft, fieldIndex)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			} else if name[len(name)-1] == '?' {
+				name = name[:len(name)-1]
+			}
+			if prev, ok := fields.index[name]; ok {
+				if len(prev) <= len(fieldIndex) {
+					continue // shadowed by a less nested field
+				}
+			} else {
+				fields.names = append(fields.names, name)
+			}
+			fields.index[name] = fieldIndex
+		}
+	}
+	extract(t, nil)
+	res, _ := objectFieldsCache.LoadOrStore(t, fields)
+	return res.(*objectFields)
+}
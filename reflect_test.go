@@ -0,0 +1,209 @@
+package toy_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/stretchr/testify/require"
+)
+
+type reflectPoint struct {
+	X     int
+	Y     int `toy:"y"`
+	Label string
+	Tags  []string
+	hide  int
+}
+
+func (p *reflectPoint) Move(dx, dy int) {
+	p.X += dx
+	p.Y += dy
+}
+
+func (p reflectPoint) Sum() int {
+	return p.X + p.Y
+}
+
+func TestValueOf(t *testing.T) {
+	tests := []struct {
+		name string
+		x    any
+		want string
+		err  string
+	}{
+		{name: "nil", x: nil, want: "<nil>"},
+		{name: "value", x: toy.Int(1), want: "1"},
+		{name: "bool", x: true, want: "true"},
+		{name: "int", x: int8(-5), want: "-5"},
+		{name: "uint", x: uint32(5), want: "5"},
+		{name: "max uint64 within int", x: uint64(math.MaxInt64), want: "9223372036854775807"},
+		{name: "uint64 overflow", x: uint64(math.MaxInt64 + 1), err: "value 9223372036854775808 of type uint64 overflows int"},
+		{name: "uintptr overflow", x: uintptr(math.MaxUint64), err: "overflows int"},
+		{name: "float", x: float32(0.5), want: "0.5"},
+		{name: "string", x: "abc", want: `"abc"`},
+		{name: "bytes", x: []byte("ab"), want: `bytes("ab")`},
+		{name: "byte array", x: [2]byte{'a', 'b'}, want: `bytes("ab")`},
+		{name: "slice", x: []int{1, 2}, want: "[1, 2]"},
+		{name: "nil slice", x: []int(nil), want: "<nil>"},
+		{name: "slice overflow", x: []uint64{1, math.MaxUint64}, err: "[1]: value 18446744073709551615 of type uint64 overflows int"},
+		{name: "map", x: map[string]int{"a": 1}, want: `{a: 1}`},
+		{name: "map overflow", x: map[string]uint{"a": math.MaxUint}, err: "[a]: value"},
+		{name: "nil map", x: map[string]int(nil), want: "<nil>"},
+		{name: "pointer", x: new(int), want: "0"},
+		{name: "nil pointer", x: (*int)(nil), want: "<nil>"},
+		{name: "struct", x: reflectPoint{X: 1, Y: 2}, want: `toy_test.reflectPoint{X: 1, y: 2, Label: "", Tags: <nil>}`},
+		{name: "unsupported", x: make(chan int), err: "unsupported Go type: chan int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := toy.ValueOf(tt.x)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, v.String())
+		})
+	}
+}
+
+func TestNewGoFunction(t *testing.T) {
+	type ctxKey struct{}
+	tests := []struct {
+		name string
+		fn   any
+		args []toy.Value
+		want string
+		err  string
+	}{
+		{
+			name: "no results",
+			fn:   func() {},
+			want: "<nil>",
+		},
+		{
+			name: "arguments",
+			fn:   func(a int, b string, c []float64) string { return fmt.Sprint(a, b, c) },
+			args: []toy.Value{toy.Int(1), toy.String("x"), toy.NewArray([]toy.Value{toy.Float(0.5)})},
+			want: `"1x[0.5]"`,
+		},
+		{
+			name: "multiple results",
+			fn:   func(a, b int) (int, int) { return b, a },
+			args: []toy.Value{toy.Int(1), toy.Int(2)},
+			want: "tuple(2, 1)",
+		},
+		{
+			name: "error result",
+			fn:   func() (int, error) { return 0, errors.New("failed") },
+			err:  "failed",
+		},
+		{
+			name: "nil error result",
+			fn:   func() (int, error) { return 1, nil },
+			want: "1",
+		},
+		{
+			name: "result overflow",
+			fn:   func() uint64 { return math.MaxUint64 },
+			err:  "overflows int",
+		},
+		{
+			name: "variadic",
+			fn:   func(sep string, xs ...int) int { return len(sep) + len(xs) },
+			args: []toy.Value{toy.String("-"), toy.Int(1), toy.Int(2)},
+			want: "3",
+		},
+		{
+			name: "runtime",
+			fn:   func(r *toy.Runtime, x int) bool { return r != nil && x == 1 },
+			args: []toy.Value{toy.Int(1)},
+			want: "true",
+		},
+		{
+			name: "context",
+			fn:   func(ctx context.Context) any { return ctx.Value(ctxKey{}) },
+			want: `"value"`,
+		},
+		{
+			name: "nil argument",
+			fn:   func(p *int) bool { return p == nil },
+			args: []toy.Value{toy.Nil},
+			want: "true",
+		},
+		{
+			name: "wrong number of arguments",
+			fn:   func(a int) {},
+			err:  "want 1 argument(s), got 0",
+		},
+		{
+			name: "too few variadic arguments",
+			fn:   func(a int, xs ...int) {},
+			err:  "want at least 1 argument(s), got 0",
+		},
+		{
+			name: "invalid argument type",
+			fn:   func(a int) {},
+			args: []toy.Value{toy.String("x")},
+			err:  "invalid type for argument '#1': want 'int', got 'string'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toy.NewRuntime(&toy.Bytecode{MainFunction: &toy.CompiledFunction{}}, nil)
+			r.SetContext(context.WithValue(context.Background(), ctxKey{}, "value"))
+			fn := toy.NewGoFunction("fn", tt.fn)
+			res, err := fn.Call(r, tt.args...)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, res.String())
+		})
+	}
+}
+
+func TestObject(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string
+	}{
+		{name: "get", src: `return [p.X, p.y, p.Label]`, want: `[1, 2, "a"]`},
+		{name: "get unknown", src: `return p.Z`, want: "<nil>"},
+		{name: "get unexported", src: `return p.hide`, want: "<nil>"},
+		{name: "set", src: `p.X = 10; p.Tags = ["t"]; return [p.X, p.Tags]`, want: `[10, ["t"]]`},
+		{name: "set unknown", src: `p.Z = 1`, err: "'toy_test.reflectPoint' has no field 'Z'"},
+		{name: "set invalid type", src: `p.X = "x"`, err: "want 'int', got 'string'"},
+		{name: "method", src: `p.Move(1, 1); return p.Sum()`, want: "5"},
+		{name: "len", src: `return len(p)`, want: "4"},
+		{name: "clone", src: `c := clone(p); c.X = 10; return [p.X, c.X]`, want: "[1, 10]"},
+		{name: "frozen set", src: `f := freeze(clone(p)); f.X = 10`, err: "cannot assign to immutable object"},
+		{name: "frozen method", src: `f := freeze(clone(p)); f.Move(1, 1); return f.X`, want: "1"},
+		{name: "frozen clone", src: `c := clone(freeze(p)); c.X = 10; return [p.X, c.X]`, want: "[1, 10]"},
+		{name: "spawn", src: `return spawn(fn() { p.X = 10 }).wait()`, err: "cannot assign to immutable object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &reflectPoint{X: 1, Y: 2, Label: "a"}
+			obj, err := toy.ValueOf(p)
+			require.NoError(t, err)
+
+			script := toy.NewScript([]byte(tt.src))
+			script.Add("p", obj)
+			compiled, err := script.RunContext(context.Background())
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, compiled.Result().String())
+		})
+	}
+}
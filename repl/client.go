@@ -0,0 +1,89 @@
+package repl
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+)
+
+// Client is a client of a REPL server.
+// Use NewRemote to run a REPL evaluating the input by the server.
+type Client struct {
+	enc    *json.Encoder
+	dec    *json.Decoder
+	closer io.Closer
+	done   bool // the server has ended the session
+}
+
+// Dial connects to the REPL server listening on the Unix socket.
+func Dial(path string) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(conn)
+	c.closer = conn
+	return c, nil
+}
+
+// NewClient creates a client communicating with the server over the connection.
+func NewClient(conn io.ReadWriter) *Client {
+	return &Client{
+		enc: json.NewEncoder(conn),
+		dec: json.NewDecoder(conn),
+	}
+}
+
+// Eval sends the input, which is either a command or code,
+// to the server and returns the output.
+func (c *Client) Eval(input string) (string, error) {
+	resp, err := c.roundTrip(&request{Op: "eval", Input: input})
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Output, nil
+}
+
+// Complete returns the completions of the word before the end of the input,
+// and the length of the word in bytes. See Session.Complete.
+func (c *Client) Complete(input string) ([]string, int, error) {
+	resp, err := c.roundTrip(&request{Op: "complete", Input: input})
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Error != "" {
+		return nil, 0, errors.New(resp.Error)
+	}
+	return resp.Completions, resp.WordLen, nil
+}
+
+// Close closes the connection if it has been opened by Dial.
+func (c *Client) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+func (c *Client) roundTrip(req *request) (*response, error) {
+	if c.done {
+		return nil, errors.New("session has ended")
+	}
+	if err := c.enc.Encode(req); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := c.dec.Decode(&resp); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("connection closed by the server")
+		}
+		c.done = true
+		return nil, err
+	}
+	c.done = resp.Quit
+	return &resp, nil
+}
@@ -0,0 +1,181 @@
+package repl
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/infastin/toy"
+)
+
+// builtinCommands returns the commands available in every REPL.
+func builtinCommands() []*Command {
+	return []*Command{
+		{
+			Name:  "help",
+			Usage: "show the list of commands",
+			Run:   helpCommand,
+		},
+		{
+			Name:  "quit",
+			Usage: "exit the REPL",
+			Run: func(r *REPL, _ string) (string, error) {
+				r.Quit()
+				return "", nil
+			},
+		},
+		{
+			Name:  "load",
+			Args:  "FILE",
+			Usage: "evaluate the file in the session",
+			Run:   loadCommand,
+		},
+		{
+			Name:  "reset",
+			Usage: "discard the variables defined in the session",
+			Run: func(r *REPL, _ string) (string, error) {
+				r.session.Reset()
+				return "", nil
+			},
+		},
+		{
+			Name:  "type",
+			Args:  "EXPR",
+			Usage: "show the type of the expression",
+			Run:   typeCommand,
+		},
+		{
+			Name:  "bytecode",
+			Args:  "CODE",
+			Usage: "show the compiled instructions of the code without running it",
+			Run:   bytecodeCommand,
+		},
+		{
+			Name:  "globals",
+			Usage: "show the global variables",
+			Run:   globalsCommand,
+		},
+		{
+			Name:  "time",
+			Args:  "CODE",
+			Usage: "evaluate the code and show how long it took",
+			Run:   timeCommand,
+		},
+		{
+			Name:  "doc",
+			Args:  "NAME",
+			Usage: "show the documentation of the variable or member",
+			Run:   docCommand,
+		},
+		{
+			Name:  "save",
+			Args:  "FILE",
+			Usage: "save the code evaluated in the session to the file",
+			Run:   saveCommand,
+		},
+	}
+}
+
+func helpCommand(r *REPL, _ string) (string, error) {
+	commands := slices.SortedFunc(slices.Values(r.commands), func(a, b *Command) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	usages := make([]string, len(commands))
+	var width int
+	for i, cmd := range commands {
+		usages[i] = ":" + cmd.Name
+		if cmd.Args != "" {
+			usages[i] += " " + cmd.Args
+		}
+		width = max(width, len(usages[i]))
+	}
+	var b strings.Builder
+	for i, cmd := range commands {
+		if i != 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%-*s  %s", width, usages[i], cmd.Usage)
+	}
+	return b.String(), nil
+}
+
+func loadCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected a file name")
+	}
+	return r.session.EvalFile(r.context(), args)
+}
+
+func typeCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected an expression")
+	}
+	value, err := r.session.EvalExpr(r.context(), []byte(args))
+	if err != nil {
+		return "", err
+	}
+	return toy.TypeName(value), nil
+}
+
+func bytecodeCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected code to compile")
+	}
+	return r.session.Bytecode([]byte(args))
+}
+
+func globalsCommand(r *REPL, _ string) (string, error) {
+	names := r.session.Globals()
+	types := make([]string, len(names))
+	var nameWidth, typeWidth int
+	for i, name := range names {
+		types[i] = toy.TypeName(r.session.Get(name))
+		nameWidth = max(nameWidth, len(name))
+		typeWidth = max(typeWidth, len(types[i]))
+	}
+	var b strings.Builder
+	for i, name := range names {
+		if i != 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%-*s  %-*s  %s", nameWidth, name, typeWidth, types[i], r.session.Get(name))
+	}
+	return b.String(), nil
+}
+
+func timeCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected code to evaluate")
+	}
+	start := time.Now()
+	output, err := r.session.Eval(r.context(), []byte(args))
+	elapsed := time.Since(start)
+	if err != nil {
+		return "", err
+	}
+	if output != "" {
+		output += "\n"
+	}
+	return output + "took " + elapsed.String(), nil
+}
+
+func docCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected a name")
+	}
+	return r.session.Doc(args)
+}
+
+func saveCommand(r *REPL, args string) (string, error) {
+	if args == "" {
+		return "", errors.New("expected a file name")
+	}
+	if err := os.WriteFile(args, r.session.Source(), 0o644); err != nil {
+		return "", err
+	}
+	return "", nil
+}
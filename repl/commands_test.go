@@ -0,0 +1,84 @@
+package repl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/infastin/toy/repl"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.toy")
+	require.NoError(t, os.WriteFile(lib, []byte("#!/usr/bin/env toy\ny := x * 2\n"), 0o644))
+
+	s := repl.NewSession()
+	s.SetModules(stdlib.StdLib)
+	r := repl.New(s)
+	run := func(name, args string) (string, error) {
+		cmd := r.Command(name)
+		require.NotNil(t, cmd, name)
+		return cmd.Run(r, args)
+	}
+
+	_, err := s.Eval(context.Background(), []byte(`x := 21`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		args   string
+		output string
+		match  string // regexp matched instead of output
+		err    string
+	}{
+		{name: "load", args: lib, output: "42"},
+		{name: "load", args: filepath.Join(dir, "missing.toy"), err: "no such file or directory"},
+		{name: "load", err: "expected a file name"},
+		{name: "time", args: `y + 1`, match: `^43\ntook \d+(\.\d+)?[nµm]?s$`},
+		{name: "time", args: `z := y`, match: `^42\ntook `},
+		{name: "time", args: `1 + "a"`, err: "invalid operation"},
+		{name: "time", err: "expected code to evaluate"},
+		{name: "bytecode", args: `w := y`, match: `^0000 GETG \[\d+\]\n0003 SETG \[\d+\]\n0006 RET \[0\]$`},
+		{name: "bytecode", args: `f := fn() { return 1 }`, match: `(?m)^\[\s*\d+\] <compiled-function f> \(function\)\n\s+0000 CONST`},
+		{name: "bytecode", args: `undefined`, err: "unresolved reference 'undefined'"},
+		{name: "bytecode", err: "expected code to compile"},
+		{name: "save", err: "expected a file name"},
+	}
+	for _, tt := range tests {
+		output, err := run(tt.name, tt.args)
+		if tt.err != "" {
+			require.ErrorContains(t, err, tt.err, ":%s %s", tt.name, tt.args)
+			continue
+		}
+		require.NoError(t, err, ":%s %s", tt.name, tt.args)
+		if tt.match != "" {
+			require.Regexp(t, regexp.MustCompile(tt.match), output, ":%s %s", tt.name, tt.args)
+		} else {
+			require.Equal(t, tt.output, output, ":%s %s", tt.name, tt.args)
+		}
+	}
+
+	// :bytecode doesn't define variables
+	require.Nil(t, s.Get("w"))
+	require.Nil(t, s.Get("f"))
+
+	saved := filepath.Join(dir, "saved.toy")
+	output, err := run("save", saved)
+	require.NoError(t, err)
+	require.Empty(t, output)
+	data, err := os.ReadFile(saved)
+	require.NoError(t, err)
+	require.Equal(t, string(s.Source()), string(data))
+
+	// the saved session can be loaded again
+	r2 := repl.New(nil)
+	output, err = r2.Command("load").Run(r2, saved)
+	require.NoError(t, err)
+	require.Contains(t, output, "42")
+	require.Equal(t, s.Get("z"), r2.Session().Get("z"))
+}
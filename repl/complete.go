@@ -0,0 +1,185 @@
+package repl
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
+
+// Complete returns the completions of the word before the end of the input,
+// and the length of the word in bytes.
+//
+// A word that follows a selector chain, e.g. "config.server.po",
+// is completed with the keys of the table or members of the module
+// the chain refers to. Otherwise, it is completed with keywords,
+// builtins and global variables.
+//
+// Nothing is completed inside string literals, character literals
+// and comments, except for the code of string interpolations.
+func (s *Session) Complete(input string) (completions []string, wordLen int) {
+	if inLiteral(input) {
+		return nil, 0
+	}
+	word := lastWord(input)
+	before := input[:len(input)-len(word)]
+
+	var candidates []string
+	if chain, ok := strings.CutSuffix(before, "."); ok {
+		value := s.resolveChain(lastChain(chain))
+		if value == nil {
+			return nil, len(word)
+		}
+		candidates = memberNames(value)
+	} else {
+		candidates = s.names()
+	}
+
+	for _, name := range candidates {
+		if strings.HasPrefix(name, word) {
+			completions = append(completions, name)
+		}
+	}
+	slices.Sort(completions)
+	return slices.Compact(completions), len(word)
+}
+
+// inLiteral reports whether the end of the input is inside
+// a string literal, a character literal or a comment.
+func inLiteral(input string) bool {
+	src := []byte(input)
+	fileSet := token.NewFileSet()
+	srcFile := fileSet.AddFile("(repl)", -1, len(src))
+
+	var unterminated []int // offsets of unterminated literals
+	scanner := parser.NewScanner(srcFile, src, func(pos token.FilePos, msg string) {
+		if strings.HasSuffix(msg, "not terminated") {
+			unterminated = append(unterminated, pos.Offset)
+		}
+	}, parser.ScanComments)
+
+	var stack []token.Token // opening quotes and braces
+	for {
+		tok, literal, pos := scanner.Scan()
+		switch tok {
+		case token.EOF:
+			// the text of a string is inside its quotes,
+			// while the code of an interpolation is inside its braces
+			return len(stack) != 0 && stack[len(stack)-1] != token.LBrace
+		case token.DoubleQuote, token.Backtick, token.DoubleSingleQuote:
+			if n := len(stack); n != 0 && stack[n-1] == tok {
+				stack = stack[:n-1]
+			} else {
+				stack = append(stack, tok)
+			}
+		case token.LBrace:
+			stack = append(stack, tok)
+		case token.RBrace:
+			if n := len(stack); n != 0 && stack[n-1] == token.LBrace {
+				stack = stack[:n-1]
+			}
+		case token.Char, token.Comment:
+			offset := srcFile.Offset(pos)
+			if offset+len(literal) == len(src) &&
+				(strings.HasPrefix(literal, "//") || slices.Contains(unterminated, offset)) {
+				return true
+			}
+		}
+	}
+}
+
+// names returns the names of keywords, builtins and global variables.
+func (s *Session) names() []string {
+	var names []string
+	for tok := token.Break; tok.IsKeyword(); tok++ {
+		names = append(names, tok.String())
+	}
+	for _, name := range s.symbolTable.Names() {
+		if name != replPrintName && name != replResultName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolveChain returns the value the chain of selectors refers to,
+// or nil if it can't be resolved without evaluating code.
+func (s *Session) resolveChain(chain string) toy.Value {
+	names := strings.Split(chain, ".")
+	value := s.Get(names[0])
+	for _, name := range names[1:] {
+		if value == nil {
+			return nil
+		}
+		value = member(value, name)
+	}
+	return value
+}
+
+func member(value toy.Value, name string) toy.Value {
+	switch x := value.(type) {
+	case *toy.BuiltinModule:
+		return x.Members[name]
+	case toy.Mapping:
+		for key, value := range x.Entries() {
+			if key, ok := key.(toy.String); ok && string(key) == name {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// memberNames returns the names of the members of a module
+// or string keys of a mapping that are valid identifiers.
+func memberNames(value toy.Value) []string {
+	switch x := value.(type) {
+	case *toy.BuiltinModule:
+		return slices.Collect(maps.Keys(x.Members))
+	case toy.Mapping:
+		var names []string
+		for key := range x.Entries() {
+			if key, ok := key.(toy.String); ok && isIdent(string(key)) {
+				names = append(names, string(key))
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// lastWord returns the identifier characters at the end of the input.
+func lastWord(input string) string {
+	i := strings.LastIndexFunc(input, func(r rune) bool {
+		return !isIdentRune(r)
+	})
+	return input[i+1:]
+}
+
+// lastChain returns the chain of selectors at the end of the input,
+// e.g. "a.b" for "x := a.b".
+func lastChain(input string) string {
+	i := strings.LastIndexFunc(input, func(r rune) bool {
+		return !isIdentRune(r) && r != '.'
+	})
+	chain := input[i+1:]
+	if chain == "" || strings.HasPrefix(chain, ".") || strings.Contains(chain, "..") {
+		return ""
+	}
+	return chain
+}
+
+func isIdent(s string) bool {
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		return false
+	}
+	return strings.IndexFunc(s, func(r rune) bool { return !isIdentRune(r) }) == -1
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,51 @@
+package repl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/infastin/toy/repl"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionComplete(t *testing.T) {
+	s := repl.NewSession()
+	s.SetModules(stdlib.StdLib)
+	_, err := s.Eval(context.Background(), []byte(`fmt := import("fmt"); config := {server: {port: 80, host: "a"}}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		input   string
+		want    []string
+		wordLen int
+	}{
+		{input: `fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `x := fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `config.server.po`, want: []string{"port"}, wordLen: 2},
+		{input: `config.server.`, want: []string{"host", "port"}},
+		{input: `fmt.printl`, want: []string{"println"}, wordLen: 6},
+		{input: `missing.x`, wordLen: 1},
+		{input: `"fm`},
+		{input: `"a b fm`},
+		{input: "`fm"},
+		{input: "''fm"},
+		{input: `'f`},
+		{input: `// fm`},
+		{input: `x := 1 // fm`},
+		{input: `/* fm`},
+		{input: `"a" + fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `/* a */ fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: "// a\nfm", want: []string{"fmt"}, wordLen: 2},
+		{input: `"a {fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `"a {fmt.sprintf("x {fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `"a {fmt.sprintf("fm`},
+		{input: `"a {{fm`, want: []string{"fmt"}, wordLen: 2},
+		{input: `"a {x} fm`},
+	}
+	for _, tt := range tests {
+		completions, wordLen := s.Complete(tt.input)
+		require.Equal(t, tt.want, completions, tt.input)
+		require.Equal(t, tt.wordLen, wordLen, tt.input)
+	}
+}
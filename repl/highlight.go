@@ -0,0 +1,101 @@
+package repl
+
+import (
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
+
+// tokenClass is the class of a token used for syntax highlighting.
+type tokenClass int
+
+const (
+	classNone tokenClass = iota
+	classKeyword
+	classString
+	classNumber
+	classComment
+	classIllegal
+)
+
+// highlighting represents the result of scanning the input
+// for syntax highlighting.
+type highlighting struct {
+	classes  []tokenClass // classes of bytes of the input
+	brackets map[int]int  // offsets of matching brackets
+}
+
+// highlight scans the input and classifies its bytes.
+func highlight(input []byte) *highlighting {
+	h := &highlighting{
+		classes:  make([]tokenClass, len(input)),
+		brackets: make(map[int]int),
+	}
+
+	fileSet := token.NewFileSet()
+	srcFile := fileSet.AddFile("(repl)", -1, len(input))
+	scanner := parser.NewScanner(srcFile, input, nil, parser.ScanComments)
+
+	type bracket struct {
+		tok    token.Token
+		offset int
+	}
+
+	var (
+		stack     []bracket
+		lastClass = classNone
+		lastStart = 0
+	)
+	for {
+		tok, _, pos := scanner.Scan()
+		offset := srcFile.Offset(pos)
+		// each class spans until the start of the next token
+		for i := lastStart; i < offset && i < len(input); i++ {
+			h.classes[i] = lastClass
+		}
+		if tok == token.EOF {
+			break
+		}
+		lastStart, lastClass = offset, classOf(tok)
+
+		if offset >= len(input) {
+			continue
+		}
+		switch ch := input[offset]; {
+		case ch == '(' && tok == token.LParen,
+			ch == '[' && tok == token.LBrack,
+			ch == '{' && tok == token.LBrace:
+			stack = append(stack, bracket{tok: tok, offset: offset})
+		case ch == ')' && tok == token.RParen,
+			ch == ']' && tok == token.RBrack,
+			ch == '}' && tok == token.RBrace:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if top.tok+token.RParen-token.LParen == tok {
+				h.brackets[top.offset] = offset
+				h.brackets[offset] = top.offset
+			}
+		}
+	}
+
+	return h
+}
+
+func classOf(tok token.Token) tokenClass {
+	switch {
+	case tok.IsKeyword():
+		return classKeyword
+	case tok == token.Int || tok == token.Float:
+		return classNumber
+	case tok == token.Char || tok == token.StringFragment || tok == token.DoubleQuote ||
+		tok == token.Backtick || tok == token.DoubleSingleQuote:
+		return classString
+	case tok == token.Comment:
+		return classComment
+	case tok == token.Illegal:
+		return classIllegal
+	}
+	return classNone
+}
@@ -0,0 +1,84 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// historySize is the maximum number of entries kept in the history file.
+const historySize = 1000
+
+// DefaultHistoryFile returns the default location of the history file,
+// which is $XDG_STATE_HOME/toy/history, or ~/.local/state/toy/history
+// if XDG_STATE_HOME is not set.
+func DefaultHistoryFile() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "toy", "history"), nil
+}
+
+// readHistory reads the entries of the history file.
+// Each line of the file is a quoted entry, since entries may span several lines.
+// If the file contains more than historySize entries, it is truncated.
+func readHistory(name string) ([]string, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		entry, err := strconv.Unquote(sc.Text())
+		if err != nil {
+			// skip malformed lines
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) > historySize {
+		entries = entries[len(entries)-historySize:]
+		var b strings.Builder
+		for _, entry := range entries {
+			b.WriteString(strconv.Quote(entry))
+			b.WriteByte('\n')
+		}
+		if err := os.WriteFile(name, []byte(b.String()), 0o600); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// appendHistory appends the entry to the history file.
+func appendHistory(name, entry string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(strconv.Quote(entry) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,89 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "toy", "history")
+
+	entries, err := readHistory(name)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	want := []string{`x := 1`, "f := fn() {\n\treturn \"a\"\n}", `:load "a b.toy"`}
+	for _, entry := range want {
+		require.NoError(t, appendHistory(name, entry))
+	}
+	entries, err = readHistory(name)
+	require.NoError(t, err)
+	require.Equal(t, want, entries)
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, 3, strings.Count(string(data), "\n"), "entries must take one line each")
+
+	fi, err := os.Stat(name)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+}
+
+func TestHistoryMalformed(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history")
+	data := strconv.Quote("a") + "\nnot quoted\n" + `"unterminated` + "\n" + strconv.Quote("b") + "\n"
+	require.NoError(t, os.WriteFile(name, []byte(data), 0o600))
+
+	entries, err := readHistory(name)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, entries)
+}
+
+func TestHistoryTruncate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "history")
+	var b strings.Builder
+	for i := range historySize + 10 {
+		b.WriteString(strconv.Quote(strconv.Itoa(i)))
+		b.WriteByte('\n')
+	}
+	require.NoError(t, os.WriteFile(name, []byte(b.String()), 0o600))
+
+	entries, err := readHistory(name)
+	require.NoError(t, err)
+	require.Len(t, entries, historySize)
+	require.Equal(t, "10", entries[0])
+	require.Equal(t, strconv.Itoa(historySize+9), entries[len(entries)-1])
+
+	// the file is truncated as well
+	entries, err = readHistory(name)
+	require.NoError(t, err)
+	require.Len(t, entries, historySize)
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	require.Equal(t, historySize, strings.Count(string(data), "\n"))
+
+	// new entries are appended after the kept ones
+	require.NoError(t, appendHistory(name, "last"))
+	entries, err = readHistory(name)
+	require.NoError(t, err)
+	require.Equal(t, "11", entries[0])
+	require.Equal(t, "last", entries[len(entries)-1])
+}
+
+func TestDefaultHistoryFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	name, err := DefaultHistoryFile()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("/state", "toy", "history"), name)
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/user")
+	name, err = DefaultHistoryFile()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("/home/user", ".local", "state", "toy", "history"), name)
+}
@@ -1,193 +1,66 @@
-package main
+package repl
 
 import (
 	"bytes"
-	"io"
+	"fmt"
 	"slices"
 	"strings"
 	"unicode"
 
-	"github.com/infastin/toy"
-	"github.com/infastin/toy/ast"
-	"github.com/infastin/toy/parser"
-	"github.com/infastin/toy/stdlib"
-	"github.com/infastin/toy/token"
-
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-)
-
-type compiler struct {
-	symbolTable *toy.SymbolTable
-	globals     []toy.Value
-	modules     toy.ModuleMap
-	constants   []toy.Value
-	output      []string
-}
-
-func newCompiler() *compiler {
-	s := new(compiler)
-
-	replPrintFunc := func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
-		if len(args) == 1 && args[0] == toy.Nil {
-			return toy.Nil, nil
-		}
-		var b strings.Builder
-		for i, arg := range args {
-			if i != 0 {
-				b.WriteByte(' ')
-			}
-			b.WriteString(arg.String())
-		}
-		if b.Len() != 0 {
-			s.output = append(s.output, b.String())
-		}
-		return toy.Nil, nil
-	}
-
-	printFunc := func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
-		var b strings.Builder
-		for i, arg := range args {
-			if i != 0 {
-				b.WriteByte(' ')
-			}
-			b.WriteString(toy.AsString(arg))
-		}
-		if b.Len() != 0 {
-			s.output = append(s.output, b.String())
-		}
-		return toy.Nil, nil
-	}
-
-	printfFunc := func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
-		var (
-			format string
-			rest   []toy.Value
-		)
-		if err := toy.UnpackArgs(args, "format", &format, "...", &rest); err != nil {
-			return nil, err
-		}
-		str, err := toy.Format(format, rest...)
-		if err != nil {
-			return nil, err
-		}
-		if len(str) != 0 {
-			s.output = append(s.output, str)
-		}
-		return toy.Nil, nil
-	}
-
-	toy.Universe = append(toy.Universe,
-		toy.NewVariable("__replPrint__", toy.NewBuiltinFunction("__replPrint__", replPrintFunc)),
-		toy.NewVariable("print", toy.NewBuiltinFunction("print", printFunc)),
-		toy.NewVariable("printf", toy.NewBuiltinFunction("printf", printfFunc)),
-	)
-
-	s.globals = make([]toy.Value, toy.GlobalsSize)
-
-	s.symbolTable = toy.NewSymbolTable()
-	for i, v := range toy.Universe {
-		s.symbolTable.DefineBuiltin(i, v.Name())
-	}
-
-	s.modules = stdlib.StdLib.Copy()
-	s.modules.Remove("fmt")
-
-	return s
-}
-
-func (s *compiler) compileAndRun(input []byte) (string, error) {
-	fileSet := token.NewFileSet()
-	srcFile := fileSet.AddFile("(repl)", -1, len(input))
-
-	p := parser.NewParser(srcFile, input, nil)
-	file, err := p.ParseFile()
-	if err != nil {
-		return "", err
-	}
-
-	file = addPrints(file)
-	symbolTable := s.symbolTable.Copy()
 
-	c := toy.NewCompiler(srcFile, symbolTable, s.constants, s.modules, nil)
-	if err := c.Compile(file); err != nil {
-		return "", err
-	}
-
-	bytecode := c.Bytecode()
-	bytecode.RemoveDuplicates()
-
-	rt := toy.NewRuntime(bytecode, s.globals)
-	if err := rt.Run(); err != nil {
-		return "", err
-	}
-
-	s.constants = bytecode.Constants
-	s.symbolTable = symbolTable
-
-	output := strings.Join(s.output, "\n")
-	s.output = s.output[:0]
-
-	return output, nil
-}
-
-func addPrints(file *ast.File) *ast.File {
-	var stmts []ast.Stmt
-	for _, s := range file.Stmts {
-		switch s := s.(type) {
-		case *ast.ExprStmt:
-			stmts = append(stmts, &ast.ExprStmt{
-				Expr: &ast.CallExpr{
-					Func: &ast.Ident{Name: "__replPrint__"},
-					Args: []ast.Expr{s.Expr},
-				},
-			})
-		case *ast.AssignStmt:
-			stmts = append(stmts, s, &ast.ExprStmt{
-				Expr: &ast.CallExpr{
-					Func: &ast.Ident{
-						Name: "__replPrint__",
-					},
-					Args: s.LHS,
-				},
-			})
-		default:
-			stmts = append(stmts, s)
-		}
-	}
-	return &ast.File{
-		InputFile: file.InputFile,
-		Stmts:     stmts,
-	}
-}
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
 
+// model is the bubbletea model of the REPL implementing line editing.
 type model struct {
 	input         [][]rune
 	line          int
 	col           int
-	compiler      *compiler
+	repl          *REPL
 	quitting      bool
 	err           error
 	history       [][][]rune
 	uncommited    [][][]rune
 	uncommitedIdx int
-	textStyle     lipgloss.Style
-	cursorStyle   lipgloss.Style
+	completions   []string
+	search        *search
+	styles        map[tokenClass]lipgloss.Style
 }
 
-func newModel() model {
-	return model{
+// search represents the state of the reverse incremental search in the history.
+type search struct {
+	query  []rune
+	index  int      // index of the matching history entry
+	input  [][]rune // input before the search
+	failed bool
+}
+
+func newModel(r *REPL, history []string) *model {
+	entries := make([][][]rune, len(history))
+	for i, entry := range history {
+		entries[i] = splitLines(entry)
+	}
+	return &model{
 		input:         make([][]rune, 1),
 		line:          0,
 		col:           0,
 		quitting:      false,
-		compiler:      newCompiler(),
+		repl:          r,
 		err:           nil,
-		history:       nil,
-		uncommited:    make([][][]rune, 1),
-		uncommitedIdx: 0,
-		textStyle:     lipgloss.NewStyle().Inline(true),
-		cursorStyle:   lipgloss.NewStyle().Inline(true).Reverse(true),
+		history:       entries,
+		uncommited:    make([][][]rune, len(entries)+1),
+		uncommitedIdx: len(entries),
+		styles: map[tokenClass]lipgloss.Style{
+			classNone:    lipgloss.NewStyle().Inline(true),
+			classKeyword: lipgloss.NewStyle().Inline(true).Foreground(lipgloss.Color("5")),
+			classString:  lipgloss.NewStyle().Inline(true).Foreground(lipgloss.Color("2")),
+			classNumber:  lipgloss.NewStyle().Inline(true).Foreground(lipgloss.Color("3")),
+			classComment: lipgloss.NewStyle().Inline(true).Faint(true),
+			classIllegal: lipgloss.NewStyle().Inline(true).Foreground(lipgloss.Color("1")),
+		},
 	}
 }
 
@@ -227,6 +100,73 @@ func (m *model) downHistory() {
 	}
 }
 
+// startSearch starts the reverse incremental search in the history.
+func (m *model) startSearch() {
+	m.search = &search{
+		index: len(m.history),
+		input: m.input,
+	}
+}
+
+// searchFrom looks for the most recent history entry
+// before the given index containing the query,
+// and replaces the input with it.
+func (m *model) searchFrom(index int) {
+	if len(m.search.query) == 0 {
+		m.search.index = len(m.history)
+		m.search.failed = false
+		m.setInput(m.search.input)
+		return
+	}
+	query := string(m.search.query)
+	for i := index - 1; i >= 0; i-- {
+		if strings.Contains(joinLines(m.history[i]), query) {
+			m.search.index = i
+			m.search.failed = false
+			m.setInput(cloneInput(m.history[i]))
+			return
+		}
+	}
+	m.search.failed = true
+}
+
+// cancelSearch stops the search and restores the input.
+func (m *model) cancelSearch() {
+	m.setInput(m.search.input)
+	m.search = nil
+}
+
+// updateSearch handles the key pressed during the search.
+// It reports whether the key has been handled.
+func (m *model) updateSearch(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "ctrl+r":
+		m.searchFrom(m.search.index)
+	case "backspace", "ctrl+h":
+		if len(m.search.query) != 0 {
+			m.search.query = m.search.query[:len(m.search.query)-1]
+		}
+		m.searchFrom(len(m.history))
+	case "ctrl+g", "ctrl+c", "esc":
+		m.cancelSearch()
+	default:
+		if msg.Type != tea.KeyRunes {
+			// accept the found entry
+			m.search = nil
+			return false
+		}
+		m.search.query = append(m.search.query, msg.Runes...)
+		m.searchFrom(min(m.search.index+1, len(m.history)))
+	}
+	return true
+}
+
+func (m *model) setInput(input [][]rune) {
+	m.input = input
+	m.line = len(m.input) - 1
+	m.col = len(m.input[m.line])
+}
+
 func (m *model) prevLineOrUpHistory() {
 	if m.line > 0 {
 		m.line--
@@ -454,19 +394,26 @@ func (m *model) onEnter() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	nl, err := checkNewLine(input)
-	if err != nil {
-		m.err = err
-		return m, nil
-	}
-	if nl {
-		m.newLine()
-		return m, nil
+	if input[0] != ':' {
+		nl, err := checkNewLine(input)
+		if err != nil {
+			m.err = err
+			return m, nil
+		}
+		if nl {
+			m.newLine()
+			return m, nil
+		}
 	}
 
-	output, err := m.compiler.compileAndRun(input)
+	entry := string(input)
+	output, err := m.repl.eval(entry)
 	if err != nil {
 		m.err = err
+		if m.repl.quitting {
+			m.quitting = true
+			return m, tea.Quit
+		}
 		return m, nil
 	}
 
@@ -476,6 +423,11 @@ func (m *model) onEnter() (tea.Model, tea.Cmd) {
 	}
 
 	m.history = append(m.history, m.input)
+	if m.repl.history != "" {
+		if err := appendHistory(m.repl.history, entry); err != nil {
+			m.err = err
+		}
+	}
 	clear(m.uncommited)
 	m.uncommited = append(m.uncommited, nil)
 	m.uncommitedIdx = len(m.uncommited) - 1
@@ -484,6 +436,11 @@ func (m *model) onEnter() (tea.Model, tea.Cmd) {
 	m.line = 0
 	m.col = 0
 
+	if m.repl.quitting {
+		m.quitting = true
+		cmds = append(cmds, tea.Quit)
+	}
+
 	return m, tea.Sequence(cmds...)
 }
 
@@ -553,16 +510,23 @@ func (m *model) handleUserInput(runes []rune) {
 }
 
 func (m *model) Init() tea.Cmd {
-	return tea.Printf("Toy Language %s (%s)", version, compilationDate)
+	if m.repl.banner == "" {
+		return nil
+	}
+	return tea.Println(m.repl.banner)
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.err != nil {
 		m.err = nil
 	}
+	m.completions = nil
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.search != nil && m.updateSearch(msg) {
+			break
+		}
 		switch msg.String() {
 		case "ctrl+q":
 			m.quitting = true
@@ -586,6 +550,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.upHistory()
 		case "ctrl+n":
 			m.downHistory()
+		case "ctrl+r":
+			m.startSearch()
 		case "left":
 			m.charForward()
 		case "right", "ctrl+f":
@@ -613,7 +579,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "enter":
 			return m.onEnter()
 		case "tab":
-			m.handleUserInput([]rune{' ', ' '})
+			m.complete()
 		default:
 			m.handleUserInput(msg.Runes)
 		}
@@ -622,32 +588,50 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) view(persist bool) string {
-	if persist || m.quitting {
-		cursorStyle := m.cursorStyle
-		m.cursorStyle = m.textStyle
-		defer func() { m.cursorStyle = cursorStyle }()
+	showCursor := !persist && !m.quitting
+
+	input := m.source()
+	h := highlight(input)
+
+	// highlight the bracket at or before the cursor and its match
+	matched := make(map[int]bool)
+	if showCursor {
+		cursor := m.cursorOffset()
+		for _, offset := range []int{cursor, cursor - 1} {
+			if other, ok := h.brackets[offset]; ok {
+				matched[offset], matched[other] = true, true
+				break
+			}
+		}
 	}
+
 	var b strings.Builder
+	var offset int
 	for i, line := range m.input {
 		if i == 0 {
 			b.WriteString(">>> ")
 		} else {
 			b.WriteString("\n... ")
 		}
-		if m.line != i {
-			b.WriteString(m.textStyle.Render(string(line)))
-			continue
-		}
-		b.WriteString(m.textStyle.Render(string(line[:m.col])))
-		if m.col < len(line) {
-			b.WriteString(m.cursorStyle.Render(string(line[m.col])))
-			b.WriteString(m.textStyle.Render(string(line[m.col+1:])))
-		} else {
-			b.WriteString(m.cursorStyle.Render(" "))
+		col := -1
+		if showCursor && m.line == i {
+			col = m.col
 		}
+		m.renderLine(&b, line, offset, col, h, matched)
+		offset += len(string(line)) + 1
 	}
 	if !persist {
 		b.WriteByte('\n')
+		if m.search != nil {
+			if m.search.failed {
+				b.WriteString("failed ")
+			}
+			fmt.Fprintf(&b, "reverse-i-search: %s\n", string(m.search.query))
+		}
+		if len(m.completions) != 0 {
+			b.WriteString(strings.Join(m.completions, "  "))
+			b.WriteByte('\n')
+		}
 		if m.err != nil {
 			b.WriteString(m.err.Error())
 			b.WriteByte('\n')
@@ -656,14 +640,133 @@ func (m *model) view(persist bool) string {
 	return b.String()
 }
 
-func (m *model) View() string {
-	return m.view(false)
+// renderLine renders the line starting at the given offset of the input
+// with the cursor at the given column, or without the cursor if col is -1.
+func (m *model) renderLine(
+	b *strings.Builder,
+	line []rune,
+	offset, col int,
+	h *highlighting,
+	matched map[int]bool,
+) {
+	type runeStyle struct {
+		class         tokenClass
+		cursor, match bool
+	}
+
+	var (
+		run      []rune
+		runStyle runeStyle
+	)
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		style := m.styles[runStyle.class]
+		if runStyle.match {
+			style = style.Bold(true).Underline(true)
+		}
+		if runStyle.cursor {
+			style = style.Reverse(true)
+		}
+		b.WriteString(style.Render(string(run)))
+		run = run[:0]
+	}
+
+	for j, r := range line {
+		rs := runeStyle{
+			class:  h.classes[offset],
+			cursor: j == col,
+			match:  matched[offset],
+		}
+		if rs != runStyle {
+			flush()
+			runStyle = rs
+		}
+		run = append(run, r)
+		offset += len(string(r))
+	}
+	if col == len(line) {
+		flush()
+		runStyle = runeStyle{cursor: true}
+		run = append(run, ' ')
+	}
+	flush()
+}
+
+func splitLines(s string) [][]rune {
+	lines := strings.Split(s, "\n")
+	input := make([][]rune, len(lines))
+	for i, line := range lines {
+		input[i] = []rune(line)
+	}
+	return input
+}
+
+func joinLines(input [][]rune) string {
+	lines := make([]string, len(input))
+	for i, line := range input {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
 }
 
-func (m *model) Run(in io.Reader, out io.Writer) error {
-	p := tea.NewProgram(m, tea.WithInput(in), tea.WithOutput(out))
-	if _, err := p.Run(); err != nil {
-		return err
+func cloneInput(input [][]rune) [][]rune {
+	input = slices.Clone(input)
+	for i := range input {
+		input[i] = slices.Clone(input[i])
 	}
-	return nil
+	return input
+}
+
+// source returns the input as a byte slice.
+func (m *model) source() []byte {
+	var buf bytes.Buffer
+	for i, line := range m.input {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(string(line))
+	}
+	return buf.Bytes()
+}
+
+// cursorOffset returns the byte offset of the cursor in the input.
+func (m *model) cursorOffset() int {
+	var offset int
+	for _, line := range m.input[:m.line] {
+		offset += len(string(line)) + 1
+	}
+	return offset + len(string(m.input[m.line][:m.col]))
+}
+
+// complete completes the word before the cursor.
+// If there's no word to complete, it inserts an indentation.
+func (m *model) complete() {
+	before := string(m.input[m.line][:m.col])
+	if lastWord(before) == "" && !strings.HasSuffix(before, ".") {
+		m.handleUserInput([]rune{' ', ' '})
+		return
+	}
+	completions, wordLen := m.repl.complete(before)
+	if len(completions) == 0 {
+		return
+	}
+	prefix := completions[0]
+	for _, c := range completions[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > wordLen {
+		m.handleUserInput([]rune(prefix[wordLen:]))
+		return
+	}
+	if len(completions) > 1 {
+		m.completions = completions
+	}
+}
+
+func (m *model) View() string {
+	return m.view(false)
 }
@@ -0,0 +1,191 @@
+// Package repl implements an interactive Read-Eval-Print Loop for Toy,
+// which can be embedded into other applications.
+//
+//	s := repl.NewSession()
+//	s.SetModules(stdlib.StdLib)
+//	s.Add("config", config)
+//	r := repl.New(s)
+//	if err := r.Run(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// Sessions can also be served to remote clients with Server,
+// e.g. to inspect a running application with "toy attach".
+package repl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Command represents a REPL command, which is invoked
+// by entering its name prefixed with a colon, e.g. ":help".
+type Command struct {
+	Name  string // name of the command
+	Args  string // description of the arguments, e.g. "FILE"; or empty
+	Usage string // short description of the command
+	// Run runs the command with the arguments
+	// entered after the name of the command,
+	// and returns the output to be printed.
+	Run func(r *REPL, args string) (string, error)
+}
+
+// REPL represents an interactive Read-Eval-Print Loop
+// running in a terminal.
+type REPL struct {
+	session  *Session
+	remote   *Client
+	ctx      context.Context
+	commands []*Command
+	banner   string
+	history  string
+	in       io.Reader
+	out      io.Writer
+	quitting bool
+}
+
+// New creates a new REPL evaluating the input in the given session.
+// If s is nil, a new session without modules is used.
+func New(s *Session) *REPL {
+	if s == nil {
+		s = NewSession()
+	}
+	return &REPL{
+		session:  s,
+		commands: builtinCommands(),
+	}
+}
+
+// NewRemote creates a new REPL evaluating the input by the server
+// the client is connected to. Commands are run by the server as well.
+func NewRemote(c *Client) *REPL {
+	return &REPL{remote: c}
+}
+
+// Session returns the session of the REPL,
+// or nil if the REPL is remote.
+func (r *REPL) Session() *Session {
+	return r.session
+}
+
+// SetBanner sets the text printed when the REPL starts.
+func (r *REPL) SetBanner(banner string) {
+	r.banner = banner
+}
+
+// SetHistoryFile sets the file the entered input is saved to,
+// so it can be recalled in the following sessions.
+// If name is empty (the default), the history is not saved.
+// See DefaultHistoryFile.
+func (r *REPL) SetHistoryFile(name string) {
+	r.history = name
+}
+
+// SetInput sets the input of the REPL.
+// If in is nil (the default), os.Stdin is used.
+func (r *REPL) SetInput(in io.Reader) {
+	r.in = in
+}
+
+// SetOutput sets the output of the REPL.
+// If out is nil (the default), os.Stdout is used.
+func (r *REPL) SetOutput(out io.Writer) {
+	r.out = out
+}
+
+// AddCommand adds a new command or replaces the command with the same name.
+func (r *REPL) AddCommand(cmd *Command) {
+	i := slices.IndexFunc(r.commands, func(c *Command) bool {
+		return c.Name == cmd.Name
+	})
+	if i != -1 {
+		r.commands[i] = cmd
+		return
+	}
+	r.commands = append(r.commands, cmd)
+}
+
+// Command returns the command with the given name, or nil.
+func (r *REPL) Command(name string) *Command {
+	i := slices.IndexFunc(r.commands, func(c *Command) bool {
+		return c.Name == name
+	})
+	if i == -1 {
+		return nil
+	}
+	return r.commands[i]
+}
+
+// Quit makes the REPL exit after the current input has been handled.
+func (r *REPL) Quit() {
+	r.quitting = true
+}
+
+// Run runs the REPL until the user quits.
+func (r *REPL) Run() error {
+	in, out := r.in, r.out
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	var history []string
+	if r.history != "" {
+		var err error
+		if history, err = readHistory(r.history); err != nil {
+			return err
+		}
+	}
+	r.quitting = false
+	p := tea.NewProgram(newModel(r, history), tea.WithInput(in), tea.WithOutput(out))
+	if _, err := p.Run(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// eval evaluates the input, which is either a command or code.
+func (r *REPL) eval(input string) (string, error) {
+	if r.remote != nil {
+		output, err := r.remote.Eval(input)
+		if r.remote.done {
+			r.quitting = true
+		}
+		return output, err
+	}
+	if line, ok := strings.CutPrefix(input, ":"); ok {
+		name, args, _ := strings.Cut(line, " ")
+		cmd := r.Command(name)
+		if cmd == nil {
+			return "", fmt.Errorf("unknown command ':%s', enter :help for the list of commands", name)
+		}
+		return cmd.Run(r, strings.TrimSpace(args))
+	}
+	return r.session.Eval(r.context(), []byte(input))
+}
+
+// complete returns the completions of the word before the end of the input.
+func (r *REPL) complete(input string) ([]string, int) {
+	if r.remote != nil {
+		completions, wordLen, err := r.remote.Complete(input)
+		if err != nil {
+			return nil, 0
+		}
+		return completions, wordLen
+	}
+	return r.session.Complete(input)
+}
+
+func (r *REPL) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
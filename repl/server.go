@@ -0,0 +1,173 @@
+package repl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"sync"
+)
+
+// Server serves REPL sessions to remote clients, e.g. "toy attach",
+// which allows to inspect and modify the state of a running application.
+//
+// Each connection gets its own session created by NewSession.
+// Commands, such as :load and :save, are run by the server,
+// so files are read and written on the host of the server.
+//
+//	srv := &repl.Server{
+//		NewSession: func(io.ReadWriter) (*repl.Session, error) {
+//			return repl.NewCompiledSession(compiled), nil
+//		},
+//	}
+//	go srv.ListenAndServe(ctx, "/run/app/repl.sock")
+type Server struct {
+	// NewSession creates the session for a new connection.
+	// The connection is closed if it returns an error,
+	// which allows to reject unwanted clients.
+	NewSession func(conn io.ReadWriter) (*Session, error)
+	// Authorize is called before evaluating or completing each input,
+	// including commands. The input is rejected if it returns an error.
+	// If nil, all the input is allowed.
+	Authorize func(s *Session, input string) error
+	// Commands are the commands added to each session
+	// besides the builtin ones.
+	Commands []*Command
+}
+
+// request is a message sent by the client.
+type request struct {
+	Op    string `json:"op"` // "eval" or "complete"
+	Input string `json:"input"`
+}
+
+// response is a message sent by the server.
+type response struct {
+	Output      string   `json:"output,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Quit        bool     `json:"quit,omitempty"`
+	Completions []string `json:"completions,omitempty"`
+	WordLen     int      `json:"wordLen,omitempty"`
+}
+
+// ListenAndServe listens on the Unix socket and serves the connections
+// until the context is canceled. A stale socket file is removed,
+// while any other file at the path is left intact.
+func (srv *Server) ListenAndServe(ctx context.Context, path string) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(ctx, l)
+}
+
+// Serve accepts the connections on the listener and serves them
+// until the context is canceled. The listener is closed when Serve returns.
+func (srv *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			// unblock reading when the server is stopped
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			srv.ServeConn(ctx, conn)
+		}()
+	}
+}
+
+// ServeConn serves a single session over the connection
+// until the client quits or disconnects.
+func (srv *Server) ServeConn(ctx context.Context, conn io.ReadWriter) error {
+	if srv.NewSession == nil {
+		return errors.New("NewSession is not set")
+	}
+
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	s, err := srv.NewSession(conn)
+	if err != nil {
+		enc.Encode(&response{Error: err.Error(), Quit: true})
+		return err
+	}
+	r := New(s)
+	for _, cmd := range srv.Commands {
+		r.AddCommand(cmd)
+	}
+
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		resp := srv.handle(ctx, r, &req)
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+		if resp.Quit {
+			return nil
+		}
+	}
+}
+
+func (srv *Server) handle(ctx context.Context, r *REPL, req *request) *response {
+	switch req.Op {
+	case "eval":
+		if err := srv.authorize(r.session, req.Input); err != nil {
+			return &response{Error: err.Error()}
+		}
+		r.ctx = ctx
+		output, err := r.eval(req.Input)
+		resp := &response{Output: output, Quit: r.quitting}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		return resp
+	case "complete":
+		if err := srv.authorize(r.session, req.Input); err != nil {
+			return &response{Error: err.Error()}
+		}
+		completions, wordLen := r.session.Complete(req.Input)
+		return &response{Completions: completions, WordLen: wordLen}
+	default:
+		return &response{Error: "unknown operation '" + req.Op + "'"}
+	}
+}
+
+func (srv *Server) authorize(s *Session, input string) error {
+	if srv.Authorize == nil {
+		return nil
+	}
+	return srv.Authorize(s, input)
+}
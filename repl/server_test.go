@@ -0,0 +1,83 @@
+package repl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/infastin/toy/repl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	path := filepath.Join(t.TempDir(), "repl.sock")
+
+	srv := &repl.Server{
+		NewSession: func(io.ReadWriter) (*repl.Session, error) {
+			return repl.NewSession(), nil
+		},
+		Authorize: func(_ *repl.Session, input string) error {
+			if strings.Contains(input, "secret") {
+				return errors.New("forbidden")
+			}
+			return nil
+		},
+	}
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, path) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	c, err := repl.Dial(path)
+	require.NoError(t, err)
+	defer c.Close()
+
+	output, err := c.Eval(`value := 1; value + 1`)
+	require.NoError(t, err)
+	require.Equal(t, "1\n2", output)
+
+	completions, wordLen, err := c.Complete(`val`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"value"}, completions)
+	require.Equal(t, 3, wordLen)
+
+	_, err = c.Eval(`secret := 1`)
+	require.EqualError(t, err, "forbidden")
+	_, _, err = c.Complete(`secret`)
+	require.EqualError(t, err, "forbidden")
+
+	_, err = c.Eval(`undefined`)
+	require.Error(t, err)
+
+	_, err = c.Eval(`:quit`)
+	require.NoError(t, err)
+	_, err = c.Eval(`value`)
+	require.Error(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestServerKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+
+	srv := &repl.Server{
+		NewSession: func(io.ReadWriter) (*repl.Session, error) {
+			return repl.NewSession(), nil
+		},
+	}
+	require.Error(t, srv.ListenAndServe(context.Background(), path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+}
@@ -0,0 +1,483 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/ast"
+	"github.com/infastin/toy/parser"
+	"github.com/infastin/toy/token"
+)
+
+// Session holds the state of a REPL session:
+// the variables defined by the evaluated code, the compiled constants
+// and the modules available for import.
+//
+// Besides the builtins of toy.Universe, sessions define
+// "print" and "printf" functions that write their arguments
+// to the output of the session followed by a newline.
+//
+// Each input is run by a new runtime, which can be configured
+// with SetRuntimeHook, e.g. to set limits or a policy.
+type Session struct {
+	symbolTable *toy.SymbolTable
+	globals     []toy.Value
+	constants   []toy.Value
+	modules     toy.ModuleGetter
+	output      bytes.Buffer
+	added       []namedValue      // variables added by the host
+	docs        map[string]string // documentation of variables added by the host
+	source      []string          // successfully evaluated inputs
+	result      toy.Value         // value of the last evaluated expression
+	compiled    *toy.Compiled     // script the session is attached to, if any
+	shared      int               // number of globals shared with the script
+	runtimeHook func(r *toy.Runtime)
+}
+
+type namedValue struct {
+	name  string
+	value toy.Value
+}
+
+// NewSession creates a new session without modules.
+func NewSession() *Session {
+	return newSession(nil)
+}
+
+// NewCompiledSession creates a new session without modules
+// attached to the compiled script, which allows to inspect
+// and modify the global variables of the script while it's used by the host.
+//
+// The values of the global variables are copied to the session
+// before running each input and copied back after it's run,
+// while the compiled script is locked.
+// Variables defined by the session are not visible to the script.
+func NewCompiledSession(c *toy.Compiled) *Session {
+	return newSession(c)
+}
+
+func newSession(c *toy.Compiled) *Session {
+	s := &Session{
+		modules:  make(toy.ModuleMap),
+		docs:     make(map[string]string),
+		compiled: c,
+	}
+	s.init()
+	s.Add(replPrintName, toy.NewBuiltinFunction(replPrintName, replPrintFunc))
+	s.Add(replResultName, toy.NewBuiltinFunction(replResultName, s.replResultFunc))
+	s.Add("print", toy.NewBuiltinFunction("print", printFunc))
+	s.Add("printf", toy.NewBuiltinFunction("printf", printfFunc))
+	return s
+}
+
+func (s *Session) init() {
+	s.globals = make([]toy.Value, toy.GlobalsSize)
+	s.constants = nil
+	s.source = nil
+	if s.compiled != nil {
+		s.symbolTable = s.compiled.SymbolTable()
+		s.shared = s.symbolTable.MaxSymbols()
+		// functions of the script refer to its constants by index,
+		// so the constants of the session must start with them
+		s.constants = slices.Clip(s.compiled.Bytecode().Constants)
+		return
+	}
+	s.symbolTable = toy.NewSymbolTable()
+	for i, v := range toy.Universe {
+		s.symbolTable.DefineBuiltin(i, v.Name())
+	}
+}
+
+// Reset discards the variables defined by the evaluated code,
+// leaving only the ones added with Add.
+func (s *Session) Reset() {
+	s.init()
+	for _, v := range s.added {
+		s.define(v.name, v.value)
+	}
+}
+
+// SetModules sets the modules available for import.
+func (s *Session) SetModules(modules toy.ModuleGetter) {
+	if modules == nil {
+		modules = make(toy.ModuleMap)
+	}
+	s.modules = modules
+}
+
+// Add defines a new global variable or updates an existing one.
+func (s *Session) Add(name string, value toy.Value) {
+	i := slices.IndexFunc(s.added, func(v namedValue) bool {
+		return v.name == name
+	})
+	if i != -1 {
+		s.added[i].value = value
+	} else {
+		s.added = append(s.added, namedValue{name: name, value: value})
+	}
+	s.define(name, value)
+}
+
+// SetRuntimeHook sets the function called with the runtime
+// created to run each input before it's run.
+func (s *Session) SetRuntimeHook(hook func(r *toy.Runtime)) {
+	s.runtimeHook = hook
+}
+
+// SetDoc sets the documentation of the variable shown by the :doc command.
+func (s *Session) SetDoc(name, doc string) {
+	s.docs[name] = doc
+}
+
+func (s *Session) define(name string, value toy.Value) {
+	symbol, _, ok := s.symbolTable.Resolve(name, false)
+	if !ok || symbol.Scope != toy.ScopeGlobal {
+		symbol = s.symbolTable.Define(name)
+	}
+	s.globals[symbol.Index] = value
+}
+
+// Get returns the value of the global variable,
+// or nil if there's no such variable.
+func (s *Session) Get(name string) toy.Value {
+	symbol, _, ok := s.symbolTable.Resolve(name, false)
+	if !ok || symbol.Scope != toy.ScopeGlobal {
+		return nil
+	}
+	if s.compiled != nil {
+		// the script may have changed the value since the last input
+		s.compiled.UpdateGlobals(func(globals []toy.Value) error {
+			if symbol.Index < s.shared {
+				s.globals[symbol.Index] = globals[symbol.Index]
+			}
+			return nil
+		})
+	}
+	return s.globals[symbol.Index]
+}
+
+// Globals returns the names of the global variables
+// in the order of their definition.
+func (s *Session) Globals() []string {
+	var symbols []*toy.Symbol
+	for _, name := range s.symbolTable.Names() {
+		if name == replPrintName || name == replResultName {
+			continue
+		}
+		symbol, _, ok := s.symbolTable.Resolve(name, false)
+		if ok && symbol.Scope == toy.ScopeGlobal {
+			symbols = append(symbols, symbol)
+		}
+	}
+	slices.SortFunc(symbols, func(a, b *toy.Symbol) int {
+		return a.Index - b.Index
+	})
+	names := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		names[i] = symbol.Name
+	}
+	return names
+}
+
+// Source returns the successfully evaluated code, one input per line,
+// which can be saved to a file and run as a script.
+func (s *Session) Source() []byte {
+	var b bytes.Buffer
+	for _, input := range s.source {
+		b.WriteString(input)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// Eval compiles and runs the input, and returns its output.
+// The value of each expression statement
+// and the variables assigned by each assignment are printed.
+// If the input fails to compile, the state of the session is left intact.
+func (s *Session) Eval(ctx context.Context, input []byte) (string, error) {
+	return s.eval(ctx, "(repl)", input, addPrints)
+}
+
+// EvalFile compiles and runs the file in the same way as Eval.
+func (s *Session) EvalFile(ctx context.Context, name string) (string, error) {
+	input, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	if len(input) > 1 && string(input[:2]) == "#!" {
+		copy(input, "//")
+	}
+	return s.eval(ctx, name, input, addPrints)
+}
+
+// EvalExpr evaluates the expression and returns its value.
+// Unlike Eval, it doesn't print the value.
+func (s *Session) EvalExpr(ctx context.Context, input []byte) (toy.Value, error) {
+	s.result = nil
+	if _, err := s.eval(ctx, "(repl)", input, setResult); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+// Bytecode compiles the input without running it
+// and returns its disassembled instructions along with
+// the constants the input adds to the session.
+func (s *Session) Bytecode(input []byte) (string, error) {
+	bytecode, err := s.compile("(repl)", input, nil, s.symbolTable.Copy())
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, line := range bytecode.FormatInstructions() {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	for i := len(s.constants); i < len(bytecode.Constants); i++ {
+		c := bytecode.Constants[i]
+		fmt.Fprintf(&b, "[% 3d] %s (%s)\n", i, c, toy.TypeName(c))
+		if fn, ok := c.(*toy.CompiledFunction); ok {
+			for _, line := range fn.FormatInstructions() {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// Doc returns the documentation of the value the name refers to,
+// which can be a chain of selectors, e.g. "fmt.println".
+// Besides the documentation set with SetDoc, it describes the type
+// of the value and the members of modules and tables.
+func (s *Session) Doc(name string) (string, error) {
+	if lastChain(name) != name {
+		return "", fmt.Errorf("invalid name '%s'", name)
+	}
+	value := s.resolveChain(name)
+	if value == nil {
+		return "", fmt.Errorf("unknown name '%s'", name)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", name, toy.TypeName(value))
+	if doc := s.docs[name]; doc != "" {
+		b.WriteString("\n\n")
+		b.WriteString(doc)
+	}
+	if names := memberNames(value); len(names) != 0 {
+		slices.Sort(names)
+		b.WriteString("\n")
+		var width int
+		for _, name := range names {
+			width = max(width, len(name))
+		}
+		for _, name := range names {
+			fmt.Fprintf(&b, "\n  %-*s  %s", width, name, toy.TypeName(member(value, name)))
+		}
+	}
+	return b.String(), nil
+}
+
+// eval compiles the input transformed by the given function and runs it.
+func (s *Session) eval(
+	ctx context.Context,
+	name string,
+	input []byte,
+	transform func(file *ast.File) (*ast.File, error),
+) (string, error) {
+	symbolTable := s.symbolTable.Copy()
+	bytecode, err := s.compile(name, input, transform, symbolTable)
+	if err != nil {
+		return "", err
+	}
+
+	defer s.output.Reset()
+
+	if err := s.run(ctx, bytecode); err != nil {
+		return "", err
+	}
+
+	s.constants = bytecode.Constants
+	s.symbolTable = symbolTable
+	s.source = append(s.source, strings.TrimSpace(string(input)))
+
+	return strings.TrimSuffix(s.output.String(), "\n"), nil
+}
+
+// run runs the compiled input,
+// synchronizing the globals with the attached script if any.
+func (s *Session) run(ctx context.Context, bytecode *toy.Bytecode) error {
+	rt := toy.NewRuntime(bytecode, s.globals)
+	if s.runtimeHook != nil {
+		s.runtimeHook(rt)
+	}
+	rt.SetContext(ctx)
+	rt.SetStdout(&s.output)
+	rt.SetStderr(&s.output)
+	if s.compiled == nil {
+		return rt.Run()
+	}
+	return s.compiled.UpdateGlobals(func(globals []toy.Value) error {
+		copy(s.globals[:s.shared], globals)
+		defer copy(globals[:s.shared], s.globals)
+		return rt.Run()
+	})
+}
+
+func (s *Session) compile(
+	name string,
+	input []byte,
+	transform func(file *ast.File) (*ast.File, error),
+	symbolTable *toy.SymbolTable,
+) (*toy.Bytecode, error) {
+	fileSet := token.NewFileSet()
+	srcFile := fileSet.AddFile(name, -1, len(input))
+
+	p := parser.NewParser(srcFile, input, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if transform != nil {
+		if file, err = transform(file); err != nil {
+			return nil, err
+		}
+	}
+
+	c := toy.NewCompiler(srcFile, symbolTable, s.constants, s.modules, nil)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	return bytecode, nil
+}
+
+// replPrintName is the name of the function
+// that prints the values of the evaluated statements.
+const replPrintName = "__replPrint__"
+
+// replResultName is the name of the function
+// that stores the value of the expression evaluated by EvalExpr.
+const replResultName = "__replResult__"
+
+func addPrints(file *ast.File) (*ast.File, error) {
+	var stmts []ast.Stmt
+	for _, s := range file.Stmts {
+		switch s := s.(type) {
+		case *ast.ExprStmt:
+			stmts = append(stmts, &ast.ExprStmt{
+				Expr: &ast.CallExpr{
+					Func: &ast.Ident{Name: replPrintName},
+					Args: []ast.Expr{s.Expr},
+				},
+			})
+		case *ast.AssignStmt:
+			stmts = append(stmts, s, &ast.ExprStmt{
+				Expr: &ast.CallExpr{
+					Func: &ast.Ident{
+						Name: replPrintName,
+					},
+					Args: s.LHS,
+				},
+			})
+		default:
+			stmts = append(stmts, s)
+		}
+	}
+	return &ast.File{
+		InputFile: file.InputFile,
+		Stmts:     stmts,
+	}, nil
+}
+
+func setResult(file *ast.File) (*ast.File, error) {
+	if len(file.Stmts) != 1 {
+		return nil, errors.New("expected a single expression")
+	}
+	stmt, ok := file.Stmts[0].(*ast.ExprStmt)
+	if !ok {
+		return nil, errors.New("expected an expression")
+	}
+	return &ast.File{
+		InputFile: file.InputFile,
+		Stmts: []ast.Stmt{
+			&ast.ExprStmt{
+				Expr: &ast.CallExpr{
+					Func: &ast.Ident{Name: replResultName},
+					Args: []ast.Expr{stmt.Expr},
+				},
+			},
+		},
+	}, nil
+}
+
+func (s *Session) replResultFunc(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var value toy.Value
+	if err := toy.UnpackArgs(args, "value", &value); err != nil {
+		return nil, err
+	}
+	s.result = value
+	return toy.Nil, nil
+}
+
+func replPrintFunc(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) == 1 && args[0] == toy.Nil {
+		return toy.Nil, nil
+	}
+	var b strings.Builder
+	for i, arg := range args {
+		if i != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(arg.String())
+	}
+	if b.Len() != 0 {
+		b.WriteByte('\n')
+		io.WriteString(r.Stdout(), b.String())
+	}
+	return toy.Nil, nil
+}
+
+func printFunc(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var b strings.Builder
+	for i, arg := range args {
+		if i != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(toy.AsString(arg))
+	}
+	if b.Len() != 0 {
+		b.WriteByte('\n')
+		io.WriteString(r.Stdout(), b.String())
+	}
+	return toy.Nil, nil
+}
+
+func printfFunc(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var (
+		format string
+		rest   []toy.Value
+	)
+	if err := toy.UnpackArgs(args, "format", &format, "...", &rest); err != nil {
+		return nil, err
+	}
+	str, err := toy.Format(format, rest...)
+	if err != nil {
+		return nil, err
+	}
+	if len(str) != 0 {
+		io.WriteString(r.Stdout(), str+"\n")
+	}
+	return toy.Nil, nil
+}
@@ -0,0 +1,92 @@
+package repl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/repl"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	s := repl.NewSession()
+	s.SetModules(stdlib.StdLib)
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{`x := 40`, `40`},
+		{`x + 2`, `42`},
+		{`f := fn(a) { return "a" + string(a) }`, `<compiled-function f>`},
+		{`f(2)`, `"a2"`},
+		{`print("a", 1)`, "a 1"},
+		{`text := import("text"); text.contains("abc", "b")`, "<module \"text\">\ntrue"},
+	}
+	for _, tt := range tests {
+		output, err := s.Eval(ctx, []byte(tt.input))
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.output, output, tt.input)
+	}
+
+	value, err := s.EvalExpr(ctx, []byte(`[x, f(1)]`))
+	require.NoError(t, err)
+	require.Equal(t, `[40, "a1"]`, toy.AsString(value))
+
+	_, err = s.Eval(ctx, []byte(`y`))
+	require.Error(t, err)
+
+	s.Add("host", toy.Int(1))
+	s.Reset()
+	require.Nil(t, s.Get("x"))
+	require.Equal(t, toy.Int(1), s.Get("host"))
+}
+
+func TestCompiledSession(t *testing.T) {
+	ctx := context.Background()
+	script := toy.NewScript([]byte(`
+counter := 0
+inc := fn() { counter += 1; return "count: " + string(counter) }
+`))
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+	require.NoError(t, compiled.Run())
+
+	s := repl.NewCompiledSession(compiled)
+	output, err := s.Eval(ctx, []byte(`inc()`))
+	require.NoError(t, err)
+	require.Equal(t, `"count: 1"`, output)
+
+	_, err = s.Eval(ctx, []byte(`counter = 10; local := 1`))
+	require.NoError(t, err)
+	require.Equal(t, toy.Int(10), compiled.Get("counter").Value())
+	require.False(t, compiled.IsDefined("local"))
+
+	fn, err := compiled.Func("inc")
+	require.NoError(t, err)
+	res, err := fn.Call()
+	require.NoError(t, err)
+	require.Equal(t, toy.String("count: 11"), res)
+
+	value, err := s.EvalExpr(ctx, []byte(`inc()`))
+	require.NoError(t, err)
+	require.Equal(t, toy.String("count: 12"), value)
+}
+
+func TestCompiledSessionPool(t *testing.T) {
+	ctx := context.Background()
+	script := toy.NewScript([]byte(`get := fn() { return "value" }`))
+	compiled, err := script.Run()
+	require.NoError(t, err)
+
+	pool := toy.NewCompiledPool(compiled)
+	c := pool.Get()
+	defer pool.Put(c)
+
+	value, err := repl.NewCompiledSession(c).EvalExpr(ctx, []byte(`get()`))
+	require.NoError(t, err)
+	require.Equal(t, toy.String("value"), value)
+}
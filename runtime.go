@@ -1,10 +1,13 @@
 package toy
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/infastin/toy/bytecode"
@@ -15,7 +18,7 @@ import (
 type deferredCall struct {
 	fn   Callable
 	args []Value
-	pos  token.Pos
+	src  sourceRange
 }
 
 // frame represents a function call frame.
@@ -26,6 +29,13 @@ type frame struct {
 	basePointer int
 	deferred    []*deferredCall
 	curDefer    *deferredCall
+	handlers    []handler
+}
+
+// handler is an exception handler installed by a try statement.
+type handler struct {
+	ip int // position of the handler code
+	sp int // stack pointer at the beginning of the try block
 }
 
 // Runtime is a virtual machine that executes the bytecode.
@@ -34,6 +44,7 @@ type Runtime struct {
 	stack       []Value
 	sp          int
 	globals     []Value
+	shared      []bool // globals shared with other runtimes, cloned on first access
 	fileSet     *token.FileSet
 	frames      []frame
 	framesIndex int
@@ -41,6 +52,21 @@ type Runtime struct {
 	curInsts    []byte
 	ip          int
 	aborting    *int64
+	limits      Limits
+	usage       *usage
+	frameBase   int   // number of frames preceding the current call stack
+	result      Value // value returned by the main function
+	policy      *Policy
+	fs          *FS
+	env         *Env
+	args        []string
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	ctx         context.Context
+	runCtx      context.Context    // context of the current execution
+	cancel      context.CancelFunc // cancels runCtx
+	mu          sync.Mutex         // protects cancel
 }
 
 // NewRuntime creates a Toy runtime.
@@ -58,6 +84,7 @@ func NewRuntime(bytecode *Bytecode, globals []Value) *Runtime {
 		framesIndex: 1,
 		ip:          -1,
 		aborting:    new(int64),
+		usage:       new(usage),
 	}
 	r.frames[0].fn = bytecode.MainFunction
 	r.frames[0].ip = -1
@@ -66,9 +93,75 @@ func NewRuntime(bytecode *Bytecode, globals []Value) *Runtime {
 	return r
 }
 
+// reset releases values referenced by the runtime
+// and resets its configuration, so that it can be reused.
+func (r *Runtime) reset() {
+	clear(r.stack)
+	clear(r.frames[1:])
+	r.frames[0] = frame{fn: r.frames[0].fn, ip: -1}
+	r.sp = 0
+	r.result = nil
+	r.limits = Limits{}
+	r.policy = nil
+	r.fs = nil
+	r.env = nil
+	r.args = nil
+	r.stdin, r.stdout, r.stderr = nil, nil, nil
+	r.ctx = nil
+}
+
+// SetContext sets the context of the runtime.
+// Builtin functions use it to cancel blocking operations
+// and to access request-scoped values.
+// The execution is aborted when the context is done.
+func (r *Runtime) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// Context returns the context of the runtime.
+// During the execution the returned context is also
+// cancelled when the execution is aborted.
+// If the context is not set, context.Background is returned.
+// It is safe to call Context on a nil Runtime.
+func (r *Runtime) Context() context.Context {
+	if r == nil {
+		return context.Background()
+	}
+	if r.runCtx != nil {
+		return r.runCtx
+	}
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
 // Abort aborts the execution.
 func (r *Runtime) Abort() {
 	atomic.StoreInt64(r.aborting, 1)
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+}
+
+// begin sets up the context of the execution,
+// so that the execution is aborted when the context is done.
+// The returned function must be called after the execution has finished.
+func (r *Runtime) begin() (end func()) {
+	ctx, cancel := context.WithCancel(r.Context())
+	stop := context.AfterFunc(ctx, r.Abort)
+	r.mu.Lock()
+	r.runCtx, r.cancel = ctx, cancel
+	r.mu.Unlock()
+	return func() {
+		stop()
+		r.mu.Lock()
+		r.runCtx, r.cancel = nil, nil
+		r.mu.Unlock()
+		cancel()
+	}
 }
 
 // Run starts the execution.
@@ -76,32 +169,83 @@ func (r *Runtime) Run() error {
 	// reset VM states
 	r.sp = 0
 	r.curFrame = &(r.frames[0])
+	r.curFrame.handlers = r.curFrame.handlers[:0]
 	r.curInsts = r.curFrame.fn.instructions
 	r.framesIndex = 1
+	r.frameBase = 0
 	r.ip = -1
-	_, err := r.run()
+	r.usage = new(usage)
+	r.result = Nil
+	end := r.begin()
+	res, err := r.run()
+	end()
 	atomic.StoreInt64(r.aborting, 0)
 	if err != nil {
-		rErr := r.unwindStack(err).(*runtimeError)
-		var b strings.Builder
-		fmt.Fprintf(&b, "runtime error: %s\n", rErr.Error())
-		b.WriteString("\nstacktrace:")
-		for i, pos := range rErr.Trace {
-			if i != len(rErr.Trace)-1 {
-				b.WriteString("\n├─ ")
-			} else {
-				b.WriteString("\n└─ ")
-			}
-			fmt.Fprintf(&b, "at %s", pos.String())
-		}
-		return errors.New(b.String())
+		return r.unwindStack(err)
+	}
+	if res != nil {
+		r.result = res
 	}
 	return nil
 }
 
-func (r *Runtime) run() (_ Value, err error) {
+// Result returns the value returned by the top-level return statement
+// of the last run, or nil if the script didn't return a value.
+func (r *Runtime) Result() Value {
+	if r.result == nil {
+		return Nil
+	}
+	return r.result
+}
+
+// callTop calls fn with the given arguments at the top level of the runtime,
+// i.e. as if it was called from the main function, which is not executed.
+// Errors are reported the same way Run reports them.
+func (r *Runtime) callTop(fn Value, args ...Value) (Value, error) {
+	callable, ok := fn.(Callable)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not callable", TypeName(fn))
+	}
+	// reset VM states
+	r.sp = 0
+	r.curFrame = &(r.frames[0])
+	r.curFrame.handlers = r.curFrame.handlers[:0]
+	r.curInsts = r.curFrame.fn.instructions
+	r.framesIndex = 1
+	r.frameBase = 0
+	r.ip = -1
+	r.usage = new(usage)
+	end := r.begin()
+	res, err := r.safeCall(callable, args)
+	end()
+	atomic.StoreInt64(r.aborting, 0)
+	if err != nil {
+		if rErr := (*RuntimeError)(nil); errors.As(err, &rErr) {
+			return nil, rErr
+		}
+		return nil, &RuntimeError{Err: err}
+	}
+	return res, nil
+}
+
+func (r *Runtime) run() (Value, error) {
+	for {
+		res, err := r.exec()
+		if err == nil || !r.catch(err) {
+			return res, err
+		}
+	}
+}
+
+// exec executes the instructions until the current run returns or fails.
+func (r *Runtime) exec() (_ Value, err error) {
 	for atomic.LoadInt64(r.aborting) == 0 {
 		r.ip++
+		if r.limits.MaxInstructions > 0 {
+			if r.usage.insts.Add(1) > r.limits.MaxInstructions {
+				return nil, &LimitError{Kind: LimitInstructions, Limit: r.limits.MaxInstructions}
+			}
+		}
 		switch r.curInsts[r.ip] {
 		case bytecode.OpConstant:
 			r.ip += 2
@@ -130,6 +274,10 @@ func (r *Runtime) run() (_ Value, err error) {
 					r.sp -= 2
 					return nil, err
 				}
+				if err := r.track(res); err != nil {
+					r.sp -= 2
+					return nil, err
+				}
 			}
 
 			r.stack[r.sp-2] = res
@@ -203,9 +351,16 @@ func (r *Runtime) run() (_ Value, err error) {
 			r.sp--
 			globalIndex := read2(r.curInsts, r.ip)
 			r.globals[globalIndex] = r.stack[r.sp]
+			if r.shared != nil {
+				r.shared[globalIndex] = false
+			}
 		case bytecode.OpGetGlobal:
 			r.ip += 2
 			globalIndex := read2(r.curInsts, r.ip)
+			if r.shared != nil && r.shared[globalIndex] {
+				r.globals[globalIndex] = r.globals[globalIndex].Clone()
+				r.shared[globalIndex] = false
+			}
 			val := r.globals[globalIndex]
 			r.stack[r.sp] = val
 			r.sp++
@@ -224,6 +379,9 @@ func (r *Runtime) run() (_ Value, err error) {
 			if unindent == 1 {
 				str = unindentString(str)
 			}
+			if err := r.track(String(str)); err != nil {
+				return nil, err
+			}
 			r.stack[r.sp] = String(str)
 			r.sp++
 		case bytecode.OpArray:
@@ -234,7 +392,11 @@ func (r *Runtime) run() (_ Value, err error) {
 			elements := r.takeListElements(numElements, splat)
 			r.sp -= numElements
 
-			r.stack[r.sp] = NewArray(elements)
+			arr := NewArray(elements)
+			if err := r.track(arr); err != nil {
+				return nil, err
+			}
+			r.stack[r.sp] = arr
 			r.sp++
 		case bytecode.OpTable:
 			r.ip += 3
@@ -270,6 +432,9 @@ func (r *Runtime) run() (_ Value, err error) {
 			}
 			r.sp -= numElements
 
+			if err := r.track(t); err != nil {
+				return nil, err
+			}
 			r.stack[r.sp] = t
 			r.sp++
 		case bytecode.OpTuple:
@@ -280,6 +445,9 @@ func (r *Runtime) run() (_ Value, err error) {
 			elements := r.takeListElements(numElements, splat)
 			r.sp -= numElements
 
+			if err := r.track(Tuple(elements)); err != nil {
+				return nil, err
+			}
 			r.stack[r.sp] = Tuple(elements)
 			r.sp++
 		case bytecode.OpIndex:
@@ -307,9 +475,22 @@ func (r *Runtime) run() (_ Value, err error) {
 			left := r.stack[r.sp-2]
 			right := r.stack[r.sp-3]
 			r.sp -= 3
+			t, isTable := left.(*Table)
+			var n int
+			if isTable {
+				n = t.Len()
+			}
 			if err := SetProperty(left, key, right); err != nil {
 				return nil, err
 			}
+			if isTable && t.Len() > n {
+				if err := r.CheckLen(t.Len()); err != nil {
+					return nil, err
+				}
+				if err := r.Alloc(entrySize); err != nil {
+					return nil, err
+				}
+			}
 		case bytecode.OpSliceIndex:
 			r.ip++
 			op := r.curInsts[r.ip]
@@ -402,6 +583,11 @@ func (r *Runtime) run() (_ Value, err error) {
 				if ret == nil {
 					ret = Nil
 				}
+				if n, _ := collectionSize(ret); n >= 0 {
+					if err := r.CheckLen(n); err != nil {
+						return nil, err
+					}
+				}
 				r.stack[r.sp] = ret
 				r.sp++
 			}
@@ -454,7 +640,7 @@ func (r *Runtime) run() (_ Value, err error) {
 			r.curFrame.deferred = append(r.curFrame.deferred, &deferredCall{
 				fn:   callable,
 				args: args,
-				pos:  r.curFrame.fn.deferMap[deferIdx],
+				src:  r.curFrame.fn.deferMap[deferIdx],
 			})
 		case bytecode.OpTry:
 			r.ip += 2
@@ -472,8 +658,18 @@ func (r *Runtime) run() (_ Value, err error) {
 
 			var status Value
 			ret, err := r.safeCall(callable, args)
+			if errors.Is(err, ErrLimitExceeded) {
+				// limit errors can't be caught
+				return nil, err
+			}
 			if err != nil {
-				status = newExceptionTable(err)
+				e := newErrorFrom(err)
+				if e.trace == nil {
+					// errors returned by builtin functions
+					// have no trace, so use the call site
+					e.trace = []Frame{r.currentFrame()}
+				}
+				status = e
 			} else {
 				status = Nil
 			}
@@ -483,6 +679,12 @@ func (r *Runtime) run() (_ Value, err error) {
 
 			r.stack[r.sp] = Tuple{ret, status}
 			r.sp++
+		case bytecode.OpTryBegin:
+			r.ip += 4
+			pos := read4(r.curInsts, r.ip)
+			r.curFrame.handlers = append(r.curFrame.handlers, handler{ip: pos, sp: r.sp})
+		case bytecode.OpTryEnd:
+			r.curFrame.handlers = r.curFrame.handlers[:len(r.curFrame.handlers)-1]
 		case bytecode.OpThrow:
 			r.ip++
 			numErrors := int(r.curInsts[r.ip])
@@ -495,6 +697,9 @@ func (r *Runtime) run() (_ Value, err error) {
 			}
 			r.sp--
 
+			if e, ok := errVal.(*Error); ok {
+				return nil, e.throw()
+			}
 			return nil, &Exception{Value: errVal}
 		case bytecode.OpDefineLocal:
 			r.ip++
@@ -576,7 +781,10 @@ func (r *Runtime) run() (_ Value, err error) {
 				numOptionals:  fn.numOptionals,
 				varArgs:       fn.varArgs,
 				sourceMap:     fn.sourceMap,
+				deferMap:      fn.deferMap,
 				free:          free,
+				name:          fn.name,
+				module:        fn.module,
 			}
 			r.stack[r.sp] = cl
 			r.sp++
@@ -617,6 +825,8 @@ func (r *Runtime) run() (_ Value, err error) {
 
 			var itValue *iterator
 			switch iterable := dst.(type) {
+			case *Chan:
+				itValue = newIterator(iterable.elements(r.Context()))
 			case KVIterable:
 				itValue = newIterator2(iterable.Entries())
 			case Iterable:
@@ -687,6 +897,9 @@ func (r *Runtime) callCompiled(fn *CompiledFunction, args []Value, pause bool) (
 	if r.framesIndex >= len(r.frames) {
 		return nil, ErrStackOverflow
 	}
+	if r.limits.MaxCallDepth > 0 && r.frameBase+r.framesIndex > r.limits.MaxCallDepth {
+		return nil, &LimitError{Kind: LimitCallDepth, Limit: int64(r.limits.MaxCallDepth)}
+	}
 
 	// update call frame
 	r.curFrame.ip = r.ip // store current ip before call
@@ -694,6 +907,7 @@ func (r *Runtime) callCompiled(fn *CompiledFunction, args []Value, pause bool) (
 	r.curFrame.fn = fn
 	r.curFrame.freeVars = fn.free
 	r.curFrame.basePointer = r.sp
+	r.curFrame.handlers = r.curFrame.handlers[:0]
 	r.curInsts = fn.instructions
 	r.ip = -1
 	r.framesIndex++
@@ -709,6 +923,7 @@ func (r *Runtime) callCompiled(fn *CompiledFunction, args []Value, pause bool) (
 		// after the current frame
 		r.frames = frames[framesIndex-1:]
 		r.framesIndex = 1
+		r.frameBase += framesIndex - 1
 
 		res, err := r.run()
 		if err != nil {
@@ -718,6 +933,7 @@ func (r *Runtime) callCompiled(fn *CompiledFunction, args []Value, pause bool) (
 		// restore call stack
 		r.frames = frames
 		r.framesIndex = framesIndex - 1
+		r.frameBase -= framesIndex - 1
 		r.curFrame = &r.frames[r.framesIndex-1]
 		r.curInsts = r.curFrame.fn.instructions
 		r.ip = r.curFrame.ip
@@ -751,31 +967,30 @@ func (r *Runtime) takeListElements(numElems, splat int) []Value {
 
 // unwindStack unwindes the call stack invoking all deferred calls along the way.
 func (r *Runtime) unwindStack(reason error) error {
-	var rErr *runtimeError
+	return r.unwindFrames(reason, 0)
+}
+
+// unwindFrames is like unwindStack, but stops when the number of frames is n.
+// If n > 0, the position in the frame that becomes current is added to the stacktrace,
+// but its deferred calls are not invoked.
+func (r *Runtime) unwindFrames(reason error, n int) *RuntimeError {
+	var rErr *RuntimeError
 	if !errors.As(reason, &rErr) {
-		rErr = &runtimeError{
-			Errors: []error{reason},
-			Trace:  make([]token.FilePos, 0),
-		}
+		rErr = &RuntimeError{Err: reason}
 	}
 
-	for r.framesIndex > 0 {
-		var filePos token.FilePos
-		if r.curFrame.curDefer != nil {
-			filePos = r.fileSet.Position(r.curFrame.curDefer.pos)
-		} else {
-			filePos = r.fileSet.Position(r.curFrame.fn.sourcePos(r.ip - 1))
-		}
-		rErr.Trace = append(rErr.Trace, filePos)
+	for r.framesIndex > n {
+		rErr.Frames = append(rErr.Frames, r.currentFrame())
 
 		if len(r.curFrame.deferred) > 0 {
 			if err := r.runDefer(); err != nil {
-				var tmp *runtimeError
+				var tmp *RuntimeError
 				if errors.As(err, &tmp) {
-					rErr.Errors = append(rErr.Errors, tmp.Errors...)
-					rErr.Trace = append(rErr.Trace, tmp.Trace...)
+					rErr.Deferred = append(rErr.Deferred, tmp.Err)
+					rErr.Deferred = append(rErr.Deferred, tmp.Deferred...)
+					rErr.Frames = append(rErr.Frames, tmp.Frames...)
 				} else {
-					rErr.Errors = append(rErr.Errors, err)
+					rErr.Deferred = append(rErr.Deferred, err)
 				}
 				continue // run remaining deferred calls
 			}
@@ -789,10 +1004,63 @@ func (r *Runtime) unwindStack(reason error) error {
 			r.sp = r.frames[r.framesIndex].basePointer
 		}
 	}
+	if n > 0 {
+		rErr.Frames = append(rErr.Frames, r.currentFrame())
+	}
 
 	return rErr
 }
 
+// currentFrame returns the stacktrace frame for the current position.
+func (r *Runtime) currentFrame() Frame {
+	var src sourceRange
+	if r.curFrame.curDefer != nil {
+		src = r.curFrame.curDefer.src
+	} else {
+		src = r.curFrame.fn.sourceAt(r.ip - 1)
+	}
+	filePos := r.fileSet.Position(src.pos)
+	module := r.curFrame.fn.module
+	if module == "" {
+		module = filePos.Filename
+	}
+	return Frame{
+		Pos:      filePos,
+		Function: r.curFrame.fn.name,
+		Module:   module,
+		Excerpt:  r.fileSet.Excerpt(src.pos, src.end),
+	}
+}
+
+// catch looks for an exception handler in the frames of the current run.
+// If there is one, catch unwinds the call stack to the frame of the handler,
+// pushes the caught error onto the stack and resumes execution at the handler.
+// Returns false if there is no handler, or if the error can't be caught.
+func (r *Runtime) catch(err error) bool {
+	if errors.Is(err, ErrLimitExceeded) {
+		// limit errors can't be caught
+		return false
+	}
+	target := -1
+	for i := r.framesIndex - 1; i >= 0; i-- {
+		if len(r.frames[i].handlers) != 0 {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return false
+	}
+	rErr := r.unwindFrames(err, target+1)
+	h := r.curFrame.handlers[len(r.curFrame.handlers)-1]
+	r.curFrame.handlers = r.curFrame.handlers[:len(r.curFrame.handlers)-1]
+	r.sp = h.sp
+	r.ip = h.ip - 1
+	r.stack[r.sp] = newErrorFrom(rErr)
+	r.sp++
+	return true
+}
+
 // runDefer invokes all deferred calls for the current frame.
 func (r *Runtime) runDefer() (err error) {
 	for len(r.curFrame.deferred) > 0 {
@@ -808,56 +1076,6 @@ func (r *Runtime) runDefer() (err error) {
 	return nil
 }
 
-// runtimeError is a special error type returned by (*Runtime).unwind()
-// that contains all errors encountered and a stacktrace.
-type runtimeError struct {
-	Errors []error
-	Trace  []token.FilePos
-}
-
-func (e *runtimeError) Error() string {
-	var b strings.Builder
-	for i, err := range e.Errors {
-		if i != 0 {
-			if i != len(e.Errors)-1 {
-				b.WriteString("\n├─ ")
-			} else {
-				b.WriteString("\n└─ ")
-			}
-		}
-		b.WriteString(err.Error())
-	}
-	return b.String()
-}
-
-func (e *runtimeError) Unwrap() error {
-	// we only care about the initial error
-	return e.Errors[0]
-}
-
-// newExceptionTable turns the error into an immutable table
-// containing information about the exception/runtime error.
-// This table is then returned by try keyword.
-// The table consists of msg and val fields:
-// - msg field contains the string representation of the error.
-// - val field contains the value thrown by throw keyword.
-func newExceptionTable(err error) Value {
-	if rErr := (*runtimeError)(nil); errors.As(err, &rErr) {
-		// we only care about the initial error
-		err = rErr.Errors[0]
-	}
-	var val Value
-	if exc := (*Exception)(nil); errors.As(err, &exc) {
-		val = exc.Value
-	} else {
-		val = Nil
-	}
-	t := NewTable(2)
-	t.SetProperty(String("msg"), String(err.Error()))
-	t.SetProperty(String("val"), val)
-	return t.Freeze()
-}
-
 // read2 reads a 2-byte operand assumming that
 // ip is at the last byte of the operand.
 func read2(ins []byte, ip int) int {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"path/filepath"
 	"sync"
 
@@ -15,19 +17,40 @@ import (
 type Script struct {
 	variables        map[string]*Variable
 	modules          ModuleGetter
+	name             string
 	input            []byte
 	enableFileImport bool
 	importDir        string
+	importPath       []string
+	importFileExt    []string
+	moduleFiles      map[string]string
+	manifest         *Manifest
+	lockfile         *Lockfile
+	limits           Limits
+	policy           *Policy
+	fs               fs.FS
+	environ          []string
+	args             []string
+	stdin            io.Reader
+	stdout           io.Writer
+	stderr           io.Writer
 }
 
 // NewScript creates a Script instance with an input script.
 func NewScript(input []byte) *Script {
 	return &Script{
 		variables: make(map[string]*Variable),
+		name:      "(main)",
 		input:     input,
 	}
 }
 
+// SetName sets the name of the script, which is used as the file name
+// in positions of errors. The default name is "(main)".
+func (s *Script) SetName(name string) {
+	s.name = name
+}
+
 // Add adds a new variable or updates an existing variable to the script.
 func (s *Script) Add(name string, value Value) {
 	s.variables[name] = &Variable{name, value}
@@ -56,37 +79,152 @@ func (s *Script) SetImports(modules ModuleGetter) {
 }
 
 // SetImportDir sets the initial import directory for script files.
+// If the script uses the host filesystem, dir is converted to an absolute path
+// when the script is compiled.
 func (s *Script) SetImportDir(dir string) error {
-	dir, err := filepath.Abs(dir)
-	if err != nil {
+	if _, err := filepath.Abs(dir); err != nil {
 		return err
 	}
 	s.importDir = dir
 	return nil
 }
 
+// SetImportPath sets the list of directories searched for script files
+// that can't be found in the import directory or using the manifest.
+func (s *Script) SetImportPath(dirs ...string) {
+	s.importPath = dirs
+}
+
+// SetImportFileExt sets the extensions of script files.
+// See Compiler.SetImportFileExt for details.
+func (s *Script) SetImportFileExt(exts ...string) error {
+	if len(exts) == 0 {
+		return fmt.Errorf("missing arg: at least one argument is required")
+	}
+	for _, ext := range exts {
+		if ext != filepath.Ext(ext) || ext == "" {
+			return fmt.Errorf("invalid file extension: %s", ext)
+		}
+	}
+	s.importFileExt = exts
+	return nil
+}
+
+// SetModuleFiles sets the files imported instead of the modules
+// with the given names. See Compiler.SetModuleFiles for details.
+func (s *Script) SetModuleFiles(files map[string]string) {
+	s.moduleFiles = files
+}
+
+// SetManifest sets the manifest used to resolve imports of script files.
+func (s *Script) SetManifest(m *Manifest) {
+	s.manifest = m
+}
+
+// SetLockfile sets the lock file used to verify the contents of imported files.
+// If l is not nil, the script fails to compile if an imported file
+// is missing from the lock file or doesn't match its hash.
+func (s *Script) SetLockfile(l *Lockfile) {
+	s.lockfile = l
+}
+
 // EnableFileImport enables or disables module loading from local files. Local
 // file modules are disabled by default.
 func (s *Script) EnableFileImport(enable bool) {
 	s.enableFileImport = enable
 }
 
+// SetLimits sets resource limits for the runtime executing the script.
+func (s *Script) SetLimits(limits Limits) {
+	s.limits = limits
+}
+
+// SetFS sets the filesystem used by the script for file imports
+// and by the builtin modules that access files.
+// If fsys is nil (the default), the host filesystem is used.
+// To allow scripts to modify files, fsys must implement WritableFS.
+func (s *Script) SetFS(fsys fs.FS) {
+	s.fs = fsys
+}
+
+// SetEnv sets the environment variables available to the script
+// in the form "key=value". Each run of the script gets its own copy
+// of the environment, isolated from the environment of the process.
+// If environ is nil (the default), the script uses the environment of the process.
+func (s *Script) SetEnv(environ []string) {
+	s.environ = environ
+}
+
+// SetArgs sets the command-line arguments available to the script,
+// starting with the name of the script.
+// If args is nil (the default), os.Args is used.
+func (s *Script) SetArgs(args []string) {
+	s.args = args
+}
+
+// SetStdin sets the standard input of the script.
+// If stdin is nil (the default), os.Stdin is used.
+func (s *Script) SetStdin(stdin io.Reader) {
+	s.stdin = stdin
+}
+
+// SetStdout sets the standard output of the script.
+// If stdout is nil (the default), os.Stdout is used.
+func (s *Script) SetStdout(stdout io.Writer) {
+	s.stdout = stdout
+}
+
+// SetStderr sets the standard error of the script.
+// If stderr is nil (the default), os.Stderr is used.
+func (s *Script) SetStderr(stderr io.Writer) {
+	s.stderr = stderr
+}
+
+// SetPolicy sets the policy restricting capabilities available to the script.
+// The policy is applied to the builtin modules when the script is compiled.
+func (s *Script) SetPolicy(p *Policy) {
+	s.policy = p
+}
+
 // Compile compiles the script with all the defined variables,
 // and returns Compiled object.
 func (s *Script) Compile() (*Compiled, error) {
 	symbolTable, globals := s.prepCompile()
 
 	fileSet := token.NewFileSet()
-	srcFile := fileSet.AddFile("(main)", -1, len(s.input))
+	srcFile := fileSet.AddFile(s.name, -1, len(s.input))
 	p := parser.NewParser(srcFile, s.input, nil)
 	file, err := p.ParseFile()
 	if err != nil {
 		return nil, err
 	}
 
-	c := NewCompiler(srcFile, symbolTable, nil, s.modules, nil)
+	modules := s.modules
+	if s.policy != nil && modules != nil {
+		modules = s.policy.Modules(modules)
+	}
+
+	c := NewCompiler(srcFile, symbolTable, nil, modules, nil)
 	c.EnableFileImport(s.enableFileImport)
-	c.SetImportDir(s.importDir)
+	c.SetFS(s.fs)
+	importDir := s.importDir
+	if s.fs != nil {
+		importDir = rootedPath(importDir)
+	} else if importDir != "" {
+		if importDir, err = filepath.Abs(importDir); err != nil {
+			return nil, err
+		}
+	}
+	c.SetImportDir(importDir)
+	c.SetImportPath(s.importPath...)
+	c.SetModuleFiles(s.moduleFiles)
+	if s.importFileExt != nil {
+		if err := c.SetImportFileExt(s.importFileExt...); err != nil {
+			return nil, err
+		}
+	}
+	c.SetManifest(s.manifest)
+	c.SetLockfile(s.lockfile)
 	if err := c.Compile(file); err != nil {
 		return nil, err
 	}
@@ -110,8 +248,17 @@ func (s *Script) Compile() (*Compiled, error) {
 
 	return &Compiled{
 		globalIndexes: globalIndexes,
+		symbolTable:   symbolTable,
 		bytecode:      bytecode,
 		globals:       globals,
+		limits:        s.limits,
+		policy:        s.policy,
+		fs:            s.fs,
+		environ:       s.environ,
+		args:          s.args,
+		stdin:         s.stdin,
+		stdout:        s.stdout,
+		stderr:        s.stderr,
 	}, nil
 }
 
@@ -168,8 +315,20 @@ func (s *Script) prepCompile() (symbolTable *SymbolTable, globals []Value) {
 // Use Script.Compile() to create Compiled object.
 type Compiled struct {
 	globalIndexes map[string]int // global symbol name to index
+	symbolTable   *SymbolTable
 	bytecode      *Bytecode
 	globals       []Value
+	shared        []bool // globals shared with other instances, see CompiledPool
+	result        Value  // value returned by the last run
+	limits        Limits
+	policy        *Policy
+	fs            fs.FS
+	environ       []string
+	args          []string
+	stdin         io.Reader
+	stdout        io.Writer
+	stderr        io.Writer
+	runtime       *Runtime // reused runtime, see CompiledPool
 	lock          sync.RWMutex
 }
 
@@ -178,8 +337,12 @@ func (c *Compiled) Run() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	r := NewRuntime(c.bytecode, c.globals)
-	return r.Run()
+	r := c.newRuntime()
+	if err := r.Run(); err != nil {
+		return err
+	}
+	c.result = r.Result()
+	return nil
 }
 
 // RunContext is like Run but includes a context.
@@ -187,7 +350,8 @@ func (c *Compiled) RunContext(ctx context.Context) (err error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	r := NewRuntime(c.bytecode, c.globals)
+	r := c.newRuntime()
+	r.SetContext(ctx)
 	ch := make(chan error, 1)
 	go func() {
 		defer func() {
@@ -212,26 +376,148 @@ func (c *Compiled) RunContext(ctx context.Context) (err error) {
 		err = ctx.Err()
 	case err = <-ch:
 	}
+	if err == nil {
+		c.result = r.Result()
+	}
 
 	return err
 }
 
+// Result returns the value returned by the top-level return statement
+// of the script during the last successful run,
+// or nil if the script didn't return a value.
+func (c *Compiled) Result() Value {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.result == nil {
+		return Nil
+	}
+	return c.result
+}
+
+// newRuntime creates a runtime configured to execute the script.
+func (c *Compiled) newRuntime() *Runtime {
+	r := c.runtime
+	if r != nil {
+		r.reset()
+	} else {
+		r = NewRuntime(c.bytecode, c.globals)
+	}
+	r.SetLimits(c.limits)
+	r.SetPolicy(c.policy)
+	r.SetFS(c.fs)
+	if c.environ != nil {
+		r.SetEnv(NewEnv(c.environ))
+	}
+	r.SetArgs(c.args)
+	r.SetStdin(c.stdin)
+	r.SetStdout(c.stdout)
+	r.SetStderr(c.stderr)
+	return r
+}
+
+// Func returns a handle to the function stored in the global variable
+// with the given name, which allows to call the function from Go
+// after the script has been executed.
+// Returns an error if the variable is not defined or is not callable.
+func (c *Compiled) Func(name string) (*Func, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	idx, ok := c.globalIndexes[name]
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not defined", name)
+	}
+	if _, ok := c.globals[idx].(Callable); !ok {
+		return nil, fmt.Errorf("'%s' is not callable", name)
+	}
+	return &Func{compiled: c, name: name, idx: idx}, nil
+}
+
+// Func is a handle to a function defined by the compiled script.
+// Use Compiled.Func to create Func object.
+//
+// Each call is executed in a new runtime that shares globals
+// with the compiled script and is configured the same way Run configures it.
+// Calls are serialized with Run and other calls using the same Compiled,
+// so use Compiled.Clone or CompiledPool to call functions in parallel.
+type Func struct {
+	compiled *Compiled
+	name     string
+	idx      int
+}
+
+// Name returns the name of the global variable the function is stored in.
+func (f *Func) Name() string {
+	return f.name
+}
+
+// Call calls the function with the given arguments.
+// Panics in the called function are recovered and returned as errors.
+func (f *Func) Call(args ...Value) (Value, error) {
+	return f.CallContext(context.Background(), args...)
+}
+
+// CallContext is like Call but includes a context.
+// If the context is done before the function returns,
+// the execution is aborted and the context's error is returned.
+func (f *Func) CallContext(ctx context.Context, args ...Value) (Value, error) {
+	c := f.compiled
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// the variable may have been reassigned since the handle was created
+	fn := c.global(f.idx)
+	if _, ok := fn.(Callable); !ok {
+		return nil, fmt.Errorf("'%s' is not callable", f.name)
+	}
+
+	r := c.newRuntime()
+	r.SetContext(ctx)
+	res, err := r.callTop(fn, args...)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 // Bytecode returns a compiled bytecode.
 func (c *Compiled) Bytecode() *Bytecode {
 	return c.bytecode
 }
 
+// SymbolTable returns a copy of the symbol table of the global scope,
+// which allows to compile code referring to the global variables of the script,
+// e.g. in a REPL session attached to the script.
+func (c *Compiled) SymbolTable() *SymbolTable {
+	return c.symbolTable.Copy()
+}
+
+// UpdateGlobals calls fn with the values of the global variables
+// indexed by the symbols of SymbolTable.
+// Calls are serialized with Run and function calls,
+// so fn can safely read and modify the values.
+func (c *Compiled) UpdateGlobals(fn func(globals []Value) error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for idx := range c.shared {
+		c.global(idx)
+	}
+	return fn(c.globals)
+}
+
 // Clone creates a new copy of Compiled. Cloned copies are safe for concurrent
 // use by multiple goroutines.
 func (c *Compiled) Clone() *Compiled {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 
-	clone := &Compiled{
-		globalIndexes: c.globalIndexes,
-		bytecode:      c.bytecode,
-		globals:       make([]Value, len(c.globals)),
-	}
+	clone := c.withGlobals(make([]Value, len(c.globals)))
 
 	// copy global objects
 	for idx, g := range c.globals {
@@ -243,6 +529,106 @@ func (c *Compiled) Clone() *Compiled {
 	return clone
 }
 
+// withGlobals returns a new Compiled with the same bytecode
+// and configuration as c, but with the given globals.
+func (c *Compiled) withGlobals(globals []Value) *Compiled {
+	return &Compiled{
+		globalIndexes: c.globalIndexes,
+		symbolTable:   c.symbolTable,
+		bytecode:      c.bytecode,
+		globals:       globals,
+		limits:        c.limits,
+		policy:        c.policy,
+		fs:            c.fs,
+		environ:       c.environ,
+		args:          c.args,
+		stdin:         c.stdin,
+		stdout:        c.stdout,
+		stderr:        c.stderr,
+	}
+}
+
+// global returns the value of the global variable with the given index.
+// If the value is shared with other instances of the script,
+// it is replaced by its copy first, so the caller can modify it.
+// The caller must hold the write lock.
+func (c *Compiled) global(idx int) Value {
+	if c.shared != nil && c.shared[idx] {
+		c.globals[idx] = c.globals[idx].Clone()
+		c.shared[idx] = false
+	}
+	return c.globals[idx]
+}
+
+// SetLimits sets resource limits for the runtime executing the script.
+func (c *Compiled) SetLimits(limits Limits) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.limits = limits
+}
+
+// SetPolicy sets the policy for the runtime executing the script.
+// The policy passed to Script.SetPolicy is still applied to the builtin modules.
+func (c *Compiled) SetPolicy(p *Policy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.policy = p
+}
+
+// SetFS sets the filesystem used by the runtime executing the script.
+// Modules imported from files are resolved at compile time
+// and are not affected by SetFS.
+func (c *Compiled) SetFS(fsys fs.FS) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.fs = fsys
+}
+
+// SetEnv sets the environment variables for the runtime executing the script.
+// See Script.SetEnv for details.
+func (c *Compiled) SetEnv(environ []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.environ = environ
+}
+
+// SetArgs sets the command-line arguments for the runtime executing the script.
+// See Script.SetArgs for details.
+func (c *Compiled) SetArgs(args []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.args = args
+}
+
+// SetStdin sets the standard input for the runtime executing the script.
+func (c *Compiled) SetStdin(stdin io.Reader) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stdin = stdin
+}
+
+// SetStdout sets the standard output for the runtime executing the script.
+func (c *Compiled) SetStdout(stdout io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stdout = stdout
+}
+
+// SetStderr sets the standard error for the runtime executing the script.
+func (c *Compiled) SetStderr(stderr io.Writer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stderr = stderr
+}
+
 // IsDefined returns true if the variable name is defined (has value) before or
 // after the execution.
 func (c *Compiled) IsDefined(name string) bool {
@@ -264,12 +650,13 @@ func (c *Compiled) IsDefined(name string) bool {
 
 // Get returns a variable identified by the name.
 func (c *Compiled) Get(name string) *Variable {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	// the write lock is needed to copy the shared global
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
 	value := Value(Nil)
 	if idx, ok := c.globalIndexes[name]; ok {
-		value = c.globals[idx]
+		value = c.global(idx)
 		if value == nil {
 			value = Nil
 		}
@@ -283,12 +670,13 @@ func (c *Compiled) Get(name string) *Variable {
 
 // GetAll returns all the variables that are defined by the compiled script.
 func (c *Compiled) GetAll() []*Variable {
-	c.lock.RLock()
-	defer c.lock.RUnlock()
+	// the write lock is needed to copy the shared globals
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
 	var vars []*Variable
 	for name, idx := range c.globalIndexes {
-		value := c.globals[idx]
+		value := c.global(idx)
 		if value == nil {
 			value = Nil
 		}
@@ -312,6 +700,9 @@ func (c *Compiled) Set(name string, value Value) error {
 		return fmt.Errorf("'%s' is not defined", name)
 	}
 	c.globals[idx] = value
+	if c.shared != nil {
+		c.shared[idx] = false
+	}
 
 	return nil
 }
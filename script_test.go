@@ -0,0 +1,138 @@
+package toy_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompiledFunc(t *testing.T) {
+	script := toy.NewScript([]byte(`
+count := 0
+add := fn(a, b?, ...rest) {
+	count += 1
+	if b == nil { b = 1 }
+	return a + b + len(rest)
+}
+fail := fn() { throw "failed" }
+value := 1
+wait := fn() { for {} }`))
+	compiled, err := script.Run()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		fn      string
+		args    []toy.Value
+		want    toy.Value
+		funcErr string
+		err     string
+	}{
+		{name: "arguments", fn: "add", args: []toy.Value{toy.Int(1), toy.Int(2)}, want: toy.Int(3)},
+		{name: "optional argument", fn: "add", args: []toy.Value{toy.Int(1)}, want: toy.Int(2)},
+		{name: "variadic arguments", fn: "add", args: []toy.Value{toy.Int(1), toy.Int(2), toy.Nil, toy.Nil}, want: toy.Int(5)},
+		{name: "wrong number of arguments", fn: "add", err: "want at least 1 argument(s), got 0"},
+		{name: "error", fn: "fail", err: "failed"},
+		{name: "builtin", fn: "len", funcErr: "'len' is not defined"},
+		{name: "not defined", fn: "missing", funcErr: "'missing' is not defined"},
+		{name: "not callable", fn: "value", funcErr: "'value' is not callable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := compiled.Func(tt.fn)
+			if tt.funcErr != "" {
+				require.EqualError(t, err, tt.funcErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.fn, fn.Name())
+			res, err := fn.Call(tt.args...)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, res)
+		})
+	}
+
+	// calls share globals with the script
+	require.Equal(t, toy.Int(3), compiled.Get("count").Value())
+
+	// the handle calls the current value of the variable
+	fn, err := compiled.Func("add")
+	require.NoError(t, err)
+	require.NoError(t, compiled.Set("add", toy.Int(1)))
+	_, err = fn.Call()
+	require.EqualError(t, err, "'add' is not callable")
+}
+
+func TestFuncCallContext(t *testing.T) {
+	script := toy.NewScript([]byte(`
+time := import("time")
+loop := fn() { for {} }
+sleep := fn() { time.sleep(time.hour) }`))
+	script.SetImports(stdlib.StdLib)
+	compiled, err := script.Run()
+	require.NoError(t, err)
+
+	for _, name := range []string{"loop", "sleep"} {
+		t.Run(name, func(t *testing.T) {
+			fn, err := compiled.Func(name)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = fn.CallContext(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+			_, err = fn.CallContext(ctx)
+			require.ErrorIs(t, err, context.Canceled)
+		})
+	}
+}
+
+func TestFuncSerialized(t *testing.T) {
+	script := toy.NewScript([]byte(`
+inc := fn() {
+	v := counter
+	for i in range(0, 100) {}
+	counter = v + 1
+}
+counter += 1`))
+	script.Add("counter", toy.Int(0))
+	compiled, err := script.Compile()
+	require.NoError(t, err)
+	require.NoError(t, compiled.Run())
+
+	fn, err := compiled.Func("inc")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				_, err := fn.Call()
+				assert.NoError(t, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, compiled.Run())
+		}()
+	}
+	wg.Wait()
+
+	// 11 runs and 100 calls, none of the increments is lost
+	require.Equal(t, toy.Int(111), compiled.Get("counter").Value())
+}
@@ -0,0 +1,114 @@
+package errors
+
+import (
+	"github.com/infastin/toy"
+)
+
+var Module = &toy.BuiltinModule{
+	Name: "errors",
+	Members: map[string]toy.Value{
+		"new":    toy.NewBuiltinFunction("errors.new", newFn),
+		"wrap":   toy.NewBuiltinFunction("errors.wrap", wrapFn),
+		"is":     toy.NewBuiltinFunction("errors.is", isFn),
+		"unwrap": toy.NewBuiltinFunction("errors.unwrap", unwrapFn),
+		"kind":   toy.NewBuiltinFunction("errors.kind", kindFn),
+
+		"Error":      toy.ErrorKindError,
+		"Exception":  toy.ErrorKindException,
+		"Runtime":    toy.ErrorKindRuntime,
+		"Type":       toy.ErrorKindInvalidType,
+		"NotExist":   toy.ErrorKindNotExist,
+		"Exist":      toy.ErrorKindExist,
+		"Permission": toy.ErrorKindPermission,
+		"Denied":     toy.ErrorKindDenied,
+	},
+}
+
+func newFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	return toy.ErrorKindError.Call(r, args...)
+}
+
+// unpackError unpacks the error argument, which may be nil.
+func unpackError(arg toy.Value) (*toy.Error, error) {
+	switch x := arg.(type) {
+	case *toy.Error:
+		return x, nil
+	case toy.NilValue:
+		return nil, nil
+	default:
+		return nil, &toy.InvalidArgumentTypeError{
+			Name: "err",
+			Want: "error or nil",
+			Got:  toy.TypeName(arg),
+		}
+	}
+}
+
+func wrapFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var (
+		arg toy.Value
+		msg string
+	)
+	if err := toy.UnpackArgs(args, "err", &arg, "msg", &msg); err != nil {
+		return nil, err
+	}
+	err, uerr := unpackError(arg)
+	if uerr != nil {
+		return nil, uerr
+	}
+	if err == nil {
+		return toy.Nil, nil
+	}
+	return toy.NewError(err.Kind(), msg, err), nil
+}
+
+func isFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var arg, target toy.Value
+	if err := toy.UnpackArgs(args, "err", &arg, "target", &target); err != nil {
+		return nil, err
+	}
+	err, uerr := unpackError(arg)
+	if uerr != nil {
+		return nil, uerr
+	}
+	switch x := target.(type) {
+	case *toy.ErrorKind:
+		return toy.Bool(err.Is(x)), nil
+	case *toy.Error:
+		for ; err != nil; err = err.Cause() {
+			if eq, _ := toy.Equal(err, x); eq {
+				return toy.True, nil
+			}
+		}
+		return toy.False, nil
+	default:
+		return nil, &toy.InvalidArgumentTypeError{
+			Name: "target",
+			Want: "error or errorKind",
+			Got:  toy.TypeName(target),
+		}
+	}
+}
+
+func unwrapFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var arg toy.Value
+	if err := toy.UnpackArgs(args, "err", &arg); err != nil {
+		return nil, err
+	}
+	err, uerr := unpackError(arg)
+	if uerr != nil {
+		return nil, uerr
+	}
+	if err == nil || err.Cause() == nil {
+		return toy.Nil, nil
+	}
+	return err.Cause(), nil
+}
+
+func kindFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var name string
+	if err := toy.UnpackArgs(args, "name", &name); err != nil {
+		return nil, err
+	}
+	return toy.NewErrorKind(name), nil
+}
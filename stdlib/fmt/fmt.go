@@ -1,7 +1,7 @@
 package fmt
 
 import (
-	"os"
+	"io"
 	"strings"
 
 	"github.com/infastin/toy"
@@ -17,7 +17,7 @@ var Module = &toy.BuiltinModule{
 	},
 }
 
-func printFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func printFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var b strings.Builder
 	for i, arg := range args {
 		if i != 0 {
@@ -26,12 +26,12 @@ func printFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 		b.WriteString(toy.AsString(arg))
 	}
 	if b.Len() != 0 {
-		os.Stdout.WriteString(b.String())
+		io.WriteString(r.Stdout(), b.String())
 	}
 	return toy.Nil, nil
 }
 
-func printlnFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func printlnFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var b strings.Builder
 	for i, arg := range args {
 		if i != 0 {
@@ -40,11 +40,11 @@ func printlnFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 		b.WriteString(toy.AsString(arg))
 	}
 	b.WriteByte('\n')
-	os.Stdout.WriteString(b.String())
+	io.WriteString(r.Stdout(), b.String())
 	return toy.Nil, nil
 }
 
-func printfFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func printfFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		format string
 		rest   []toy.Value
@@ -57,12 +57,12 @@ func printfFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 		return nil, err
 	}
 	if len(s) != 0 {
-		os.Stdout.WriteString(s)
+		io.WriteString(r.Stdout(), s)
 	}
 	return toy.Nil, nil
 }
 
-func printfnFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func printfnFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		format string
 		rest   []toy.Value
@@ -74,6 +74,6 @@ func printfnFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	os.Stdout.WriteString(s + "\n")
+	io.WriteString(r.Stdout(), s+"\n")
 	return toy.Nil, nil
 }
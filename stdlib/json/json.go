@@ -18,6 +18,11 @@ var Module = &toy.BuiltinModule{
 }
 
 func encodeSequence(enc *jx.Encoder, seq toy.Sequence) (err error) {
+	if seq.Len() == 0 {
+		// avoid an indented empty line between the brackets
+		enc.Raw([]byte("[]"))
+		return nil
+	}
 	enc.ArrStart()
 	for elem := range seq.Elements() {
 		if err := EncodeObject(enc, elem); err != nil {
@@ -29,6 +34,11 @@ func encodeSequence(enc *jx.Encoder, seq toy.Sequence) (err error) {
 }
 
 func encodeMapping(enc *jx.Encoder, mapping toy.Mapping) (err error) {
+	if mapping.Len() == 0 {
+		// avoid an indented empty line between the braces
+		enc.Raw([]byte("{}"))
+		return nil
+	}
 	enc.ObjStart()
 	for key, value := range mapping.Entries() {
 		keyStr, ok := key.(toy.String)
@@ -30,19 +30,30 @@ var Module = &toy.BuiltinModule{
 		"Interface":      InterfaceType,
 		"InterfaceFlags": InterfaceFlagsType,
 
-		"lookupIP":        toy.NewBuiltinFunction("lookupIP", lookupIPFn),
+		"lookupIP":        toy.NewBuiltinFunction("lookupIP", withNetwork(lookupIPFn)),
 		"parseCIDR":       toy.NewBuiltinFunction("parseCIDR", parseCIDRFn),
-		"interfaceAddrs":  toy.NewBuiltinFunction("interfaceAddrs", interfaceAddrsFn),
-		"resolveIPAddr":   toy.NewBuiltinFunction("resolveIPAddr", resolveIPAddrFn),
-		"lookupInterface": toy.NewBuiltinFunction("lookupInterface", lookupInterfaceFn),
-		"interfaces":      toy.NewBuiltinFunction("interfaces", interfacesFn),
+		"interfaceAddrs":  toy.NewBuiltinFunction("interfaceAddrs", withNetwork(interfaceAddrsFn)),
+		"resolveIPAddr":   toy.NewBuiltinFunction("resolveIPAddr", withNetwork(resolveIPAddrFn)),
+		"lookupInterface": toy.NewBuiltinFunction("lookupInterface", withNetwork(lookupInterfaceFn)),
+		"interfaces":      toy.NewBuiltinFunction("interfaces", withNetwork(interfacesFn)),
 		"joinHostPort":    toy.NewBuiltinFunction("joinHostPort", joinHostPortFn),
 		"splitHostPort":   toy.NewBuiltinFunction("splitHostPort", fndef.ASRSSE("hostport", net.SplitHostPort)),
-		"lookupAddr":      toy.NewBuiltinFunction("lookupAddr", fndef.ASRSsE("addr", net.LookupAddr)),
-		"lookupHost":      toy.NewBuiltinFunction("lookupHost", fndef.ASRSsE("host", net.LookupHost)),
+		"lookupAddr":      toy.NewBuiltinFunction("lookupAddr", withNetwork(lookupAddrFn)),
+		"lookupHost":      toy.NewBuiltinFunction("lookupHost", withNetwork(lookupHostFn)),
 	},
 }
 
+// withNetwork wraps fn so that it returns an error
+// if the policy of the runtime doesn't allow network access.
+func withNetwork(fn toy.CallableFunc) toy.CallableFunc {
+	return func(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+		if err := r.CheckNetwork(); err != nil {
+			return nil, err
+		}
+		return fn(r, args...)
+	}
+}
+
 type IP net.IP
 
 var IPType = toy.NewType[IP]("net.IP", func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
@@ -303,12 +314,12 @@ func ipDefaultMaskMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return IP(net.IP(recv).DefaultMask()), nil
 }
 
-func lookupIPFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func lookupIPFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var host string
 	if err := toy.UnpackArgs(args, "host", &host); err != nil {
 		return nil, err
 	}
-	ips, err := net.LookupIP(host)
+	ips, err := net.DefaultResolver.LookupIP(r.Context(), "ip", host)
 	if err != nil {
 		return nil, err
 	}
@@ -495,6 +506,38 @@ func ipMaskSizeMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return toy.Tuple{toy.Int(ones), toy.Int(bits)}, nil
 }
 
+func lookupAddrFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var addr string
+	if err := toy.UnpackArgs(args, "addr", &addr); err != nil {
+		return nil, err
+	}
+	names, err := net.DefaultResolver.LookupAddr(r.Context(), addr)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]toy.Value, 0, len(names))
+	for _, name := range names {
+		elems = append(elems, toy.String(name))
+	}
+	return toy.NewArray(elems), nil
+}
+
+func lookupHostFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var host string
+	if err := toy.UnpackArgs(args, "host", &host); err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupHost(r.Context(), host)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]toy.Value, 0, len(addrs))
+	for _, addr := range addrs {
+		elems = append(elems, toy.String(addr))
+	}
+	return toy.NewArray(elems), nil
+}
+
 func parseCIDRFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var s string
 	if err := toy.UnpackArgs(args, "s", &s); err != nil {
@@ -802,16 +845,26 @@ func (a *IPAddr) Convert(p any) error {
 	return nil
 }
 
-func resolveIPAddrFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func resolveIPAddrFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var network, address string
 	if err := toy.UnpackArgs(args, "network", &network, "address", &address); err != nil {
 		return nil, err
 	}
-	addr, err := net.ResolveIPAddr(network, address)
+	switch network {
+	case "ip", "ip4", "ip6":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(r.Context(), address)
 	if err != nil {
 		return nil, err
 	}
-	return (*IPAddr)(addr), nil
+	for _, addr := range addrs {
+		if network == "ip" || (network == "ip4") == (addr.IP.To4() != nil) {
+			return (*IPAddr)(&addr), nil
+		}
+	}
+	return nil, &net.AddrError{Err: "no suitable address found", Addr: address}
 }
 
 type Interface net.Interface
@@ -1,20 +1,72 @@
 package env
 
 import (
-	"os"
-
 	"github.com/infastin/toy"
-	"github.com/infastin/toy/internal/fndef"
 )
 
 var Module = &toy.BuiltinModule{
 	Name: "env",
 	Members: map[string]toy.Value{
-		"expand": toy.NewBuiltinFunction("env.expand", fndef.ASRS("s", os.ExpandEnv)),
-		"clear":  toy.NewBuiltinFunction("env.clear", fndef.AR(os.Clearenv)),
-		"get":    toy.NewBuiltinFunction("env.get", fndef.ASRS("key", os.Getenv)),
-		"set":    toy.NewBuiltinFunction("env.set", fndef.ASSRE("key", "value", os.Setenv)),
-		"unset":  toy.NewBuiltinFunction("env.unset", fndef.ASRE("key", os.Unsetenv)),
-		"lookup": toy.NewBuiltinFunction("env.lookup", fndef.ASRSB("key", os.LookupEnv)),
+		"expand": toy.NewBuiltinFunction("env.expand", expandFn),
+		"clear":  toy.NewBuiltinFunction("env.clear", clearFn),
+		"get":    toy.NewBuiltinFunction("env.get", getFn),
+		"set":    toy.NewBuiltinFunction("env.set", setFn),
+		"unset":  toy.NewBuiltinFunction("env.unset", unsetFn),
+		"lookup": toy.NewBuiltinFunction("env.lookup", lookupFn),
 	},
 }
+
+func expandFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var s string
+	if err := toy.UnpackArgs(args, "s", &s); err != nil {
+		return nil, err
+	}
+	return toy.String(r.Env().Expand(s)), nil
+}
+
+func clearFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	r.Env().Clear()
+	return toy.Nil, nil
+}
+
+func getFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var key string
+	if err := toy.UnpackArgs(args, "key", &key); err != nil {
+		return nil, err
+	}
+	return toy.String(r.Env().Get(key)), nil
+}
+
+func setFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var key, value string
+	if err := toy.UnpackArgs(args, "key", &key, "value", &value); err != nil {
+		return nil, err
+	}
+	if err := r.Env().Set(key, value); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func unsetFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var key string
+	if err := toy.UnpackArgs(args, "key", &key); err != nil {
+		return nil, err
+	}
+	if err := r.Env().Unset(key); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func lookupFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var key string
+	if err := toy.UnpackArgs(args, "key", &key); err != nil {
+		return nil, err
+	}
+	value, ok := r.Env().Lookup(key)
+	return toy.Tuple{toy.String(value), toy.Bool(ok)}, nil
+}
@@ -1,16 +1,20 @@
 package os
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/infastin/toy"
 	"github.com/infastin/toy/internal/fndef"
 	"github.com/infastin/toy/stdlib/enum"
-	"github.com/infastin/toy/stdlib/time"
+	toytime "github.com/infastin/toy/stdlib/time"
 	"github.com/infastin/toy/token"
 )
 
@@ -21,9 +25,9 @@ var Module = &toy.BuiltinModule{
 		"arch":     toy.String(runtime.GOARCH),
 		"devnull":  toy.String(os.DevNull),
 
-		"stdin":  (*File)(os.Stdin),
-		"stdout": (*File)(os.Stdout),
-		"stderr": (*File)(os.Stderr),
+		"stdin":  &File{name: os.Stdin.Name(), stream: stdinStream},
+		"stdout": &File{name: os.Stdout.Name(), stream: stdoutStream},
+		"stderr": &File{name: os.Stderr.Name(), stream: stderrStream},
 
 		"O":    O,
 		"Seek": Seek,
@@ -45,11 +49,11 @@ var Module = &toy.BuiltinModule{
 		"mkdir":      toy.NewBuiltinFunction("os.mkdir", mkdirFn),
 		"mkdirTemp":  toy.NewBuiltinFunction("os.mkdirTemp", mkdirTempFn),
 		"remove":     toy.NewBuiltinFunction("os.remove", removeFn),
-		"rename":     toy.NewBuiltinFunction("os.rename", fndef.ASSRE("oldpath", "newpath", os.Rename)),
-		"link":       toy.NewBuiltinFunction("os.link", fndef.ASSRE("oldname", "newname", os.Link)),
-		"readlink":   toy.NewBuiltinFunction("os.readlink", fndef.ASRSE("name", os.Readlink)),
-		"symlink":    toy.NewBuiltinFunction("os.symlink", fndef.ASSRE("oldname", "newname", os.Symlink)),
-		"chdir":      toy.NewBuiltinFunction("os.chdir", fndef.ASRE("dir", os.Chdir)),
+		"rename":     toy.NewBuiltinFunction("os.rename", renameFn),
+		"link":       toy.NewBuiltinFunction("os.link", linkFn),
+		"readlink":   toy.NewBuiltinFunction("os.readlink", readlinkFn),
+		"symlink":    toy.NewBuiltinFunction("os.symlink", symlinkFn),
+		"chdir":      toy.NewBuiltinFunction("os.chdir", chdirFn),
 		"chmod":      toy.NewBuiltinFunction("os.chmod", chmodFn),
 		"chown":      toy.NewBuiltinFunction("os.chown", chownFn),
 		"lchown":     toy.NewBuiltinFunction("os.lchown", lchownFn),
@@ -59,7 +63,7 @@ var Module = &toy.BuiltinModule{
 		"stat":       toy.NewBuiltinFunction("os.stat", statFn),
 		"lstat":      toy.NewBuiltinFunction("os.lstat", lstatFn),
 		"truncate":   toy.NewBuiltinFunction("os.truncate", truncateFn),
-		"getwd":      toy.NewBuiltinFunction("os.getwd", fndef.ARSE(os.Getwd)),
+		"getwd":      toy.NewBuiltinFunction("os.getwd", getwdFn),
 
 		"getuid":  toy.NewBuiltinFunction("os.getuid", fndef.ARI(os.Getuid)),
 		"getgid":  toy.NewBuiltinFunction("os.getgid", fndef.ARI(os.Getgid)),
@@ -232,19 +236,22 @@ func (m FileMode) UnaryOp(op token.Token) (toy.Value, error) {
 	return nil, toy.ErrInvalidOperation
 }
 
-func readFileFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func readFileFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	data, err := os.ReadFile(name)
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	data, err := r.FS().ReadFile(name)
 	if err != nil {
 		return nil, err
 	}
 	return toy.Bytes(data), nil
 }
 
-func writeFileFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func writeFileFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		data toy.StringOrBytes
@@ -253,18 +260,24 @@ func writeFileFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "data", &data, "perm?", &perm); err != nil {
 		return nil, err
 	}
-	if err := os.WriteFile(name, data.Bytes(), os.FileMode(perm)); err != nil {
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().WriteFile(name, data.Bytes(), os.FileMode(perm)); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func readDirFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func readDirFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	entries, err := os.ReadDir(name)
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	entries, err := r.FS().ReadDir(name)
 	if err != nil {
 		return nil, err
 	}
@@ -275,7 +288,7 @@ func readDirFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return toy.NewArray(elems), nil
 }
 
-func mkdirFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func mkdirFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		perm FileMode = 0755
@@ -284,31 +297,37 @@ func mkdirFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "perm?", &perm, "all?", &all); err != nil {
 		return nil, err
 	}
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
 	if all {
-		if err := os.MkdirAll(name, os.FileMode(perm)); err != nil {
+		if err := r.FS().MkdirAll(name, os.FileMode(perm)); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := os.Mkdir(name, os.FileMode(perm)); err != nil {
+		if err := r.FS().Mkdir(name, os.FileMode(perm)); err != nil {
 			return nil, err
 		}
 	}
 	return toy.Nil, nil
 }
 
-func mkdirTempFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func mkdirTempFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var dir, pattern string
 	if err := toy.UnpackArgs(args, "dir", &dir, "pattern", &pattern); err != nil {
 		return nil, err
 	}
-	res, err := os.MkdirTemp(dir, pattern)
+	if err := r.CheckFS(tempDir(r, dir), toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	res, err := r.FS().MkdirTemp(dir, pattern)
 	if err != nil {
 		return nil, err
 	}
 	return toy.String(res), nil
 }
 
-func removeFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func removeFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		all  = false
@@ -316,34 +335,125 @@ func removeFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "all?", &all); err != nil {
 		return nil, err
 	}
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
 	if all {
-		if err := os.RemoveAll(name); err != nil {
+		if err := r.FS().RemoveAll(name); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := os.Remove(name); err != nil {
+		if err := r.FS().Remove(name); err != nil {
 			return nil, err
 		}
 	}
 	return toy.Nil, nil
 }
 
-func argsFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func renameFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var oldpath, newpath string
+	if err := toy.UnpackArgs(args, "oldpath", &oldpath, "newpath", &newpath); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(oldpath, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(newpath, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Rename(oldpath, newpath); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func linkFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var oldname, newname string
+	if err := toy.UnpackArgs(args, "oldname", &oldname, "newname", &newname); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(oldname, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(newname, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Link(oldname, newname); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func readlinkFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var name string
+	if err := toy.UnpackArgs(args, "name", &name); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	res, err := r.FS().Readlink(name)
+	if err != nil {
+		return nil, err
+	}
+	return toy.String(res), nil
+}
+
+func symlinkFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var oldname, newname string
+	if err := toy.UnpackArgs(args, "oldname", &oldname, "newname", &newname); err != nil {
+		return nil, err
+	}
+	if err := r.CheckSymlink(oldname, newname); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Symlink(oldname, newname); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func chdirFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var dir string
+	if err := toy.UnpackArgs(args, "dir", &dir); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(dir, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Chdir(dir); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func getwdFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	dir, err := r.FS().Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return toy.String(dir), nil
+}
+
+func argsFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	elems := make([]toy.Value, 0, len(os.Args))
-	for _, arg := range os.Args {
+	elems := make([]toy.Value, 0, len(r.Args()))
+	for _, arg := range r.Args() {
 		elems = append(elems, toy.String(arg))
 	}
 	return toy.NewArray(elems), nil
 }
 
-func environFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func environFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	envs := os.Environ()
+	envs := r.Env().Environ()
 	t := toy.NewTable(len(envs))
 	for _, env := range envs {
 		parts := strings.SplitN(env, "=", 2)
@@ -352,7 +462,7 @@ func environFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return t, nil
 }
 
-func chmodFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func chmodFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		mode FileMode
@@ -360,13 +470,16 @@ func chmodFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "mode", &mode); err != nil {
 		return nil, err
 	}
-	if err := os.Chmod(name, os.FileMode(mode)); err != nil {
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Chmod(name, os.FileMode(mode)); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func chownFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func chownFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name     string
 		uid, gid int
@@ -374,13 +487,16 @@ func chownFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "uid", &uid, "gid", &gid); err != nil {
 		return nil, err
 	}
-	if err := os.Chown(name, uid, gid); err != nil {
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Chown(name, uid, gid); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func lchownFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func lchownFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name     string
 		uid, gid int
@@ -388,7 +504,10 @@ func lchownFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "uid", &uid, "gid", &gid); err != nil {
 		return nil, err
 	}
-	if err := os.Lchown(name, uid, gid); err != nil {
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Lchown(name, uid, gid); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
@@ -421,7 +540,7 @@ func (f *FileInfo) Property(key toy.Value) (value toy.Value, found bool, err err
 	case "mode":
 		return FileMode(f.info.Mode()), true, nil
 	case "modTime":
-		return time.Time(f.info.ModTime()), true, nil
+		return toytime.Time(f.info.ModTime()), true, nil
 	case "type":
 		return FileMode(f.info.Mode().Type()), true, nil
 	case "perm":
@@ -446,31 +565,37 @@ func (f *FileInfo) Property(key toy.Value) (value toy.Value, found bool, err err
 	return toy.Nil, false, nil
 }
 
-func statFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func statFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	info, err := os.Stat(name)
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	info, err := r.FS().Stat(name)
 	if err != nil {
 		return nil, err
 	}
 	return &FileInfo{info: info}, nil
 }
 
-func lstatFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func lstatFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	info, err := os.Lstat(name)
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	info, err := r.FS().Lstat(name)
 	if err != nil {
 		return nil, err
 	}
 	return &FileInfo{info: info}, nil
 }
 
-func truncateFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func truncateFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		size int64
@@ -478,7 +603,10 @@ func truncateFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "size", &size); err != nil {
 		return nil, err
 	}
-	if err := os.Truncate(name, size); err != nil {
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	if err := r.FS().Truncate(name, size); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
@@ -549,18 +677,88 @@ func dirEntryInfoMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return &FileInfo{info: info}, nil
 }
 
-type File os.File
+// File represents an open file of the host or a virtual filesystem,
+// or one of the standard streams of the runtime.
+// Use NewFile to wrap *os.File and File.File to access the underlying file.
+type File struct {
+	file   fs.File
+	name   string
+	stream stdStream
+}
+
+// stdStream represents a standard stream of the runtime.
+type stdStream int
+
+const (
+	noStream stdStream = iota
+	stdinStream
+	stdoutStream
+	stderrStream
+)
+
+// NewFile returns a new File with the given name.
+func NewFile(file fs.File, name string) *File {
+	return &File{file: file, name: name}
+}
 
 var FileType = toy.NewType[*File]("os.File", nil)
 
+// File returns the underlying file, which is *os.File
+// for files of the host filesystem, or nil for the standard streams.
+func (f *File) File() fs.File {
+	return f.file
+}
+
 func (f *File) Type() toy.ValueType { return FileType }
-func (f *File) String() string      { return fmt.Sprintf("<os.File %q>", (*os.File)(f).Name()) }
+func (f *File) String() string      { return fmt.Sprintf("<os.File %q>", f.name) }
 func (f *File) IsFalsy() bool       { return false }
+func (f *File) Clone() toy.Value    { return &File{file: f.file, name: f.name, stream: f.stream} }
 
-func (f *File) Clone() toy.Value {
-	c := new(File)
-	*c = *f
-	return c
+// get returns the underlying file or the standard stream of the given runtime.
+func (f *File) get(r *toy.Runtime) any {
+	switch f.stream {
+	case stdinStream:
+		return r.Stdin()
+	case stdoutStream:
+		return r.Stdout()
+	case stderrStream:
+		return r.Stderr()
+	}
+	return f.file
+}
+
+// interruptible calls fn, which performs a blocking operation on the given file,
+// and interrupts it when the context of the runtime is done,
+// if the file supports deadlines.
+func (f *File) interruptible(r *toy.Runtime, file any, fn func() error) error {
+	ctx := r.Context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, ok := file.(interface{ SetDeadline(t time.Time) error })
+	if !ok || ctx.Done() == nil {
+		return fn()
+	}
+	if err := d.SetDeadline(time.Time{}); err != nil {
+		// the file doesn't support deadlines
+		return fn()
+	}
+	stop := context.AfterFunc(ctx, func() {
+		d.SetDeadline(time.Now())
+	})
+	err := fn()
+	if !stop() {
+		d.SetDeadline(time.Time{})
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// unsupported returns an error for the operation not supported by the file.
+func (f *File) unsupported(op string) error {
+	return &fs.PathError{Op: op, Path: f.name, Err: errors.ErrUnsupported}
 }
 
 func (f *File) Property(key toy.Value) (value toy.Value, found bool, err error) {
@@ -573,7 +771,7 @@ func (f *File) Property(key toy.Value) (value toy.Value, found bool, err error)
 	}
 	switch string(keyStr) {
 	case "name":
-		return toy.String((*os.File)(f).Name()), true, nil
+		return toy.String(f.name), true, nil
 	}
 	method, ok := fileMethods[string(keyStr)]
 	if ok {
@@ -596,7 +794,7 @@ var fileMethods = map[string]*toy.BuiltinFunction{
 	"readdir":  toy.NewBuiltinFunction("readdir", fileReaddirMd),
 }
 
-func fileWriteMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
+func fileWriteMd(r *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	var (
 		recv = args[0].(*File)
 		data toy.StringOrBytes
@@ -607,12 +805,26 @@ func fileWriteMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	}
 	var n int
 	if off != nil {
-		n, err = (*os.File)(recv).WriteAt(data.Bytes(), *off)
+		w, ok := recv.get(r).(io.WriterAt)
+		if !ok {
+			return nil, recv.unsupported("write")
+		}
+		err = recv.interruptible(r, w, func() (err error) {
+			n, err = w.WriteAt(data.Bytes(), *off)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		n, err = (*os.File)(recv).Write(data.Bytes())
+		w, ok := recv.get(r).(io.Writer)
+		if !ok {
+			return nil, recv.unsupported("write")
+		}
+		err = recv.interruptible(r, w, func() (err error) {
+			n, err = w.Write(data.Bytes())
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -620,7 +832,7 @@ func fileWriteMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	return toy.Int(n), nil
 }
 
-func fileReadMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
+func fileReadMd(r *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	var (
 		recv = args[0].(*File)
 		buf  toy.Bytes
@@ -631,12 +843,26 @@ func fileReadMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	}
 	var n int
 	if off != nil {
-		n, err = (*os.File)(recv).ReadAt(buf, *off)
+		ra, ok := recv.get(r).(io.ReaderAt)
+		if !ok {
+			return nil, recv.unsupported("read")
+		}
+		err = recv.interruptible(r, ra, func() (err error) {
+			n, err = ra.ReadAt(buf, *off)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		n, err = (*os.File)(recv).Read(buf)
+		rd, ok := recv.get(r).(io.Reader)
+		if !ok {
+			return nil, recv.unsupported("read")
+		}
+		err = recv.interruptible(r, rd, func() (err error) {
+			n, err = rd.Read(buf)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -644,44 +870,56 @@ func fileReadMd(_ *toy.Runtime, args ...toy.Value) (_ toy.Value, err error) {
 	return toy.Tuple{buf[:n], toy.Int(n)}, nil
 }
 
-func fileCloseMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileCloseMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*File)
 	args = args[1:]
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	if err := (*os.File)(recv).Close(); err != nil {
+	c, ok := recv.get(r).(io.Closer)
+	if !ok {
+		return nil, recv.unsupported("close")
+	}
+	if err := c.Close(); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileStatMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileStatMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*File)
 	args = args[1:]
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	info, err := (*os.File)(recv).Stat()
+	f, ok := recv.get(r).(interface{ Stat() (fs.FileInfo, error) })
+	if !ok {
+		return nil, recv.unsupported("stat")
+	}
+	info, err := f.Stat()
 	if err != nil {
 		return nil, err
 	}
 	return &FileInfo{info: info}, nil
 }
 
-func fileSyncMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileSyncMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*File)
 	args = args[1:]
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	if err := (*os.File)(recv).Sync(); err != nil {
+	f, ok := recv.get(r).(interface{ Sync() error })
+	if !ok {
+		return nil, recv.unsupported("sync")
+	}
+	if err := f.Sync(); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileTruncateMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileTruncateMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		recv = args[0].(*File)
 		size int64
@@ -689,13 +927,20 @@ func fileTruncateMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args[1:], "size", &size); err != nil {
 		return nil, err
 	}
-	if err := (*os.File)(recv).Truncate(size); err != nil {
+	if err := r.CheckFS(recv.name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	f, ok := recv.get(r).(interface{ Truncate(size int64) error })
+	if !ok {
+		return nil, recv.unsupported("truncate")
+	}
+	if err := f.Truncate(size); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileChownMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileChownMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		recv     = args[0].(*File)
 		uid, gid int
@@ -703,13 +948,20 @@ func fileChownMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args[1:], "uid", &uid, "gid", &gid); err != nil {
 		return nil, err
 	}
-	if err := (*os.File)(recv).Chown(uid, gid); err != nil {
+	if err := r.CheckFS(recv.name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	f, ok := recv.get(r).(interface{ Chown(uid, gid int) error })
+	if !ok {
+		return nil, recv.unsupported("chown")
+	}
+	if err := f.Chown(uid, gid); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileChmodMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileChmodMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		recv = args[0].(*File)
 		mode FileMode
@@ -717,25 +969,39 @@ func fileChmodMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args[1:], "mode", &mode); err != nil {
 		return nil, err
 	}
-	if err := (*os.File)(recv).Chmod(os.FileMode(mode)); err != nil {
+	if err := r.CheckFS(recv.name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	f, ok := recv.get(r).(interface{ Chmod(mode os.FileMode) error })
+	if !ok {
+		return nil, recv.unsupported("chmod")
+	}
+	if err := f.Chmod(os.FileMode(mode)); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileChdirMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileChdirMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*File)
 	args = args[1:]
 	if len(args) != 0 {
 		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
 	}
-	if err := (*os.File)(recv).Chdir(); err != nil {
+	if err := r.CheckFS(recv.name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	f, ok := recv.get(r).(interface{ Chdir() error })
+	if !ok {
+		return nil, recv.unsupported("chdir")
+	}
+	if err := f.Chdir(); err != nil {
 		return nil, err
 	}
 	return toy.Nil, nil
 }
 
-func fileSeekMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileSeekMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		recv   = args[0].(*File)
 		offset int64
@@ -744,14 +1010,18 @@ func fileSeekMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args[1:], "offset", &offset, "whence", &whence); err != nil {
 		return nil, err
 	}
-	ret, err := (*os.File)(recv).Seek(offset, whence)
+	seeker, ok := recv.get(r).(io.Seeker)
+	if !ok {
+		return nil, recv.unsupported("seek")
+	}
+	ret, err := seeker.Seek(offset, whence)
 	if err != nil {
 		return nil, err
 	}
 	return toy.Int(ret), nil
 }
 
-func fileReaddirMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func fileReaddirMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		recv = args[0].(*File)
 		n    = -1
@@ -759,7 +1029,11 @@ func fileReaddirMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args[1:], "n?", &n); err != nil {
 		return nil, err
 	}
-	entries, err := (*os.File)(recv).ReadDir(n)
+	dir, ok := recv.get(r).(fs.ReadDirFile)
+	if !ok {
+		return nil, recv.unsupported("readdir")
+	}
+	entries, err := dir.ReadDir(n)
 	if err != nil {
 		return nil, err
 	}
@@ -770,7 +1044,7 @@ func fileReaddirMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return toy.NewArray(elems), nil
 }
 
-func openFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func openFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var (
 		name string
 		flag = os.O_RDONLY
@@ -779,33 +1053,54 @@ func openFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	if err := toy.UnpackArgs(args, "name", &name, "flag?", &flag, "perm?", &perm); err != nil {
 		return nil, err
 	}
-	file, err := os.OpenFile(name, flag, os.FileMode(perm))
+	access := toy.FSReadOnly
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		access = toy.FSReadWrite
+	}
+	if err := r.CheckFS(name, access); err != nil {
+		return nil, err
+	}
+	file, err := r.FS().OpenFile(name, flag, os.FileMode(perm))
 	if err != nil {
 		return nil, err
 	}
-	return (*File)(file), nil
+	return NewFile(file, name), nil
 }
 
-func createFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func createFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	file, err := os.Create(name)
+	if err := r.CheckFS(name, toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	file, err := r.FS().Create(name)
 	if err != nil {
 		return nil, err
 	}
-	return (*File)(file), nil
+	return NewFile(file, name), nil
 }
 
-func createTempFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func createTempFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var dir, pattern string
 	if err := toy.UnpackArgs(args, "dir", &dir, "pattern", &pattern); err != nil {
 		return nil, err
 	}
-	file, err := os.CreateTemp(dir, pattern)
+	if err := r.CheckFS(tempDir(r, dir), toy.FSReadWrite); err != nil {
+		return nil, err
+	}
+	file, name, err := r.FS().CreateTemp(dir, pattern)
 	if err != nil {
 		return nil, err
 	}
-	return (*File)(file), nil
+	return NewFile(file, name), nil
+}
+
+// tempDir returns the directory used by mkdirTemp and createTemp.
+func tempDir(r *toy.Runtime, dir string) string {
+	if dir == "" && r.FS().IsHost() {
+		return os.TempDir()
+	}
+	return dir
 }
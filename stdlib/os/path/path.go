@@ -22,11 +22,11 @@ var Module = &toy.BuiltinModule{
 		"noext":        toy.NewBuiltinFunction("path.noext", noextFn),
 		"stem":         toy.NewBuiltinFunction("path.stem", stemFn),
 		"clean":        toy.NewBuiltinFunction("path.clean", fndef.ASRS("path", filepath.Clean)),
-		"evalSymlinks": toy.NewBuiltinFunction("path.evalSymlinks", fndef.ASRSE("path", filepath.EvalSymlinks)),
+		"evalSymlinks": toy.NewBuiltinFunction("path.evalSymlinks", evalSymlinksFn),
 		"split":        toy.NewBuiltinFunction("path.split", fndef.ASRSS("path", filepath.Split)),
 		"splitList":    toy.NewBuiltinFunction("path.splitList", fndef.ASRSs("path", filepath.SplitList)),
 		"match":        toy.NewBuiltinFunction("path.match", fndef.ASSRBE("pattern", "name", filepath.Match)),
-		"glob":         toy.NewBuiltinFunction("path.glob", fndef.ASRSsE("pattern", filepath.Glob)),
+		"glob":         toy.NewBuiltinFunction("path.glob", globFn),
 		"rel":          toy.NewBuiltinFunction("path.rel", fndef.ASSRSE("basepath", "targetpath", filepath.Rel)),
 		"fromSlash":    toy.NewBuiltinFunction("path.fromSlash", fndef.ASRS("path", filepath.FromSlash)),
 		"toSlash":      toy.NewBuiltinFunction("path.toSlash", fndef.ASRS("path", filepath.ToSlash)),
@@ -95,24 +95,68 @@ func expandFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return toy.String(filepath.Join(usr.HomeDir, s[1:])), nil
 }
 
-func existsFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func evalSymlinksFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var path string
+	if err := toy.UnpackArgs(args, "path", &path); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(path, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	res, err := r.FS().EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	return toy.String(res), nil
+}
+
+func globFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var pattern string
+	if err := toy.UnpackArgs(args, "pattern", &pattern); err != nil {
+		return nil, err
+	}
+	if err := r.CheckFS(filepath.Dir(pattern), toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	matches, err := r.FS().Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	elems := make([]toy.Value, 0, len(matches))
+	for _, match := range matches {
+		// skip matches that are resolved outside of the root directory
+		if r.CheckFS(match, toy.FSReadOnly) != nil {
+			continue
+		}
+		elems = append(elems, toy.String(match))
+	}
+	return toy.NewArray(elems), nil
+}
+
+func existsFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var name string
 	if err := toy.UnpackArgs(args, "name", &name); err != nil {
 		return nil, err
 	}
-	if _, err := os.Stat(name); err != nil {
+	if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+		return nil, err
+	}
+	if _, err := r.FS().Stat(name); err != nil {
 		return toy.False, nil
 	}
 	return toy.True, nil
 }
 
 func makeIsFn(typ os.FileMode) toy.CallableFunc {
-	return func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	return func(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 		var name string
 		if err := toy.UnpackArgs(args, "name", &name); err != nil {
 			return nil, err
 		}
-		stat, err := os.Stat(name)
+		if err := r.CheckFS(name, toy.FSReadOnly); err != nil {
+			return nil, err
+		}
+		stat, err := r.FS().Stat(name)
 		if err != nil {
 			return toy.False, nil
 		}
@@ -3,6 +3,7 @@ package stdlib
 import (
 	"github.com/infastin/toy"
 	"github.com/infastin/toy/stdlib/base64"
+	"github.com/infastin/toy/stdlib/errors"
 	"github.com/infastin/toy/stdlib/fmt"
 	"github.com/infastin/toy/stdlib/hex"
 	"github.com/infastin/toy/stdlib/json"
@@ -15,6 +16,7 @@ import (
 	"github.com/infastin/toy/stdlib/path"
 	"github.com/infastin/toy/stdlib/rand"
 	"github.com/infastin/toy/stdlib/regexp"
+	"github.com/infastin/toy/stdlib/sync"
 	"github.com/infastin/toy/stdlib/text"
 	"github.com/infastin/toy/stdlib/time"
 	"github.com/infastin/toy/stdlib/uuid"
@@ -23,6 +25,7 @@ import (
 
 var StdLib = toy.ModuleMap{
 	"base64":  base64.Module,
+	"errors":  errors.Module,
 	"fmt":     fmt.Module,
 	"hex":     hex.Module,
 	"json":    json.Module,
@@ -35,6 +38,7 @@ var StdLib = toy.ModuleMap{
 	"path":    path.Module,
 	"rand":    rand.Module,
 	"regexp":  regexp.Module,
+	"sync":    sync.Module,
 	"text":    text.Module,
 	"time":    time.Module,
 	"uuid":    uuid.Module,
@@ -0,0 +1,372 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/infastin/toy"
+)
+
+var Module = &toy.BuiltinModule{
+	Name: "sync",
+	Members: map[string]toy.Value{
+		"WaitGroup": WaitGroupType,
+		"Mutex":     MutexType,
+		"TaskGroup": TaskGroupType,
+	},
+}
+
+// WaitGroup waits for a collection of tasks to finish.
+type WaitGroup struct {
+	mu    sync.Mutex
+	count int
+	zero  chan struct{} // closed when count is zero
+}
+
+var WaitGroupType = toy.NewType[*WaitGroup]("sync.WaitGroup", func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	return NewWaitGroup(), nil
+})
+
+// NewWaitGroup creates a new WaitGroup.
+func NewWaitGroup() *WaitGroup {
+	zero := make(chan struct{})
+	close(zero)
+	return &WaitGroup{zero: zero}
+}
+
+func (wg *WaitGroup) Type() toy.ValueType { return WaitGroupType }
+func (wg *WaitGroup) String() string      { return "<sync.WaitGroup>" }
+func (wg *WaitGroup) IsFalsy() bool       { return false }
+func (wg *WaitGroup) Clone() toy.Value    { return wg }
+
+// Add adds delta, which may be negative, to the counter.
+func (wg *WaitGroup) Add(delta int) error {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	count := wg.count + delta
+	if count < 0 {
+		return errors.New("negative wait group counter")
+	}
+	if wg.count == 0 && count > 0 {
+		wg.zero = make(chan struct{})
+	} else if wg.count > 0 && count == 0 {
+		close(wg.zero)
+	}
+	wg.count = count
+	return nil
+}
+
+// Wait blocks until the counter is zero.
+// If ctx is done before that, Wait returns ctx.Err().
+func (wg *WaitGroup) Wait(ctx context.Context) error {
+	wg.mu.Lock()
+	zero := wg.zero
+	wg.mu.Unlock()
+	select {
+	case <-zero:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (wg *WaitGroup) Property(key toy.Value) (value toy.Value, found bool, err error) {
+	keyStr, ok := key.(toy.String)
+	if !ok {
+		return nil, false, &toy.InvalidKeyTypeError{
+			Want: "string",
+			Got:  toy.TypeName(key),
+		}
+	}
+	m, ok := waitGroupMethods[string(keyStr)]
+	if !ok {
+		return toy.Nil, false, nil
+	}
+	return m.WithReceiver(wg), true, nil
+}
+
+var waitGroupMethods = map[string]*toy.BuiltinFunction{
+	"add":  toy.NewBuiltinFunction("add", waitGroupAddMd),
+	"done": toy.NewBuiltinFunction("done", waitGroupDoneMd),
+	"wait": toy.NewBuiltinFunction("wait", waitGroupWaitMd),
+}
+
+func waitGroupAddMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var (
+		recv  = args[0].(*WaitGroup)
+		delta = 1
+	)
+	if err := toy.UnpackArgs(args[1:], "delta?", &delta); err != nil {
+		return nil, err
+	}
+	if err := recv.Add(delta); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func waitGroupDoneMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*WaitGroup)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	if err := recv.Add(-1); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func waitGroupWaitMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*WaitGroup)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	if err := recv.Wait(r.Context()); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+// Mutex is a mutual exclusion lock.
+type Mutex struct {
+	ch chan struct{}
+}
+
+var MutexType = toy.NewType[*Mutex]("sync.Mutex", func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	return NewMutex(), nil
+})
+
+// NewMutex creates a new unlocked Mutex.
+func NewMutex() *Mutex {
+	return &Mutex{ch: make(chan struct{}, 1)}
+}
+
+func (m *Mutex) Type() toy.ValueType { return MutexType }
+func (m *Mutex) String() string      { return "<sync.Mutex>" }
+func (m *Mutex) IsFalsy() bool       { return false }
+func (m *Mutex) Clone() toy.Value    { return m }
+
+// Lock locks the mutex.
+// If ctx is done before the mutex is locked, Lock returns ctx.Err().
+func (m *Mutex) Lock(ctx context.Context) error {
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryLock tries to lock the mutex and reports whether it succeeded.
+func (m *Mutex) TryLock() bool {
+	select {
+	case m.ch <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock unlocks the mutex.
+func (m *Mutex) Unlock() error {
+	select {
+	case <-m.ch:
+		return nil
+	default:
+		return errors.New("unlock of unlocked mutex")
+	}
+}
+
+func (m *Mutex) Property(key toy.Value) (value toy.Value, found bool, err error) {
+	keyStr, ok := key.(toy.String)
+	if !ok {
+		return nil, false, &toy.InvalidKeyTypeError{
+			Want: "string",
+			Got:  toy.TypeName(key),
+		}
+	}
+	method, ok := mutexMethods[string(keyStr)]
+	if !ok {
+		return toy.Nil, false, nil
+	}
+	return method.WithReceiver(m), true, nil
+}
+
+var mutexMethods = map[string]*toy.BuiltinFunction{
+	"lock":    toy.NewBuiltinFunction("lock", mutexLockMd),
+	"tryLock": toy.NewBuiltinFunction("tryLock", mutexTryLockMd),
+	"unlock":  toy.NewBuiltinFunction("unlock", mutexUnlockMd),
+}
+
+func mutexLockMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*Mutex)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	if err := recv.Lock(r.Context()); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+func mutexTryLockMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*Mutex)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	return toy.Bool(recv.TryLock()), nil
+}
+
+func mutexUnlockMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*Mutex)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	if err := recv.Unlock(); err != nil {
+		return nil, err
+	}
+	return toy.Nil, nil
+}
+
+// TaskGroup runs a collection of tasks.
+// The first task to fail aborts all other tasks of the group.
+type TaskGroup struct {
+	mu    sync.Mutex
+	tasks []*toy.Task
+	err   error
+}
+
+var TaskGroupType = toy.NewType[*TaskGroup]("sync.TaskGroup", func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	return new(TaskGroup), nil
+})
+
+func (g *TaskGroup) Type() toy.ValueType { return TaskGroupType }
+func (g *TaskGroup) String() string      { return "<sync.TaskGroup>" }
+func (g *TaskGroup) IsFalsy() bool       { return false }
+func (g *TaskGroup) Clone() toy.Value    { return g }
+
+// Spawn calls fn with the given arguments concurrently in a child runtime of r
+// and adds the resulting task to the group.
+// If a task of the group has already failed, the new task is aborted immediately.
+func (g *TaskGroup) Spawn(r *toy.Runtime, fn toy.Value, args ...toy.Value) (*toy.Task, error) {
+	t, err := r.Spawn(fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	g.tasks = append(g.tasks, t)
+	failed := g.err != nil
+	g.mu.Unlock()
+	if failed {
+		t.Abort()
+	}
+	go func() {
+		if _, err := t.Wait(context.Background()); err != nil {
+			g.fail(err)
+		}
+	}()
+	return t, nil
+}
+
+// fail records the first error and aborts all tasks of the group.
+func (g *TaskGroup) fail(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.err != nil {
+		return
+	}
+	g.err = err
+	for _, t := range g.tasks {
+		t.Abort()
+	}
+}
+
+// Wait waits for all tasks of the group to finish
+// and returns their results in the order they were spawned.
+// If any of the tasks has failed, Wait returns the first error.
+// If ctx is done before that, Wait aborts all tasks and returns ctx.Err().
+func (g *TaskGroup) Wait(ctx context.Context) ([]toy.Value, error) {
+	g.mu.Lock()
+	tasks := g.tasks
+	g.mu.Unlock()
+	results := make([]toy.Value, len(tasks))
+	for i, t := range tasks {
+		select {
+		case <-t.Done():
+		case <-ctx.Done():
+			g.fail(ctx.Err())
+			return nil, ctx.Err()
+		}
+		res, err := t.Wait(ctx)
+		if err != nil {
+			g.fail(err)
+			g.mu.Lock()
+			err = g.err
+			g.mu.Unlock()
+			return nil, err
+		}
+		results[i] = res
+	}
+	return results, nil
+}
+
+func (g *TaskGroup) Property(key toy.Value) (value toy.Value, found bool, err error) {
+	keyStr, ok := key.(toy.String)
+	if !ok {
+		return nil, false, &toy.InvalidKeyTypeError{
+			Want: "string",
+			Got:  toy.TypeName(key),
+		}
+	}
+	m, ok := taskGroupMethods[string(keyStr)]
+	if !ok {
+		return toy.Nil, false, nil
+	}
+	return m.WithReceiver(g), true, nil
+}
+
+var taskGroupMethods = map[string]*toy.BuiltinFunction{
+	"spawn": toy.NewBuiltinFunction("spawn", taskGroupSpawnMd),
+	"wait":  toy.NewBuiltinFunction("wait", taskGroupWaitMd),
+}
+
+func taskGroupSpawnMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*TaskGroup)
+	args = args[1:]
+	if len(args) == 0 {
+		return nil, &toy.WrongNumArgumentsError{
+			WantMin: 1,
+			WantMax: -1,
+			Got:     len(args),
+		}
+	}
+	return recv.Spawn(r, args[0], args[1:]...)
+}
+
+func taskGroupWaitMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	recv := args[0].(*TaskGroup)
+	args = args[1:]
+	if len(args) != 0 {
+		return nil, &toy.WrongNumArgumentsError{Got: len(args)}
+	}
+	results, err := recv.Wait(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return toy.NewArray(results), nil
+}
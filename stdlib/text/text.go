@@ -319,7 +319,7 @@ var builderMethods = map[string]*toy.BuiltinFunction{
 	"reset": toy.NewBuiltinFunction("text.reset", builderResetMd),
 }
 
-func builderWriteMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+func builderWriteMd(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*Builder)
 	args = args[1:]
 	if len(args) != 1 {
@@ -331,10 +331,19 @@ func builderWriteMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	}
 	switch x := args[0].(type) {
 	case toy.String:
+		if err := builderGrow(r, recv, len(x)); err != nil {
+			return nil, err
+		}
 		(*strings.Builder)(recv).WriteString(string(x))
 	case toy.Bytes:
+		if err := builderGrow(r, recv, len(x)); err != nil {
+			return nil, err
+		}
 		(*strings.Builder)(recv).Write(x)
 	case toy.Char:
+		if err := builderGrow(r, recv, utf8.RuneLen(rune(x))); err != nil {
+			return nil, err
+		}
 		(*strings.Builder)(recv).WriteRune(rune(x))
 	default:
 		return nil, &toy.InvalidArgumentTypeError{
@@ -346,6 +355,15 @@ func builderWriteMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return toy.Nil, nil
 }
 
+// builderGrow checks the length of the builder after writing n bytes to it
+// and accounts for the memory allocated for these bytes.
+func builderGrow(r *toy.Runtime, b *Builder, n int) error {
+	if err := r.CheckLen(b.Len() + n); err != nil {
+		return err
+	}
+	return r.Alloc(int64(n))
+}
+
 func builderResetMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	recv := args[0].(*Builder)
 	args = args[1:]
@@ -24,6 +24,7 @@ var Module = &toy.BuiltinModule{
 		"unix":      toy.NewBuiltinFunction("time.unix", unixFn),
 		"unixMicro": toy.NewBuiltinFunction("time.unixMicro", unixMicroFn),
 		"unixMilli": toy.NewBuiltinFunction("time.unixMilli", unixMilliFn),
+		"sleep":     toy.NewBuiltinFunction("time.sleep", sleepFn),
 
 		"nsec": Duration(time.Nanosecond),
 		"usec": Duration(time.Microsecond),
@@ -52,6 +53,11 @@ var Module = &toy.BuiltinModule{
 	},
 }
 
+func init() {
+	toy.RegisterValueOf(func(t time.Time) (toy.Value, error) { return Time(t), nil })
+	toy.RegisterValueOf(func(d time.Duration) (toy.Value, error) { return Duration(d), nil })
+}
+
 type Time time.Time
 
 var TimeType = toy.NewType[Time]("time.Time", func(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
@@ -472,6 +478,22 @@ func durationTruncateMd(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	return Duration(time.Duration(recv).Truncate(time.Duration(m))), nil
 }
 
+func sleepFn(r *toy.Runtime, args ...toy.Value) (toy.Value, error) {
+	var d Duration
+	if err := toy.UnpackArgs(args, "d", &d); err != nil {
+		return nil, err
+	}
+	ctx := r.Context()
+	timer := time.NewTimer(time.Duration(d))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return toy.Nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 func sinceFn(_ *toy.Runtime, args ...toy.Value) (toy.Value, error) {
 	var t Time
 	if err := toy.UnpackArgs(args, "t", &t); err != nil {
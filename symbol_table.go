@@ -22,6 +22,23 @@ type Symbol struct {
 	Scope         SymbolScope
 	Index         int
 	LocalAssigned bool // if the local symbol is assigned at least once
+
+	members *moduleMembers // members of the imported module; or nil
+}
+
+// moduleMembers represents members of an imported module
+// that are known at compile time.
+type moduleMembers struct {
+	module string
+	names  []string // nil if the variable has been reassigned
+}
+
+// forgetMembers forgets the members of the module
+// the variable referred to before being reassigned.
+func (s *Symbol) forgetMembers() {
+	if s.members != nil {
+		s.members.names = nil
+	}
 }
 
 // SymbolTable represents a symbol table.
@@ -194,6 +211,9 @@ func (t *SymbolTable) defineFree(original *Symbol) *Symbol {
 		Name:  original.Name,
 		Index: len(t.freeSymbols) - 1,
 		Scope: ScopeFree,
+		// free variables share members with the original,
+		// so reassigning either of them forgets them
+		members: original.members,
 	}
 	t.store[original.Name] = symbol
 	return symbol
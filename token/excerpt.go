@@ -0,0 +1,164 @@
+package token
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Excerpt represents lines of the source code
+// with the highlighted range of columns.
+type Excerpt struct {
+	Line  int      // number of the first line, starting at 1
+	Lines []string // content of the lines without the line terminators
+	Start int      // first highlighted column of the first line, starting at 1 (byte count)
+	End   int      // column after the last highlighted one of the last line (byte count)
+}
+
+// maxExcerptLines is the maximum number of lines rendered by Excerpt.Render.
+// Lines in the middle of longer excerpts are omitted.
+const maxExcerptLines = 5
+
+// String returns the excerpt in the following form:
+//
+//	12 | x := a / b
+//	   |      ^^^^^
+func (e *Excerpt) String() string {
+	return e.Render(nil, nil)
+}
+
+// Render returns the excerpt in the same form as String,
+// applying the given styles to the gutter and the carets.
+// Nil styles leave the text as is.
+// If the excerpt spans many lines, the lines in the middle are omitted.
+func (e *Excerpt) Render(gutterStyle, caretStyle func(string) string) string {
+	if gutterStyle == nil {
+		gutterStyle = func(s string) string { return s }
+	}
+	if caretStyle == nil {
+		caretStyle = func(s string) string { return s }
+	}
+	width := len(strconv.Itoa(e.Line+len(e.Lines)-1)) + 1
+	blank := gutterStyle(strings.Repeat(" ", width) + " | ")
+
+	var b strings.Builder
+	for i, text := range e.Lines {
+		if len(e.Lines) > maxExcerptLines && i >= maxExcerptLines/2 && i < len(e.Lines)-maxExcerptLines/2 {
+			if i == maxExcerptLines/2 {
+				b.WriteByte('\n')
+				b.WriteString(blank)
+				b.WriteString("...")
+			}
+			continue
+		}
+		if i != 0 {
+			b.WriteByte('\n')
+		}
+		line := strconv.Itoa(e.Line + i)
+		b.WriteString(gutterStyle(strings.Repeat(" ", width-len(line)) + line + " | "))
+		b.WriteString(text)
+		indent, carets := e.underline(i)
+		if carets != "" {
+			b.WriteByte('\n')
+			b.WriteString(blank)
+			b.WriteString(indent)
+			b.WriteString(caretStyle(carets))
+		}
+	}
+	return b.String()
+}
+
+// underline returns the indentation of the highlighted range of the i-th line
+// and the carets spanning it. The indentation preserves tabs
+// from the source line, so that the carets stay aligned with the text.
+// Lines following the first one are highlighted starting
+// from the first non-blank character.
+func (e *Excerpt) underline(i int) (indent, carets string) {
+	text := e.Lines[i]
+	start, end := 1, len(text)+1
+	if i == 0 {
+		start = e.Start
+	} else {
+		start = len(text) - len(strings.TrimLeft(text, " \t")) + 1
+	}
+	if i == len(e.Lines)-1 {
+		end = e.End
+	}
+	start = min(start, len(text)+1)
+	end = min(end, len(text)+1)
+	if i != 0 && end <= start {
+		return "", ""
+	}
+
+	var b strings.Builder
+	for _, c := range text[:start-1] {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	width := 1
+	if end > start {
+		width = max(utf8.RuneCountInString(text[start-1:end-1]), 1)
+	}
+	return b.String(), strings.Repeat("^", width)
+}
+
+// Excerpt returns the excerpt of the source lines spanned by the range [p, end).
+// If end is NoPos, only the character at p is highlighted.
+// Returns nil if the source of the file is not available.
+func (s *FileSet) Excerpt(p, end Pos) *Excerpt {
+	if p == NoPos {
+		return nil
+	}
+	f := s.file(p)
+	if f == nil {
+		return nil
+	}
+	if end <= p || int(end) > f.Base+f.Size {
+		end = p + 1
+	}
+	return f.Excerpt(int(p)-f.Base, int(end)-f.Base)
+}
+
+// Excerpt returns the excerpt of the source lines spanned by the range [offset, end).
+// Returns nil if the source of the file is not available.
+func (f *File) Excerpt(offset, end int) *Excerpt {
+	if f.src == nil || offset < 0 || offset > len(f.src) {
+		return nil
+	}
+	if offset > 0 && offset == len(f.src) && f.src[offset-1] == '\n' {
+		// EOF after the line terminator belongs to the last line
+		offset--
+	}
+	end = min(max(end, offset+1), len(f.src)+1)
+	start := bytes.LastIndexByte(f.src[:offset], '\n') + 1
+
+	// split the source from the start of the first line
+	// up to the end of the line containing the last highlighted character
+	last := min(end-1, len(f.src))
+	if last > offset && last == len(f.src) && f.src[last-1] == '\n' {
+		// EOF after the line terminator doesn't start a new line
+		last--
+	}
+	stop := len(f.src)
+	if i := bytes.IndexByte(f.src[last:], '\n'); i >= 0 {
+		stop = last + i
+	}
+	lines := strings.Split(string(f.src[start:stop]), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	lastStart := start
+	if i := bytes.LastIndexByte(f.src[start:stop], '\n'); i >= 0 {
+		lastStart = start + i + 1
+	}
+	return &Excerpt{
+		Line:  bytes.Count(f.src[:start], []byte("\n")) + 1,
+		Lines: lines,
+		Start: offset - start + 1,
+		End:   end - lastStart + 1,
+	}
+}
@@ -140,6 +140,8 @@ type File struct {
 	// Lines contains the offset of the first character for each line
 	// (the first entry is always 0)
 	Lines []int
+	// SourceFile content, if retained
+	src []byte
 }
 
 // Set returns SourceFileSet.
@@ -147,6 +149,21 @@ func (f *File) Set() *FileSet {
 	return f.set
 }
 
+// SetSource sets the content of the file.
+// The content is retained to render source excerpts in error messages.
+func (f *File) SetSource(src []byte) {
+	if len(src) != f.Size {
+		panic("illegal source size")
+	}
+	f.src = src
+}
+
+// Source returns the content of the file,
+// or nil if the content is not retained.
+func (f *File) Source() []byte {
+	return f.src
+}
+
 // LineCount returns the current number of lines.
 func (f *File) LineCount() int {
 	return len(f.Lines)
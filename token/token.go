@@ -91,12 +91,15 @@ const (
 	Return
 	Defer
 	Try
+	Catch
+	Finally
 	Throw
 	True
 	False
 	In
 	Nil
 	Import
+	Export
 	_keywordEnd
 )
 
@@ -178,12 +181,15 @@ var tokens = [...]string{
 	Return:   "return",
 	Defer:    "defer",
 	Try:      "try",
+	Catch:    "catch",
+	Finally:  "finally",
 	Throw:    "throw",
 	True:     "true",
 	False:    "false",
 	In:       "in",
 	Nil:      "nil",
 	Import:   "import",
+	Export:   "export",
 }
 
 func (tok Token) String() string {
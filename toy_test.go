@@ -0,0 +1,46 @@
+package toy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/infastin/toy"
+	"github.com/infastin/toy/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptTest is a test case running a script,
+// which either returns the expected value
+// or fails with an error containing the expected message.
+type scriptTest struct {
+	name string
+	src  string
+	want toy.Value
+	err  string
+}
+
+// runScriptTests runs the test cases, configuring each script with fn if not nil.
+func runScriptTests(t *testing.T, tests []scriptTest, fn func(s *toy.Script)) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := toy.NewScript([]byte(tt.src))
+			script.SetImports(stdlib.StdLib)
+			if fn != nil {
+				fn(script)
+			}
+			compiled, err := script.RunContext(context.Background())
+			if tt.err != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			want := tt.want
+			if want == nil {
+				want = toy.Nil
+			}
+			require.Equal(t, toy.AsString(want), toy.AsString(compiled.Result()))
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package toy_test
+
+import (
+	"testing"
+
+	"github.com/infastin/toy"
+)
+
+func TestTryStatement(t *testing.T) {
+	runScriptTests(t, []scriptTest{
+		{
+			name: "catch thrown value",
+			src:  `try { throw "failed" } catch e { return e.val }`,
+			want: toy.String("failed"),
+		},
+		{
+			name: "catch runtime error",
+			src:  `try { x := 1 + "a" } catch e { return "caught" }`,
+			want: toy.String("caught"),
+		},
+		{
+			name: "catch without variable",
+			src:  `r := 0; try { throw "failed" } catch { r = 1 }; return r`,
+			want: toy.Int(1),
+		},
+		{
+			name: "no error",
+			src:  `r := []; try { r = append(r, 1) } catch { r = append(r, 2) } finally { r = append(r, 3) }; return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.Int(3)}),
+		},
+		{
+			name: "catch and finally",
+			src:  `r := []; try { throw "x" } catch { r = append(r, 2) } finally { r = append(r, 3) }; return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(2), toy.Int(3)}),
+		},
+		{
+			name: "finally rethrows",
+			src:  `r := []; f := fn() { try { throw "failed" } finally { r = append(r, 1) } }; try { f() } catch e { r = append(r, e.val) }; return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.String("failed")}),
+		},
+		{
+			name: "uncaught in finally",
+			src:  `try { throw "failed" } finally {}`,
+			err:  "failed",
+		},
+		{
+			name: "throw from catch runs finally",
+			src:  `r := []; try { try { throw "a" } catch e { throw "b" } finally { r = append(r, 1) } } catch e { r = append(r, e.val) }; return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.String("b")}),
+		},
+		{
+			name: "return runs finally",
+			src:  `r := []; f := fn() { try { return 1 } finally { r = append(r, 2) } }; return f(), r`,
+			want: toy.Tuple{toy.Int(1), toy.NewArray([]toy.Value{toy.Int(2)})},
+		},
+		{
+			name: "return from catch runs finally",
+			src:  `r := []; f := fn() { try { throw "x" } catch { return 1 } finally { r = append(r, 2) } }; return f(), r`,
+			want: toy.Tuple{toy.Int(1), toy.NewArray([]toy.Value{toy.Int(2)})},
+		},
+		{
+			name: "break and continue run finally",
+			src: `
+r := []
+for i in range(0, 5) {
+	try {
+		if i == 1 { continue }
+		if i == 3 { break }
+		r = append(r, i)
+	} finally {
+		r = append(r, -i)
+	}
+}
+return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(0), toy.Int(0), toy.Int(-1), toy.Int(2), toy.Int(-2), toy.Int(-3)}),
+		},
+		{
+			name: "nested finally blocks",
+			src:  `r := []; f := fn() { try { try { return 1 } finally { r = append(r, 1) } } finally { r = append(r, 2) } }; f(); return r`,
+			want: toy.NewArray([]toy.Value{toy.Int(1), toy.Int(2)}),
+		},
+		{
+			name: "catch variable is scoped",
+			src:  `e := 1; try { throw "x" } catch e { e = 2 }; return e`,
+			want: toy.Int(1),
+		},
+		{
+			name: "error from called function",
+			src:  `errors := import("errors"); f := fn() { throw errors.new("boom") }; try { f() } catch e { return e.msg }`,
+			want: toy.String("boom"),
+		},
+		{
+			name: "missing catch and finally",
+			src:  `try { x := 1 }`,
+			err:  "expected catch or finally",
+		},
+	}, nil)
+}
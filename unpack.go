@@ -364,6 +364,24 @@ func Unpack(ptr any, v Value) error {
 			paramVar.Set(reflect.ValueOf(v))
 			break
 		}
+		// Values of HostType can be unpacked into the wrapped Go value.
+		if hv, ok := v.(interface{ unwrap() any }); ok {
+			if x := reflect.ValueOf(hv.unwrap()); x.IsValid() && x.Type().AssignableTo(paramVar.Type()) {
+				paramVar.Set(x)
+				break
+			}
+		}
+		// Object can be unpacked into the wrapped struct or pointer to it.
+		if o, ok := v.(*Object); ok {
+			if o.ptr.Type().AssignableTo(paramVar.Type()) {
+				paramVar.Set(o.ptr)
+				break
+			}
+			if o.ptr.Elem().Type().AssignableTo(paramVar.Type()) {
+				paramVar.Set(o.ptr.Elem())
+				break
+			}
+		}
 		// If *ptr implements Value, return an error.
 		if paramVar.Type().Implements(reflect.TypeFor[Value]()) {
 			// It should be safe to call TypeName on potentially nil value.
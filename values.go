@@ -1093,7 +1093,7 @@ func (v String) Elements() iter.Seq[Value] {
 type Bytes []byte
 
 // Bytes is the type of Bytes.
-var BytesType = NewType[Bytes]("bytes", func(_ *Runtime, args ...Value) (Value, error) {
+var BytesType = NewType[Bytes]("bytes", func(r *Runtime, args ...Value) (Value, error) {
 	if len(args) != 1 {
 		return nil, &WrongNumArgumentsError{
 			WantMin: 1,
@@ -1102,6 +1102,15 @@ var BytesType = NewType[Bytes]("bytes", func(_ *Runtime, args ...Value) (Value,
 		}
 	}
 	if i, ok := args[0].(Int); ok {
+		if i < 0 {
+			return nil, fmt.Errorf("negative bytes size: %d", i)
+		}
+		if err := r.CheckLen(int(i)); err != nil {
+			return nil, err
+		}
+		if err := r.Alloc(int64(i)); err != nil {
+			return nil, err
+		}
 		return make(Bytes, i), nil
 	}
 	var b Bytes
@@ -1283,7 +1292,7 @@ type Array struct {
 }
 
 // ArrayType is the type of Array.
-var ArrayType = NewType[*Array]("array", func(_ *Runtime, args ...Value) (Value, error) {
+var ArrayType = NewType[*Array]("array", func(r *Runtime, args ...Value) (Value, error) {
 	switch len(args) {
 	case 1:
 		if _, isInt := args[0].(Int); !isInt {
@@ -1305,6 +1314,12 @@ var ArrayType = NewType[*Array]("array", func(_ *Runtime, args ...Value) (Value,
 		if size < 0 {
 			return nil, fmt.Errorf("negative array size: %d", size)
 		}
+		if err := r.CheckLen(size); err != nil {
+			return nil, err
+		}
+		if err := r.Alloc(int64(size) * valueSize); err != nil {
+			return nil, err
+		}
 		arr := make([]Value, int(size))
 		for i := range arr {
 			arr[i] = value